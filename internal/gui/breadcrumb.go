@@ -0,0 +1,106 @@
+package gui
+
+import (
+	"sort"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// pathSegment 面包屑中的一段路径
+type pathSegment struct {
+	name string
+	path string
+}
+
+// pathSegments 按路径规则将路径拆分为从根目录开始的各级目录
+func pathSegments(paths transfer.Paths, path string) []pathSegment {
+	var segments []pathSegment
+	for {
+		parent := paths.Dir(path)
+		if parent == path {
+			// 根目录，如 "/" 或 "C:\"
+			segments = append(segments, pathSegment{name: path, path: path})
+			break
+		}
+		segments = append(segments, pathSegment{name: paths.Base(path), path: path})
+		path = parent
+	}
+	for i, j := 0, len(segments)-1; i < j; i, j = i+1, j-1 {
+		segments[i], segments[j] = segments[j], segments[i]
+	}
+	return segments
+}
+
+// newBreadcrumb 创建面包屑路径栏，路径过长时可横向滚动
+func (p *FilePanel) newBreadcrumb() fyne.CanvasObject {
+	p.breadcrumb = container.NewHBox()
+	p.breadcrumbScroll = container.NewHScroll(p.breadcrumb)
+	return p.breadcrumbScroll
+}
+
+// updateBreadcrumb 按当前路径重建面包屑：单击一段进入该目录，箭头列出同级目录
+func (p *FilePanel) updateBreadcrumb() {
+	if p.breadcrumb == nil {
+		return
+	}
+	segments := pathSegments(p.paths(), p.GetCurrentPath())
+	objects := make([]fyne.CanvasObject, 0, len(segments)*2)
+	for i, segment := range segments {
+		segment := segment
+		if i > 0 {
+			var arrow *widget.Button
+			arrow = widget.NewButtonWithIcon("", theme.MenuDropDownIcon(), func() {
+				p.showSiblings(segment, arrow)
+			})
+			arrow.Importance = widget.LowImportance
+			objects = append(objects, arrow)
+		}
+		button := widget.NewButton(segment.name, func() {
+			p.SetPath(segment.path)
+		})
+		button.Importance = widget.LowImportance
+		if i == len(segments)-1 {
+			button.Importance = widget.MediumImportance
+		}
+		objects = append(objects, button)
+	}
+	p.breadcrumb.Objects = objects
+	// 滚动到最右侧，保持当前目录可见
+	p.breadcrumbScroll.Offset.X = p.breadcrumb.MinSize().Width
+	p.breadcrumbScroll.Refresh()
+}
+
+// showSiblings 在箭头下方列出与 segment 同级的目录
+func (p *FilePanel) showSiblings(segment pathSegment, anchor fyne.CanvasObject) {
+	files, err := p.fileSystem.ListFiles(p.paths().Dir(segment.path))
+	if err != nil {
+		dialog.ShowError(err, p.window)
+		return
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return naturalCompare(files[i].Name, files[j].Name) < 0
+	})
+	var items []*fyne.MenuItem
+	for _, file := range files {
+		if !file.IsDir {
+			continue
+		}
+		file := file
+		item := fyne.NewMenuItem(file.Name, func() {
+			p.SetPath(file.Path)
+		})
+		item.Checked = file.Path == segment.path
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return
+	}
+	pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(anchor)
+	widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", items...), p.window.Canvas(),
+		pos.Add(fyne.NewPos(0, anchor.Size().Height)))
+}
@@ -0,0 +1,494 @@
+package gui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 列标识
+const (
+	columnName    = "name"
+	columnSize    = "size"
+	columnModTime = "modtime"
+	columnType    = "type"
+	columnMode    = "mode"
+	columnOwner   = "owner"
+)
+
+// minColumnWidth 列的最小宽度
+const minColumnWidth = 40
+
+// dividerWidth 列标题之间拖动条的宽度
+const dividerWidth = 6
+
+// fileColumn 文件列表的列
+type fileColumn struct {
+	id    string
+	title string
+	width float32 // 默认宽度
+}
+
+// fileColumns 全部列，按显示顺序排列
+var fileColumns = []fileColumn{
+	{columnName, "名称", 260},
+	{columnSize, "大小", 90},
+	{columnModTime, "修改时间", 160},
+	{columnType, "类型", 80},
+	{columnMode, "权限", 100},
+	{columnOwner, "所有者", 80},
+}
+
+// columnSettings 面板的列设置
+type columnSettings struct {
+	Widths     map[string]float32 `json:"widths"`
+	Hidden     map[string]bool    `json:"hidden"`
+	SortBy     string             `json:"sort_by"`
+	Descending bool               `json:"descending"`
+}
+
+// defaultColumnSettings 返回默认列设置：按名称升序，隐藏所有者列
+func defaultColumnSettings() columnSettings {
+	return columnSettings{
+		Widths: make(map[string]float32),
+		Hidden: map[string]bool{columnOwner: true},
+		SortBy: columnName,
+	}
+}
+
+// width 返回列宽，隐藏的列为0
+func (s columnSettings) width(column fileColumn) float32 {
+	if s.Hidden[column.id] {
+		return 0
+	}
+	if width, ok := s.Widths[column.id]; ok && width >= minColumnWidth {
+		return width
+	}
+	return column.width
+}
+
+// totalWidth 返回所有可见列的总宽度
+func (s columnSettings) totalWidth() float32 {
+	var total float32
+	for _, column := range fileColumns {
+		total += s.width(column)
+	}
+	return total
+}
+
+// columnSettingsPath 返回列设置文件路径
+func columnSettingsPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "xftp798", "columns.json"), nil
+}
+
+// loadAllColumnSettings 读取全部面板的列设置，文件不存在时返回空映射
+func loadAllColumnSettings() (map[string]columnSettings, error) {
+	all := make(map[string]columnSettings)
+	settingsPath, err := columnSettingsPath()
+	if err != nil {
+		return all, nil
+	}
+	data, err := os.ReadFile(settingsPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取列设置失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("解析列设置失败: %v", err)
+	}
+	return all, nil
+}
+
+// SetColumnsKey 设置保存列设置使用的面板标识，并读取已保存的设置
+func (p *FilePanel) SetColumnsKey(key string) {
+	p.columnsKey = key
+	all, err := loadAllColumnSettings()
+	if err != nil {
+		dialog.ShowError(err, p.window)
+		return
+	}
+	if settings, ok := all[key]; ok {
+		if settings.Widths == nil {
+			settings.Widths = make(map[string]float32)
+		}
+		if settings.Hidden == nil {
+			settings.Hidden = make(map[string]bool)
+		}
+		p.columns = settings
+	}
+	p.refreshColumns()
+	p.RefreshFiles()
+}
+
+// saveColumns 保存本面板的列设置
+func (p *FilePanel) saveColumns() {
+	if p.columnsKey == "" {
+		return
+	}
+	err := func() error {
+		all, err := loadAllColumnSettings()
+		if err != nil {
+			return err
+		}
+		all[p.columnsKey] = p.columns
+		settingsPath, err := columnSettingsPath()
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(all, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(settingsPath), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(settingsPath, data, 0644)
+	}()
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("保存列设置失败: %v", err), p.window)
+	}
+}
+
+// sortBy 按指定列排序，再次点击同一列时切换升序/降序
+func (p *FilePanel) sortBy(id string) {
+	if p.columns.SortBy == id {
+		p.columns.Descending = !p.columns.Descending
+	} else {
+		p.columns.SortBy = id
+		p.columns.Descending = false
+	}
+	p.saveColumns()
+
+	sortFiles(p.currentFiles, p.columns)
+	p.cursor, p.anchor = -1, -1
+	p.refreshColumns()
+	p.list.Refresh()
+}
+
+// toggleColumn 显示或隐藏列
+func (p *FilePanel) toggleColumn(id string) {
+	p.columns.Hidden[id] = !p.columns.Hidden[id]
+	p.saveColumns()
+	p.refreshColumns()
+	p.list.Refresh()
+}
+
+// resizeColumn 调整列宽
+func (p *FilePanel) resizeColumn(column fileColumn, delta float32) {
+	width := p.columns.width(column) + delta
+	if width < minColumnWidth {
+		width = minColumnWidth
+	}
+	p.columns.Widths[column.id] = width
+	p.header.Refresh()
+	p.list.Refresh()
+}
+
+// refreshColumns 更新列标题的文字和可见性
+func (p *FilePanel) refreshColumns() {
+	for i, column := range fileColumns {
+		header := p.header.Objects[i*2].(*columnHeader)
+		divider := p.header.Objects[i*2+1]
+		text := column.title
+		if p.columns.SortBy == column.id {
+			if p.columns.Descending {
+				text += " ▼"
+			} else {
+				text += " ▲"
+			}
+		}
+		header.SetText(text)
+		if p.columns.Hidden[column.id] {
+			header.Hide()
+			divider.Hide()
+		} else {
+			header.Show()
+			divider.Show()
+		}
+	}
+	p.header.Refresh()
+}
+
+// newColumnHeader 创建列标题行
+func (p *FilePanel) newColumnHeader() *fyne.Container {
+	var objects []fyne.CanvasObject
+	for _, column := range fileColumns {
+		objects = append(objects, newColumnHeader(p, column), newColumnDivider(p, column))
+	}
+	return &fyne.Container{Layout: &headerLayout{panel: p}, Objects: objects}
+}
+
+// showColumnMenu 显示列的显示/隐藏菜单，名称列始终显示
+func (p *FilePanel) showColumnMenu(pos fyne.Position) {
+	var items []*fyne.MenuItem
+	for _, column := range fileColumns[1:] {
+		id := column.id
+		item := fyne.NewMenuItem(column.title, func() {
+			p.toggleColumn(id)
+		})
+		item.Checked = !p.columns.Hidden[id]
+		items = append(items, item)
+	}
+	widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", items...), p.window.Canvas(), pos)
+}
+
+// columnHeader 列标题，单击排序，右键选择显示的列
+type columnHeader struct {
+	widget.Button
+	panel  *FilePanel
+	column fileColumn
+}
+
+// newColumnHeader 创建列标题
+func newColumnHeader(panel *FilePanel, column fileColumn) *columnHeader {
+	header := &columnHeader{panel: panel, column: column}
+	header.Text = column.title
+	header.Alignment = widget.ButtonAlignLeading
+	header.Importance = widget.LowImportance
+	header.OnTapped = func() {
+		panel.sortBy(column.id)
+	}
+	header.ExtendBaseWidget(header)
+	return header
+}
+
+// TappedSecondary 右键显示列菜单
+func (h *columnHeader) TappedSecondary(e *fyne.PointEvent) {
+	h.panel.showColumnMenu(e.AbsolutePosition)
+}
+
+// columnDivider 列标题之间的拖动条，拖动调整左侧列的宽度
+type columnDivider struct {
+	widget.BaseWidget
+	panel  *FilePanel
+	column fileColumn
+}
+
+// newColumnDivider 创建拖动条
+func newColumnDivider(panel *FilePanel, column fileColumn) *columnDivider {
+	divider := &columnDivider{panel: panel, column: column}
+	divider.ExtendBaseWidget(divider)
+	return divider
+}
+
+// CreateRenderer 创建渲染器
+func (d *columnDivider) CreateRenderer() fyne.WidgetRenderer {
+	line := canvas.NewRectangle(theme.SeparatorColor())
+	return &columnDividerRenderer{line: line}
+}
+
+// Cursor 鼠标悬停时显示调整宽度的光标
+func (d *columnDivider) Cursor() desktop.Cursor {
+	return desktop.HResizeCursor
+}
+
+// Dragged 拖动时调整列宽
+func (d *columnDivider) Dragged(event *fyne.DragEvent) {
+	d.panel.resizeColumn(d.column, event.Dragged.DX)
+}
+
+// DragEnd 拖动结束后保存列宽
+func (d *columnDivider) DragEnd() {
+	d.panel.saveColumns()
+}
+
+// columnDividerRenderer 拖动条渲染器，在中间画一条分隔线
+type columnDividerRenderer struct {
+	line *canvas.Rectangle
+}
+
+func (r *columnDividerRenderer) Layout(size fyne.Size) {
+	r.line.Resize(fyne.NewSize(1, size.Height))
+	r.line.Move(fyne.NewPos(size.Width/2, 0))
+}
+
+func (r *columnDividerRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(dividerWidth, 1)
+}
+
+func (r *columnDividerRenderer) Refresh() {
+	r.line.FillColor = theme.SeparatorColor()
+	r.line.Refresh()
+}
+
+func (r *columnDividerRenderer) Objects() []fyne.CanvasObject {
+	return []fyne.CanvasObject{r.line}
+}
+
+func (r *columnDividerRenderer) Destroy() {
+}
+
+// headerLayout 按列宽排列标题和拖动条，与列表项的内边距对齐
+type headerLayout struct {
+	panel *FilePanel
+}
+
+func (l *headerLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	x := theme.Padding()
+	for i, column := range fileColumns {
+		width := l.panel.columns.width(column)
+		if width == 0 {
+			continue
+		}
+		header, divider := objects[i*2], objects[i*2+1]
+		header.Move(fyne.NewPos(x, 0))
+		header.Resize(fyne.NewSize(width-dividerWidth, size.Height))
+		divider.Move(fyne.NewPos(x+width-dividerWidth, 0))
+		divider.Resize(fyne.NewSize(dividerWidth, size.Height))
+		x += width
+	}
+}
+
+func (l *headerLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
+	var height float32
+	for _, object := range objects {
+		height = fyne.Max(height, object.MinSize().Height)
+	}
+	return fyne.NewSize(l.panel.columns.totalWidth()+theme.Padding()*2, height)
+}
+
+// columnLayout 按列宽排列列表项的单元格，最后一个对象（比较标记）放在所有列之后
+type columnLayout struct {
+	panel *FilePanel
+}
+
+func (l *columnLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	var x float32
+	for i, column := range fileColumns {
+		width := l.panel.columns.width(column)
+		if width == 0 {
+			objects[i].Hide()
+			continue
+		}
+		objects[i].Show()
+		objects[i].Move(fyne.NewPos(x, 0))
+		objects[i].Resize(fyne.NewSize(width, size.Height))
+		x += width
+	}
+	trailing := objects[len(fileColumns)]
+	trailing.Move(fyne.NewPos(x, 0))
+	trailing.Resize(fyne.NewSize(fyne.Max(size.Width-x, trailing.MinSize().Width), size.Height))
+}
+
+func (l *columnLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
+	var height float32
+	for _, object := range objects {
+		height = fyne.Max(height, object.MinSize().Height)
+	}
+	return fyne.NewSize(l.panel.columns.totalWidth()+objects[len(fileColumns)].MinSize().Width, height)
+}
+
+// sortFiles 按列设置排序，目录始终排在文件之前，相同时按名称排序
+func sortFiles(files []transfer.FileInfo, settings columnSettings) {
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		if a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		result := compareColumn(settings.SortBy, a, b)
+		if result == 0 {
+			result = naturalCompare(a.Name, b.Name)
+		}
+		if settings.Descending {
+			return result > 0
+		}
+		return result < 0
+	})
+}
+
+// compareColumn 按列比较两个文件
+func compareColumn(id string, a, b transfer.FileInfo) int {
+	switch id {
+	case columnSize:
+		return compareInt64(a.Size, b.Size)
+	case columnModTime:
+		return a.ModTime.Compare(b.ModTime)
+	case columnType:
+		return strings.Compare(fileType(a), fileType(b))
+	case columnMode:
+		return strings.Compare(a.Mode.String(), b.Mode.String())
+	case columnOwner:
+		return naturalCompare(a.Owner, b.Owner)
+	default:
+		return naturalCompare(a.Name, b.Name)
+	}
+}
+
+// compareInt64 比较两个整数
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// naturalCompare 自然排序比较：忽略大小写，连续数字按数值比较（file2 排在 file10 之前）
+func naturalCompare(a, b string) int {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			numA, restA := splitDigits(a)
+			numB, restB := splitDigits(b)
+			trimmedA, trimmedB := strings.TrimLeft(numA, "0"), strings.TrimLeft(numB, "0")
+			if len(trimmedA) != len(trimmedB) {
+				return compareInt64(int64(len(trimmedA)), int64(len(trimmedB)))
+			}
+			if result := strings.Compare(trimmedA, trimmedB); result != 0 {
+				return result
+			}
+			a, b = restA, restB
+			continue
+		}
+		if a[0] != b[0] {
+			return compareInt64(int64(a[0]), int64(b[0]))
+		}
+		a, b = a[1:], b[1:]
+	}
+	return compareInt64(int64(len(a)), int64(len(b)))
+}
+
+// isDigit 判断是否为数字字符
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// splitDigits 拆分出开头的连续数字
+func splitDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// fileType 返回文件类型：目录为“文件夹”，文件为小写扩展名
+func fileType(file transfer.FileInfo) string {
+	if file.IsDir {
+		return "文件夹"
+	}
+	if ext := strings.TrimPrefix(filepath.Ext(file.Name), "."); ext != "" {
+		return strings.ToLower(ext)
+	}
+	return "文件"
+}
@@ -0,0 +1,256 @@
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// compareState 两个面板共享的目录比较状态
+type compareState struct {
+	left      *FilePanel
+	right     *FilePanel
+	leftRoot  string
+	rightRoot string
+	recursive bool
+	onlyDiffs bool
+	entries   map[string]transfer.CompareEntry
+}
+
+// run 重新执行比较并刷新两个面板
+func (c *compareState) run() error {
+	entries, err := transfer.CompareDirectories(c.left.fileSystem, c.right.fileSystem, c.leftRoot, c.rightRoot, c.recursive)
+	if err != nil {
+		return err
+	}
+	c.entries = entries
+	c.left.RefreshFiles()
+	c.right.RefreshFiles()
+	return nil
+}
+
+// root 返回面板对应的比较根目录
+func (c *compareState) root(p *FilePanel) string {
+	if p == c.left {
+		return c.leftRoot
+	}
+	return c.rightRoot
+}
+
+// relPath 计算文件相对于比较根目录的路径，不在根目录下时返回false
+func (c *compareState) relPath(p *FilePanel, file transfer.FileInfo) (string, bool) {
+	rel, err := p.paths().Rel(c.root(p), file.Path)
+	// Rel 的结果使用 / 分隔，"..foo" 是根目录下的普通名称
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", false
+	}
+	return rel, true
+}
+
+// status 返回文件在面板 p 视角下的比较结果
+func (c *compareState) status(p *FilePanel, file transfer.FileInfo) (transfer.CompareStatus, bool) {
+	rel, ok := c.relPath(p, file)
+	if !ok {
+		return 0, false
+	}
+	entry, ok := c.entries[rel]
+	if !ok {
+		return 0, false
+	}
+	if p == c.right {
+		return entry.Status.Mirror(), true
+	}
+	return entry.Status, true
+}
+
+// sourceInfo 返回条目在面板 p 一侧的文件信息
+func (c *compareState) sourceInfo(p *FilePanel, entry transfer.CompareEntry) *transfer.FileInfo {
+	if p == c.left {
+		return entry.Left
+	}
+	return entry.Right
+}
+
+// isCopyable 判断该比较结果是否应从此侧复制到对侧
+func isCopyable(status transfer.CompareStatus) bool {
+	return status == transfer.CompareOnlyLeft || status == transfer.CompareNewer || status == transfer.CompareSizeDiffers
+}
+
+// compareImportance 返回比较结果标签的显示样式
+func compareImportance(status transfer.CompareStatus) widget.Importance {
+	switch status {
+	case transfer.CompareIdentical:
+		return widget.LowImportance
+	case transfer.CompareNotCompared:
+		return widget.MediumImportance
+	case transfer.CompareOnlyLeft, transfer.CompareNewer:
+		return widget.SuccessImportance
+	case transfer.CompareOnlyRight, transfer.CompareOlder:
+		return widget.WarningImportance
+	default:
+		return widget.DangerImportance
+	}
+}
+
+// toggleCompare 进入或退出比较模式
+func (p *FilePanel) toggleCompare() {
+	if p.compare != nil {
+		p.stopCompare()
+		return
+	}
+	if p.peer == nil {
+		dialog.ShowError(fmt.Errorf("没有可比较的对侧面板"), p.window)
+		return
+	}
+	for _, panel := range []*FilePanel{p, p.peer} {
+		if err := panel.checkConnected(); err != nil {
+			dialog.ShowError(err, p.window)
+			return
+		}
+	}
+
+	recursiveCheck := widget.NewCheck("递归比较子目录", nil)
+	onlyDiffsCheck := widget.NewCheck("只显示差异项", nil)
+	dialog.ShowForm("比较目录",
+		"比较",
+		"取消",
+		[]*widget.FormItem{
+			widget.NewFormItem("", recursiveCheck),
+			widget.NewFormItem("", onlyDiffsCheck),
+		},
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+			state := &compareState{
+				left:      p,
+				right:     p.peer,
+				leftRoot:  p.GetCurrentPath(),
+				rightRoot: p.peer.GetCurrentPath(),
+				recursive: recursiveCheck.Checked,
+				onlyDiffs: onlyDiffsCheck.Checked,
+			}
+			p.compare = state
+			p.peer.compare = state
+			if err := state.run(); err != nil {
+				p.stopCompare()
+				dialog.ShowError(err, p.window)
+			}
+		},
+		p.window,
+	)
+}
+
+// stopCompare 退出比较模式
+func (p *FilePanel) stopCompare() {
+	state := p.compare
+	if state == nil {
+		return
+	}
+	state.left.compare = nil
+	state.right.compare = nil
+	state.left.RefreshFiles()
+	state.right.RefreshFiles()
+}
+
+// filterCompare 在只显示差异模式下过滤掉相同的条目
+func (p *FilePanel) filterCompare(files []transfer.FileInfo) []transfer.FileInfo {
+	if p.compare == nil || !p.compare.onlyDiffs {
+		return files
+	}
+	filtered := files[:0]
+	for _, file := range files {
+		if status, ok := p.compare.status(p, file); ok && status == transfer.CompareIdentical {
+			continue
+		}
+		filtered = append(filtered, file)
+	}
+	return filtered
+}
+
+// copyDifferences 将全部选中条目（目录则为其下所有差异项）复制到对侧
+func (p *FilePanel) copyDifferences(files []transfer.FileInfo) {
+	state := p.compare
+	selected := make(map[string]bool)
+	var selectedDirs []string
+	for _, file := range files {
+		if rel, ok := state.relPath(p, file); ok {
+			selected[rel] = true
+			selectedDirs = append(selectedDirs, rel)
+		}
+	}
+
+	var sources []transfer.CompareEntry
+	var copied []string
+	for _, candidate := range sortedCompareEntries(state.entries) {
+		if !selected[candidate.RelPath] && !transfer.UnderAny(candidate.RelPath, selectedDirs) {
+			continue
+		}
+		// 父目录已整体复制，跳过其内容
+		if transfer.UnderAny(candidate.RelPath, copied) {
+			continue
+		}
+		status := candidate.Status
+		if p == state.right {
+			status = status.Mirror()
+		}
+		info := state.sourceInfo(p, candidate)
+		if !isCopyable(status) || info == nil {
+			continue
+		}
+		sources = append(sources, candidate)
+		if info.IsDir {
+			copied = append(copied, candidate.RelPath)
+		}
+	}
+
+	if len(sources) == 0 {
+		dialog.ShowInformation("比较目录", "没有需要复制的差异项", p.window)
+		return
+	}
+
+	target := p.peer
+	targetRoot := state.root(target)
+	items := make([]copyItem, len(sources))
+	for i, source := range sources {
+		items[i] = copyItem{
+			source: state.sourceInfo(p, source).Path,
+			target: target.paths().Join(targetRoot, source.RelPath),
+		}
+	}
+	// 全部传输结束后重新比较；部分失败的目录已单独显示传输报告
+	err := target.transferTo(items, transfer.Copy, func(err error) {
+		var report *transfer.TransferReport
+		if err != nil && (errors.Is(err, transfer.ErrCanceled) || errors.As(err, &report)) {
+			err = nil
+		}
+		if p.compare == state {
+			if runErr := state.run(); runErr != nil {
+				err = errors.Join(err, runErr)
+			}
+		}
+		if err != nil {
+			dialog.ShowError(err, p.window)
+		}
+	})
+	if err != nil {
+		dialog.ShowError(err, p.window)
+	}
+}
+
+// sortedCompareEntries 按相对路径排序比较结果，保证父目录在子项之前
+func sortedCompareEntries(entries map[string]transfer.CompareEntry) []transfer.CompareEntry {
+	sorted := make([]transfer.CompareEntry, 0, len(entries))
+	for _, entry := range entries {
+		sorted = append(sorted, entry)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].RelPath < sorted[j].RelPath
+	})
+	return sorted
+}
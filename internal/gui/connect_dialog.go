@@ -3,6 +3,7 @@ package gui
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"xftp798/internal/transfer"
 
 	"fyne.io/fyne/v2"
@@ -14,6 +15,7 @@ import (
 type ConnectDialog struct {
 	window    fyne.Window
 	onConnect func(*transfer.SFTPConfig)
+	defaults  *transfer.SFTPConfig // 预先填写的连接信息，可以为nil
 }
 
 // NewConnectDialog 创建新的连接对话框
@@ -24,9 +26,18 @@ func NewConnectDialog(window fyne.Window, onConnect func(*transfer.SFTPConfig))
 	}
 }
 
+// SetDefaults 设置预先填写的连接信息
+func (d *ConnectDialog) SetDefaults(config *transfer.SFTPConfig) {
+	d.defaults = config
+}
+
 // Show 显示连接对话框
 func (d *ConnectDialog) Show() {
 	// 创建输入框，设置更大的尺寸
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("可选，用作标签页标题")
+	nameEntry.Resize(fyne.NewSize(300, 40))
+
 	hostEntry := widget.NewEntry()
 	hostEntry.SetPlaceHolder("请输入服务器地址")
 	hostEntry.Resize(fyne.NewSize(300, 40))
@@ -43,12 +54,28 @@ func (d *ConnectDialog) Show() {
 	passwordEntry.SetPlaceHolder("请输入密码")
 	passwordEntry.Resize(fyne.NewSize(300, 40))
 
+	initialDirEntry := widget.NewEntry()
+	initialDirEntry.SetPlaceHolder("可选，默认为主目录")
+	initialDirEntry.Resize(fyne.NewSize(300, 40))
+
 	// 创建表单项
+	if config := d.defaults; config != nil {
+		nameEntry.SetText(config.Name)
+		hostEntry.SetText(config.Host)
+		if config.Port != 0 {
+			portEntry.SetText(strconv.Itoa(config.Port))
+		}
+		usernameEntry.SetText(config.Username)
+		initialDirEntry.SetText(config.InitialDir)
+	}
+
 	items := []*widget.FormItem{
+		widget.NewFormItem("名称", nameEntry),
 		widget.NewFormItem("服务器", hostEntry),
 		widget.NewFormItem("端口", portEntry),
 		widget.NewFormItem("用户名", usernameEntry),
 		widget.NewFormItem("密码", passwordEntry),
+		widget.NewFormItem("初始目录", initialDirEntry),
 	}
 
 	// 创建对话框
@@ -80,10 +107,12 @@ func (d *ConnectDialog) Show() {
 
 			// 创建配置
 			config := &transfer.SFTPConfig{
-				Host:     hostEntry.Text,
-				Port:     port,
-				Username: usernameEntry.Text,
-				Password: passwordEntry.Text,
+				Name:       strings.TrimSpace(nameEntry.Text),
+				Host:       hostEntry.Text,
+				Port:       port,
+				Username:   usernameEntry.Text,
+				Password:   passwordEntry.Text,
+				InitialDir: strings.TrimSpace(initialDirEntry.Text),
 			}
 
 			// 调用回调
@@ -96,6 +125,88 @@ func (d *ConnectDialog) Show() {
 	)
 
 	// 设置对话框大小
-	formDialog.Resize(fyne.NewSize(400, 300))
+	formDialog.Resize(fyne.NewSize(400, 400))
 	formDialog.Show()
 }
+
+// showConnectDialog 显示连接对话框，连接成功后本面板切换到远程文件系统；
+// 尚未重新连接的标签页预先填写上次的连接信息
+func (p *FilePanel) showConnectDialog() {
+	connectDialog := NewConnectDialog(p.window, func(config *transfer.SFTPConfig) {
+		if err := p.connect(config); err != nil {
+			dialog.ShowError(err, p.window)
+		}
+	})
+	if p.pending != nil {
+		connectDialog.SetDefaults(p.pending)
+	} else if p.remoteConfig != nil {
+		connectDialog.SetDefaults(p.remoteConfig)
+	}
+	connectDialog.Show()
+}
+
+// connect 连接服务器，替换本面板原有的连接并打开初始目录或主目录
+func (p *FilePanel) connect(config *transfer.SFTPConfig) error {
+	// 创建SFTP文件系统并连接服务器
+	remoteFS := transfer.NewSFTPFileSystem(config)
+	if err := remoteFS.Connect(); err != nil {
+		return err
+	}
+
+	// 停止基于旧连接的目录监视、外部编辑和延迟测量
+	p.stopLatencyMonitor()
+	p.stopWatchers()
+	p.closeEditSession()
+
+	// 保存远程文件系统
+	if p.remoteFS != nil {
+		if err := p.remoteFS.Close(); err != nil {
+			dialog.ShowError(fmt.Errorf("关闭连接失败: %v", err), p.window)
+		}
+	}
+	remoteFS.SetTransferOptions(p.transferOpts)
+	p.remoteFS = remoteFS
+	p.remoteConfig = config
+	p.pending = nil
+	p.fileSystem.SetRemoteFS(remoteFS)
+	p.startLatencyMonitor()
+	p.updateTitle()
+
+	// 打开初始目录或主目录
+	dir, notice := startDirectory(remoteFS, config.InitialDir)
+	p.resetCompletion()
+	p.resetPath(dir)
+	if notice != "" {
+		dialog.ShowInformation("初始目录", notice, p.window)
+	}
+	return nil
+}
+
+// startDirectory 返回连接后打开的远程目录：配置了初始目录且可用时使用初始目录，否则使用主目录，
+// 无法获取主目录时使用根目录；notice 说明未能使用预期目录的原因
+func startDirectory(remoteFS transfer.RemoteFS, initialDir string) (dir string, notice string) {
+	// 主目录即 SFTP 会话的初始工作目录（realpath "."）
+	home, err := remoteFS.HomeDir()
+	if err != nil {
+		home = "/"
+		notice = fmt.Sprintf("无法获取主目录，已打开根目录：%v", err)
+	}
+	if initialDir == "" {
+		return home, notice
+	}
+
+	target := initialDir
+	if target == "~" || strings.HasPrefix(target, "~/") {
+		target = home + target[1:]
+	} else if !transfer.RemotePaths.IsAbs(target) {
+		target = transfer.RemotePaths.Join(home, target)
+	}
+	info, err := remoteFS.Stat(target)
+	switch {
+	case err != nil:
+		return home, fmt.Sprintf("初始目录 %s 不可用，已打开 %s：%v", initialDir, home, err)
+	case !info.IsDir:
+		return home, fmt.Sprintf("初始目录 %s 不是目录，已打开 %s", initialDir, home)
+	}
+	return transfer.RemotePaths.Clean(target), ""
+}
@@ -0,0 +1,113 @@
+package gui
+
+import (
+	"path/filepath"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+)
+
+// EnableDrop 接收从系统文件管理器拖放到窗口的文件，按位置分发给对应一侧的当前标签页
+func EnableDrop(window fyne.Window, sides ...*PanelTabs) {
+	window.SetOnDropped(func(pos fyne.Position, uris []fyne.URI) {
+		var paths []string
+		for _, uri := range uris {
+			if uri.Scheme() == "file" {
+				paths = append(paths, uri.Path())
+			}
+		}
+		if len(paths) == 0 {
+			return
+		}
+		for _, side := range sides {
+			panel := side.Active()
+			if panel != nil && containsPosition(panel.container, pos) {
+				panel.dropLocalFiles(paths, panel.dropTarget(pos))
+				return
+			}
+		}
+	})
+}
+
+// Dragged 开始或继续拖动：拖动未选中的项时只选中该项
+func (i *FileListItem) Dragged(event *fyne.DragEvent) {
+	p := i.panel
+	if p.dragging == nil {
+		if i.index >= len(p.currentFiles) {
+			return
+		}
+		if !p.isSelected(i.index) {
+			p.selectOnly(i.index)
+		}
+		p.dragging = p.selectedFiles()
+	}
+	p.dragPos = event.AbsolutePosition
+}
+
+// DragEnd 拖动结束：放到对侧面板时传输到该面板的当前目录或所放置的文件夹
+func (i *FileListItem) DragEnd() {
+	p := i.panel
+	files := p.dragging
+	p.dragging = nil
+	target := p.peer
+	if len(files) == 0 || target == nil || !containsPosition(target.container, p.dragPos) {
+		return
+	}
+
+	transferFiles := func() {
+		if err := target.transferInto(filePaths(files), target.dropTarget(p.dragPos), transfer.Copy); err != nil {
+			dialog.ShowError(err, p.window)
+		}
+	}
+	p.confirmFilters(files, "复制", transferFiles)
+}
+
+// dropTarget 返回放置位置对应的目标目录：文件夹行为该文件夹，否则为当前目录
+func (p *FilePanel) dropTarget(pos fyne.Position) string {
+	if index, ok := p.rowAt(pos); ok && p.currentFiles[index].IsDir {
+		return p.currentFiles[index].Path
+	}
+	return p.GetCurrentPath()
+}
+
+// rowAt 按列表的滚动位置和行高计算绝对坐标所在的行；列表项滚动时会被回收复用，不能按列表项的位置判断
+func (p *FilePanel) rowAt(pos fyne.Position) (int, bool) {
+	if p.rowHeight <= 0 || !containsPosition(p.list, pos) {
+		return 0, false
+	}
+	origin := fyne.CurrentApp().Driver().AbsolutePositionForObject(p.list)
+	// 行之间有一个内边距宽的分隔
+	pitch := p.rowHeight + p.list.Theme().Size(theme.SizeNamePadding)
+	y := pos.Y - origin.Y + p.list.GetScrollOffset()
+	index := int(y / pitch)
+	if y < 0 || index >= len(p.currentFiles) || y-float32(index)*pitch > p.rowHeight {
+		return 0, false
+	}
+	return index, true
+}
+
+// dropLocalFiles 将本地文件加入传输队列放入本面板的目录：远程面板上传，本地面板复制
+func (p *FilePanel) dropLocalFiles(paths []string, targetDir string) {
+	if err := p.checkConnected(); err != nil {
+		dialog.ShowError(err, p.window)
+		return
+	}
+	items := make([]copyItem, len(paths))
+	for i, sourcePath := range paths {
+		items[i] = copyItem{source: sourcePath, target: p.paths().Join(targetDir, filepath.Base(sourcePath))}
+	}
+	p.queueCopy(items, nil, p.transferOpts.Filters, false, nil)
+}
+
+// containsPosition 判断绝对坐标是否位于对象内
+func containsPosition(object fyne.CanvasObject, pos fyne.Position) bool {
+	if object == nil || !object.Visible() {
+		return false
+	}
+	origin := fyne.CurrentApp().Driver().AbsolutePositionForObject(object)
+	size := object.Size()
+	return pos.X >= origin.X && pos.Y >= origin.Y &&
+		pos.X < origin.X+size.Width && pos.Y < origin.Y+size.Height
+}
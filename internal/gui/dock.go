@@ -0,0 +1,98 @@
+package gui
+
+import (
+	"fmt"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+)
+
+// DockTabs 窗口下方的标签页：第一个标签页是传输列表，其后是终端
+type DockTabs struct {
+	window    fyne.Window
+	tabs      *container.DocTabs
+	transfers *container.TabItem
+	terminals map[*container.TabItem]*terminalView
+}
+
+// NewDockTabs 创建下方的标签页，传输列表的标签页不能关闭
+func NewDockTabs(window fyne.Window, transfers *TransferList) *DockTabs {
+	d := &DockTabs{
+		window:    window,
+		transfers: container.NewTabItemWithIcon("传输", theme.DownloadIcon(), transfers.GetContainer()),
+		terminals: make(map[*container.TabItem]*terminalView),
+	}
+	d.tabs = container.NewDocTabs(d.transfers)
+	d.tabs.CloseIntercept = func(item *container.TabItem) {
+		if item != d.transfers {
+			d.closeTerminal(item)
+		}
+	}
+	d.tabs.OnSelected = func(item *container.TabItem) {
+		if view := d.terminals[item]; view != nil {
+			window.Canvas().Focus(view)
+		}
+	}
+	return d
+}
+
+// GetContainer 返回标签页容器
+func (d *DockTabs) GetContainer() fyne.CanvasObject {
+	return d.tabs
+}
+
+// openTerminal 在远程文件系统的连接上打开终端标签页，dir 不为空时进入该目录
+func (d *DockTabs) openTerminal(remoteFS transfer.RemoteFS, name, dir string) {
+	item := container.NewTabItemWithIcon("终端 "+name, theme.ComputerIcon(), nil)
+	// shell 设置的标题优先，退出后加上标记
+	view := newTerminalView(d.window, remoteFS, dir, func(title string, exited bool) {
+		text := "终端 " + name
+		if title != "" {
+			text = title
+		}
+		if exited {
+			text += "（已退出）"
+		}
+		item.Text = text
+		d.tabs.Refresh()
+	})
+	item.Content = view
+	d.terminals[item] = view
+	d.tabs.Append(item)
+	d.tabs.Select(item)
+	d.window.Canvas().Focus(view)
+}
+
+// closeTerminal 关闭终端标签页并结束 shell
+func (d *DockTabs) closeTerminal(item *container.TabItem) {
+	if view := d.terminals[item]; view != nil {
+		view.close()
+		delete(d.terminals, item)
+	}
+	d.tabs.Remove(item)
+	if d.tabs.SelectedIndex() < 0 || d.tabs.SelectedIndex() >= len(d.tabs.Items) {
+		d.tabs.Select(d.transfers)
+	}
+}
+
+// Close 结束全部终端
+func (d *DockTabs) Close() {
+	for _, view := range d.terminals {
+		view.close()
+	}
+}
+
+// openTerminal 在当前目录打开终端，仅支持远程面板
+func (p *FilePanel) openTerminal(dir string) {
+	if p.remoteFS == nil {
+		dialog.ShowError(fmt.Errorf("终端仅支持远程连接"), p.window)
+		return
+	}
+	if p.tabs == nil || p.tabs.dock == nil {
+		return
+	}
+	p.tabs.dock.openTerminal(p.remoteFS, p.Title(), dir)
+}
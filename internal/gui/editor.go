@@ -0,0 +1,474 @@
+package gui
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// diffContext 差异视图中变更前后保留的未变行数
+const diffContext = 3
+
+// diffLimit 逐行比较的最大规模（行数乘积），超过时整段显示为删除和新增
+const diffLimit = 4000000
+
+// editorEntry 编辑器的文本框，在文本框获得焦点时也能响应 Ctrl+S
+type editorEntry struct {
+	widget.Entry
+	onSave func()
+}
+
+// newEditorEntry 创建编辑器文本框
+func newEditorEntry(onSave func()) *editorEntry {
+	e := &editorEntry{onSave: onSave}
+	e.MultiLine = true
+	e.Wrapping = fyne.TextWrapOff
+	e.TextStyle = fyne.TextStyle{Monospace: true}
+	e.ExtendBaseWidget(e)
+	return e
+}
+
+// TypedShortcut 处理保存快捷键，其余交给文本框
+func (e *editorEntry) TypedShortcut(shortcut fyne.Shortcut) {
+	if s, ok := shortcut.(*desktop.CustomShortcut); ok && s.KeyName == fyne.KeyS &&
+		(s.Modifier == fyne.KeyModifierControl || s.Modifier == fyne.KeyModifierSuper) {
+		e.onSave()
+		return
+	}
+	e.Entry.TypedShortcut(shortcut)
+}
+
+// EditorWindow 表示内置文本编辑器窗口
+type EditorWindow struct {
+	window     fyne.Window
+	fileSystem *transfer.FileSystem
+	path       string
+	entry      *editorEntry
+	status     *widget.Label
+	original   string            // 打开或上次保存时的内容
+	baseline   transfer.FileInfo // 打开或上次保存时的文件信息，用于检测外部修改
+	crlf       bool              // 原文件是否全部使用 CRLF 换行
+	endings    []string          // 混用换行时 original 每行原来的换行符，保存时按行还原
+	dirty      bool
+}
+
+// ShowEditor 在内置编辑器中打开本地或远程文本文件；远程文件在独立的通道上读写，
+// 面板之后连接到其他服务器也始终保存到打开时的服务器
+func ShowEditor(fileSystem *transfer.FileSystem, path string, parent fyne.Window) {
+	detached, err := fileSystem.Detach()
+	if err != nil {
+		dialog.ShowError(err, parent)
+		return
+	}
+	e := &EditorWindow{
+		fileSystem: detached,
+		path:       path,
+		status:     widget.NewLabel(""),
+	}
+	text, info, err := e.load()
+	if err != nil {
+		detached.Close()
+		dialog.ShowError(err, parent)
+		return
+	}
+
+	e.window = fyne.CurrentApp().NewWindow("")
+	e.entry = newEditorEntry(e.save)
+	e.entry.OnChanged = func(text string) {
+		e.setDirty(text != e.original)
+	}
+	e.setContent(text, info)
+
+	toolbar := widget.NewToolbar(
+		widget.NewToolbarAction(theme.DocumentSaveIcon(), e.save),
+		widget.NewToolbarAction(theme.ViewRefreshIcon(), e.confirmReload),
+	)
+	e.window.SetContent(container.NewBorder(toolbar, e.status, nil, nil, e.entry))
+	e.window.SetCloseIntercept(func() {
+		if !e.dirty {
+			e.window.Close()
+			return
+		}
+		dialog.ShowConfirm("关闭编辑器", "文件尚未保存，确定要放弃修改吗？", func(confirm bool) {
+			if confirm {
+				e.window.Close()
+			}
+		}, e.window)
+	})
+	e.window.SetOnClosed(func() {
+		e.fileSystem.Close()
+	})
+	e.window.Resize(fyne.NewSize(900, 650))
+	e.window.Show()
+	e.window.Canvas().Focus(e.entry)
+}
+
+// load 读取文件内容和读取前的文件信息；只能编辑大小上限以内的 UTF-8 文本文件
+func (e *EditorWindow) load() (string, transfer.FileInfo, error) {
+	info, err := e.fileSystem.Stat(e.path)
+	if err != nil {
+		return "", info, fmt.Errorf("获取文件信息失败: %v", err)
+	}
+	if info.IsDir {
+		return "", info, fmt.Errorf("%s 是目录", e.path)
+	}
+	if info.Size > previewTextLimit {
+		return "", info, fmt.Errorf("文件超过 %s，无法在编辑器中打开", transfer.FormatSize(previewTextLimit))
+	}
+	data, err := e.readAll()
+	if err != nil {
+		return "", info, err
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "", info, fmt.Errorf("%s 是二进制文件，无法编辑", e.path)
+	}
+	if !utf8.Valid(data) {
+		return "", info, fmt.Errorf("%s 不是 UTF-8 编码，无法编辑", e.path)
+	}
+	return string(data), info, nil
+}
+
+// readAll 读取文件当前内容
+func (e *EditorWindow) readAll() ([]byte, error) {
+	reader, err := e.fileSystem.Open(e.path)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(io.LimitReader(reader, previewTextLimit+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %v", err)
+	}
+	return data, nil
+}
+
+// setContent 用读取到的内容替换编辑器内容，CRLF 换行在编辑时转换为 LF，保存时还原
+func (e *EditorWindow) setContent(text string, info transfer.FileInfo) {
+	e.crlf, e.endings = lineEndings(text)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	e.original = text
+	e.baseline = info
+	e.entry.SetText(text)
+	e.setDirty(false)
+	e.status.SetText(fmt.Sprintf("%s  %s  修改时间 %s", e.path, transfer.FormatSize(info.Size),
+		info.ModTime.Format("2006-01-02 15:04:05")))
+}
+
+// setDirty 更新未保存状态，并在标题中标记
+func (e *EditorWindow) setDirty(dirty bool) {
+	e.dirty = dirty
+	title := "编辑：" + e.path
+	if dirty {
+		title = "* " + title
+	}
+	e.window.SetTitle(title)
+}
+
+// save 保存文件；文件在打开后被其他人修改，或无法确认是否被修改时先询问如何处理
+func (e *EditorWindow) save() {
+	current, err := e.fileSystem.Stat(e.path)
+	if err != nil {
+		dialog.ShowConfirm("文件冲突",
+			fmt.Sprintf("无法获取文件当前的信息，文件可能已被删除或移动：\n%v\n\n仍要保存吗？", err),
+			func(confirm bool) {
+				if confirm {
+					e.write()
+				}
+			}, e.window)
+		return
+	}
+	if current.Size != e.baseline.Size || !current.ModTime.Equal(e.baseline.ModTime) {
+		e.showConflict(current)
+		return
+	}
+	e.write()
+}
+
+// write 原子地写回文件，并以写入后的文件信息作为新的比较基准
+func (e *EditorWindow) write() {
+	text := e.entry.Text
+	data := text
+	if e.crlf {
+		data = strings.ReplaceAll(text, "\n", "\r\n")
+	} else if e.endings != nil {
+		data = restoreLineEndings(e.original, text, e.endings)
+	}
+	if err := e.fileSystem.WriteFile(e.path, []byte(data)); err != nil {
+		dialog.ShowError(fmt.Errorf("保存文件失败: %v", err), e.window)
+		return
+	}
+	info, err := e.fileSystem.Stat(e.path)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("获取文件信息失败: %v", err), e.window)
+		return
+	}
+	e.original = text
+	e.baseline = info
+	e.crlf, e.endings = lineEndings(data)
+	e.setDirty(e.entry.Text != e.original)
+	e.status.SetText(fmt.Sprintf("已保存 %s  %s", e.path, info.ModTime.Format("2006-01-02 15:04:05")))
+}
+
+// showConflict 提示文件已被修改，可以覆盖、查看差异或重新加载
+func (e *EditorWindow) showConflict(current transfer.FileInfo) {
+	message := widget.NewLabel(fmt.Sprintf(
+		"文件在打开后已被修改：\n打开时 %s，修改时间 %s\n现在 %s，修改时间 %s\n\n覆盖保存将丢失对方的修改。",
+		transfer.FormatSize(e.baseline.Size), e.baseline.ModTime.Format("2006-01-02 15:04:05"),
+		transfer.FormatSize(current.Size), current.ModTime.Format("2006-01-02 15:04:05")))
+
+	conflictDialog := dialog.NewCustomWithoutButtons("文件冲突", message, e.window)
+	conflictDialog.SetButtons([]fyne.CanvasObject{
+		widget.NewButton("取消", conflictDialog.Hide),
+		widget.NewButton("查看差异", func() {
+			conflictDialog.Hide()
+			e.showDiff()
+		}),
+		widget.NewButton("重新加载", func() {
+			conflictDialog.Hide()
+			e.reload()
+		}),
+		widget.NewButtonWithIcon("覆盖保存", theme.WarningIcon(), func() {
+			conflictDialog.Hide()
+			e.write()
+		}),
+	})
+	conflictDialog.Show()
+}
+
+// showDiff 显示文件当前内容与编辑器内容的差异
+func (e *EditorWindow) showDiff() {
+	data, err := e.readAll()
+	if err != nil {
+		dialog.ShowError(err, e.window)
+		return
+	}
+	remote := strings.ReplaceAll(string(data), "\r\n", "\n")
+	lines := diffLines(strings.Split(remote, "\n"), strings.Split(e.entry.Text, "\n"))
+
+	removed := &widget.CustomTextGridStyle{FGColor: theme.ErrorColor()}
+	added := &widget.CustomTextGridStyle{FGColor: theme.SuccessColor()}
+	omitted := &widget.CustomTextGridStyle{FGColor: theme.DisabledColor()}
+	grid := widget.NewTextGrid()
+	for _, line := range lines {
+		var style widget.TextGridStyle
+		switch line.op {
+		case '-':
+			style = removed
+		case '+':
+			style = added
+		case '~':
+			style = omitted
+		}
+		text := strings.ReplaceAll(string(line.op)+" "+line.text, "\t", "    ")
+		row := widget.TextGridRow{Style: style}
+		for _, r := range text {
+			row.Cells = append(row.Cells, widget.TextGridCell{Rune: r})
+		}
+		grid.Rows = append(grid.Rows, row)
+	}
+	if len(grid.Rows) == 0 {
+		grid.SetText("内容相同")
+	}
+
+	diffWindow := fyne.CurrentApp().NewWindow("差异：" + e.path)
+	diffWindow.SetContent(container.NewBorder(
+		widget.NewLabel("- 文件当前内容    + 编辑器中的内容"), nil, nil, nil,
+		container.NewScroll(grid),
+	))
+	diffWindow.Resize(fyne.NewSize(900, 600))
+	diffWindow.Show()
+}
+
+// confirmReload 重新加载文件，有未保存的修改时先确认
+func (e *EditorWindow) confirmReload() {
+	if !e.dirty {
+		e.reload()
+		return
+	}
+	dialog.ShowConfirm("重新加载", "重新加载将丢失未保存的修改，确定继续吗？", func(confirm bool) {
+		if confirm {
+			e.reload()
+		}
+	}, e.window)
+}
+
+// reload 重新读取文件内容
+func (e *EditorWindow) reload() {
+	text, info, err := e.load()
+	if err != nil {
+		dialog.ShowError(err, e.window)
+		return
+	}
+	e.setContent(text, info)
+}
+
+// diffLine 差异中的一行，op 为 ' '（相同）、'-'（删除）、'+'（新增）或 '~'（省略的相同行）
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// lineEndings 检查文本的换行符：全部为 CRLF 时 crlf 为 true；CRLF 与 LF 混用时返回每行的换行符，
+// 最后一行之后没有换行符，不计入
+func lineEndings(text string) (crlf bool, endings []string) {
+	total, withCR := strings.Count(text, "\n"), strings.Count(text, "\r\n")
+	if withCR == 0 {
+		return false, nil
+	}
+	if withCR == total {
+		return true, nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	endings = make([]string, 0, total)
+	for _, line := range lines[:len(lines)-1] {
+		if strings.HasSuffix(line, "\r\n") {
+			endings = append(endings, "\r\n")
+		} else {
+			endings = append(endings, "\n")
+		}
+	}
+	return false, endings
+}
+
+// restoreLineEndings 将编辑后的文本按行还原换行符：与原内容相同的行保留原来的换行符，新增的行使用 LF
+func restoreLineEndings(original, text string, endings []string) string {
+	a, b := strings.Split(original, "\n"), strings.Split(text, "\n")
+	var sb strings.Builder
+	i, j := 0, 0
+	for _, line := range alignLines(a, b) {
+		switch line.op {
+		case '-':
+			i++
+			continue
+		case ' ':
+			i++
+		}
+		sb.WriteString(b[j])
+		if j < len(b)-1 {
+			if line.op == ' ' && i-1 < len(endings) {
+				sb.WriteString(endings[i-1])
+			} else {
+				sb.WriteString("\n")
+			}
+		}
+		j++
+	}
+	return sb.String()
+}
+
+// diffLines 逐行比较两段文本，只保留变更附近 diffContext 行的上下文
+func diffLines(a, b []string) []diffLine {
+	return collapseDiff(alignLines(a, b))
+}
+
+// alignLines 逐行比较两段文本，返回完整的对齐结果
+func alignLines(a, b []string) []diffLine {
+	// 去掉相同的开头和结尾，只比较中间部分
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	am, bm := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	var lines []diffLine
+	for _, text := range a[:prefix] {
+		lines = append(lines, diffLine{' ', text})
+	}
+	if len(am)*len(bm) > diffLimit {
+		for _, text := range am {
+			lines = append(lines, diffLine{'-', text})
+		}
+		for _, text := range bm {
+			lines = append(lines, diffLine{'+', text})
+		}
+	} else {
+		lines = append(lines, diffLCS(am, bm)...)
+	}
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, diffLine{' ', text})
+	}
+	return lines
+}
+
+// diffLCS 按最长公共子序列比较
+func diffLCS(a, b []string) []diffLine {
+	// lcs[i][j] 为 a[i:] 和 b[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	return lines
+}
+
+// collapseDiff 将远离变更的相同行折叠为一行省略提示；没有变更时返回nil
+func collapseDiff(lines []diffLine) []diffLine {
+	keep := make([]bool, len(lines))
+	changed := false
+	for i, line := range lines {
+		if line.op == ' ' {
+			continue
+		}
+		changed = true
+		for k := max(0, i-diffContext); k <= min(len(lines)-1, i+diffContext); k++ {
+			keep[k] = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	var result []diffLine
+	for i := 0; i < len(lines); {
+		if keep[i] {
+			result = append(result, lines[i])
+			i++
+			continue
+		}
+		start := i
+		for i < len(lines) && !keep[i] {
+			i++
+		}
+		result = append(result, diffLine{'~', fmt.Sprintf("…… 省略 %d 行相同内容", i-start)})
+	}
+	return result
+}
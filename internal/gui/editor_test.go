@@ -0,0 +1,142 @@
+package gui
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// numberedLines 返回 "1" 到 "n" 的行
+func numberedLines(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprint(i + 1)
+	}
+	return lines
+}
+
+// formatDiff 将差异转换为 "操作+内容" 形式的文字，便于比较
+func formatDiff(lines []diffLine) []string {
+	var result []string
+	for _, line := range lines {
+		result = append(result, string(line.op)+line.text)
+	}
+	return result
+}
+
+func TestDiffLines(t *testing.T) {
+	changed := numberedLines(20)
+	changed[9] = "十"
+
+	tests := []struct {
+		name string
+		a, b []string
+		want []string
+	}{
+		{name: "内容相同", a: []string{"x", "y"}, b: []string{"x", "y"}, want: nil},
+		{name: "两端为空", a: nil, b: nil, want: nil},
+		{name: "新增到空文件", a: nil, b: []string{"x"}, want: []string{"+x"}},
+		{name: "清空文件", a: []string{"x"}, b: nil, want: []string{"-x"}},
+		{name: "替换一行", a: []string{"x"}, b: []string{"y"}, want: []string{"-x", "+y"}},
+		{
+			name: "中间插入",
+			a:    []string{"1", "2", "3"},
+			b:    []string{"1", "2", "新", "3"},
+			want: []string{" 1", " 2", "+新", " 3"},
+		},
+		{
+			name: "开头删除和末尾新增",
+			a:    []string{"a", "b", "c"},
+			b:    []string{"b", "c", "d"},
+			want: []string{"-a", " b", " c", "+d"},
+		},
+		{
+			name: "远离变更的相同行被折叠",
+			a:    numberedLines(20),
+			b:    changed,
+			want: []string{
+				"~…… 省略 6 行相同内容",
+				" 7", " 8", " 9", "-10", "+十", " 11", " 12", " 13",
+				"~…… 省略 7 行相同内容",
+			},
+		},
+		{
+			name: "相邻的变更共用上下文",
+			a:    []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"},
+			b:    []string{"1", "二", "3", "4", "5", "6", "7", "八", "9"},
+			want: []string{" 1", "-2", "+二", " 3", " 4", " 5", " 6", " 7", "-8", "+八", " 9"},
+		},
+	}
+	for _, tt := range tests {
+		if got := formatDiff(diffLines(tt.a, tt.b)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: 得到 %q，期望 %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDiffLinesLimit(t *testing.T) {
+	// 超过逐行比较的规模时，中间部分整体显示为删除和新增
+	n := 2001
+	a, b := make([]string, n), make([]string, n)
+	for i := range a {
+		a[i] = fmt.Sprintf("a%d", i)
+		b[i] = fmt.Sprintf("b%d", i)
+	}
+	a = append([]string{"头"}, a...)
+	b = append([]string{"头"}, b...)
+	lines := diffLines(a, b)
+	if len(lines) != 2*n+1 {
+		t.Fatalf("得到 %d 行，期望 %d 行", len(lines), 2*n+1)
+	}
+	if lines[0].op != ' ' || lines[1].op != '-' || lines[n].op != '-' || lines[n+1].op != '+' || lines[2*n].op != '+' {
+		t.Errorf("删除和新增的顺序不正确")
+	}
+}
+
+func TestLineEndings(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		crlf    bool
+		endings []string
+	}{
+		{name: "空文件", text: ""},
+		{name: "只有 LF", text: "a\nb\n"},
+		{name: "只有 CRLF", text: "a\r\nb\r\n", crlf: true},
+		{name: "CRLF 且末尾没有换行", text: "a\r\nb", crlf: true},
+		{name: "混用", text: "a\r\nb\nc\r\nd", endings: []string{"\r\n", "\n", "\r\n"}},
+		{name: "单独的 CR 不算换行", text: "a\rb\n", endings: nil},
+	}
+	for _, tt := range tests {
+		crlf, endings := lineEndings(tt.text)
+		if crlf != tt.crlf || !reflect.DeepEqual(endings, tt.endings) {
+			t.Errorf("%s: 得到 %v, %q，期望 %v, %q", tt.name, crlf, endings, tt.crlf, tt.endings)
+		}
+	}
+}
+
+func TestRestoreLineEndings(t *testing.T) {
+	raw := "a\r\nb\nc\r\nd"
+	_, endings := lineEndings(raw)
+	original := strings.ReplaceAll(raw, "\r\n", "\n")
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "未修改", text: original, want: raw},
+		{name: "插入的行使用 LF", text: "a\nx\nb\nc\nd", want: "a\r\nx\nb\nc\r\nd"},
+		{name: "删除一行", text: "a\nc\nd", want: "a\r\nc\r\nd"},
+		{name: "修改一行", text: "a\nB\nc\nd", want: "a\r\nB\nc\r\nd"},
+		{name: "删除开头", text: "b\nc\nd", want: "b\nc\r\nd"},
+		{name: "末尾追加换行", text: "a\nb\nc\nd\n", want: "a\r\nb\nc\r\nd\n"},
+		{name: "清空", text: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := restoreLineEndings(original, tt.text, endings); got != tt.want {
+			t.Errorf("%s: 得到 %q，期望 %q", tt.name, got, tt.want)
+		}
+	}
+}
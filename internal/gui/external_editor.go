@@ -0,0 +1,253 @@
+package gui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// externalEditorSettings 外部编辑器设置
+type externalEditorSettings struct {
+	Command string `json:"command"` // 启动命令，{file} 替换为文件路径，不含 {file} 时路径追加在最后
+}
+
+// defaultEditorCommand 返回系统默认的打开命令
+func defaultEditorCommand() string {
+	switch runtime.GOOS {
+	case "windows":
+		return "notepad {file}"
+	case "darwin":
+		return "open -t {file}"
+	}
+	return "xdg-open {file}"
+}
+
+// editorSettingsPath 返回外部编辑器设置文件路径
+func editorSettingsPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "xftp798", "editor.json"), nil
+}
+
+// loadEditorSettings 读取外部编辑器设置，文件不存在时使用系统默认命令
+func loadEditorSettings() (externalEditorSettings, error) {
+	settings := externalEditorSettings{Command: defaultEditorCommand()}
+	settingsPath, err := editorSettingsPath()
+	if err != nil {
+		return settings, nil
+	}
+	data, err := os.ReadFile(settingsPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, fmt.Errorf("读取编辑器设置失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return settings, fmt.Errorf("解析编辑器设置失败: %v", err)
+	}
+	return settings, nil
+}
+
+// saveEditorSettings 保存外部编辑器设置
+func saveEditorSettings(settings externalEditorSettings) error {
+	settingsPath, err := editorSettingsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(settingsPath), 0755); err != nil {
+		return fmt.Errorf("创建配置目录失败: %v", err)
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(settingsPath, data, 0644); err != nil {
+		return fmt.Errorf("保存编辑器设置失败: %v", err)
+	}
+	return nil
+}
+
+// splitCommand 按 shell 的规则拆分命令行：空白分隔参数，单引号或双引号内的空白保留，
+// 反斜杠只转义紧随其后的引号，其余情况原样保留，以便直接写 Windows 路径
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false // 当前参数已开始，"" 也算一个空参数
+	var quote rune // 当前所在的引号，0表示不在引号内
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\''):
+			i++
+			arg.WriteRune(runes[i])
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("编辑器命令中的引号不匹配")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// launchEditor 用外部命令打开本地文件，不等待命令结束
+func launchEditor(command, localPath string) error {
+	args, err := splitCommand(command)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("编辑器命令不能为空")
+	}
+	replaced := false
+	for i, arg := range args {
+		if strings.Contains(arg, "{file}") {
+			args[i] = strings.ReplaceAll(arg, "{file}", localPath)
+			replaced = true
+		}
+	}
+	if !replaced {
+		args = append(args, localPath)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("启动编辑器失败: %v", err)
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// showOpenWith 选择外部编辑器命令并打开文件；远程文件先下载到编辑会话的临时目录，保存后自动上传
+func (p *FilePanel) showOpenWith(file transfer.FileInfo) {
+	settings, err := loadEditorSettings()
+	if err != nil {
+		dialog.ShowError(err, p.window)
+	}
+
+	commandEntry := widget.NewEntry()
+	commandEntry.SetText(settings.Command)
+	commandEntry.SetPlaceHolder("如 code --wait {file}")
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("命令", commandEntry),
+		widget.NewFormItem("", widget.NewLabel("{file} 会替换为文件路径，省略时路径追加在命令最后")),
+	}
+	formDialog := dialog.NewForm("打开方式", "打开", "取消", items, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		command := strings.TrimSpace(commandEntry.Text)
+		if command != settings.Command {
+			if err := saveEditorSettings(externalEditorSettings{Command: command}); err != nil {
+				dialog.ShowError(err, p.window)
+			}
+		}
+		if err := p.openExternal(command, file); err != nil {
+			dialog.ShowError(err, p.window)
+		}
+	}, p.window)
+	formDialog.Resize(fyne.NewSize(500, 220))
+	formDialog.Show()
+}
+
+// openExternal 用外部编辑器打开文件
+func (p *FilePanel) openExternal(command string, file transfer.FileInfo) error {
+	if p.remoteFS == nil {
+		return launchEditor(command, file.Path)
+	}
+
+	if p.editSession == nil {
+		// 会话在独立的通道上下载和上传，不与面板的目录列表等操作共用
+		worker, err := p.remoteFS.Share()
+		if err != nil {
+			return err
+		}
+		session, err := transfer.NewEditSession(worker, p.handleEditEvent)
+		if err != nil {
+			worker.Close()
+			return err
+		}
+		p.editSession = session
+	}
+	localPath, err := p.editSession.Open(file.Path)
+	if err != nil {
+		return err
+	}
+	return launchEditor(command, localPath)
+}
+
+// handleEditEvent 处理外部编辑器保存后的上传结果
+func (p *FilePanel) handleEditEvent(event transfer.EditEvent) {
+	switch {
+	case event.Err == nil:
+		fyne.CurrentApp().SendNotification(fyne.NewNotification("已上传", event.RemotePath))
+		if transfer.RemotePaths.Dir(event.RemotePath) == p.GetCurrentPath() {
+			p.RefreshFiles()
+		}
+	case errors.Is(event.Err, transfer.ErrEditConflict):
+		session := p.editSession
+		fyne.CurrentApp().SendNotification(fyne.NewNotification("上传冲突", event.RemotePath))
+		dialog.ShowConfirm("文件冲突",
+			fmt.Sprintf("%s 在打开后已在服务器上被修改。\n\n是否用本地编辑的内容覆盖？选择“否”将暂不上传，下次保存时会再次询问。", event.RemotePath),
+			func(confirm bool) {
+				if !confirm || session != p.editSession {
+					return
+				}
+				if err := session.Upload(event.LocalPath, true); err != nil {
+					dialog.ShowError(err, p.window)
+					return
+				}
+				fyne.CurrentApp().SendNotification(fyne.NewNotification("已上传", event.RemotePath))
+			}, p.window)
+	default:
+		fyne.CurrentApp().SendNotification(fyne.NewNotification("上传失败", event.RemotePath))
+		dialog.ShowError(fmt.Errorf("上传 %s 失败: %v", event.RemotePath, event.Err), p.window)
+	}
+}
+
+// closeEditSession 结束外部编辑会话并删除临时文件
+func (p *FilePanel) closeEditSession() {
+	if p.editSession == nil {
+		return
+	}
+	if err := p.editSession.Close(); err != nil {
+		dialog.ShowError(fmt.Errorf("清理临时文件失败: %v", err), p.window)
+	}
+	p.editSession = nil
+}
@@ -0,0 +1,35 @@
+package gui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{name: "空命令", command: "  ", want: nil},
+		{name: "空白分隔", command: "code  --wait\t{file}", want: []string{"code", "--wait", "{file}"}},
+		{name: "双引号中的空格", command: `"C:\Program Files\Notepad++\notepad++.exe" {file}`, want: []string{`C:\Program Files\Notepad++\notepad++.exe`, "{file}"}},
+		{name: "单引号", command: `'/opt/my editor/bin/edit' -n {file}`, want: []string{"/opt/my editor/bin/edit", "-n", "{file}"}},
+		{name: "参数中间的引号", command: `--title="a b" {file}`, want: []string{"--title=a b", "{file}"}},
+		{name: "空参数", command: `edit "" {file}`, want: []string{"edit", "", "{file}"}},
+		{name: "转义引号", command: `edit "say \"hi\"" {file}`, want: []string{"edit", `say "hi"`, "{file}"}},
+		{name: "UNC路径", command: `\\server\share\edit.exe {file}`, want: []string{`\\server\share\edit.exe`, "{file}"}},
+		{name: "引号不匹配", command: `"C:\Program Files\edit.exe {file}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := splitCommand(tt.command)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: 错误为 %v，期望出错 %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: 得到 %q，期望 %q", tt.name, got, tt.want)
+		}
+	}
+}
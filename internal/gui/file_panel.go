@@ -1,9 +1,12 @@
 package gui
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"xftp798/internal/transfer"
 
 	"fyne.io/fyne/v2"
@@ -18,16 +21,48 @@ import (
 // FilePanel 表示文件面板
 type FilePanel struct {
 	container    *fyne.Container
-	list         *widget.List
-	pathEntry    *widget.Entry
+	list         *fileList
+	pathEntry    *pathInput
 	fileSystem   *transfer.FileSystem
 	currentFiles []transfer.FileInfo
+	listing      []transfer.FileInfo // 当前目录的全部文件，currentFiles 为过滤后的结果
+	filterEntry  *widget.Entry       // 快速过滤输入框
 	window       fyne.Window
-	selectedItem int
+	selected     map[string]bool // 选中项的路径
+	cursor       int             // 键盘光标所在项
+	anchor       int             // 范围选择的起点，-1表示无
 	progressBar  *widget.ProgressBar
-	transferMgr  *transfer.TransferManager
-	onTransfer   func(source string, targetPanel *FilePanel, transferType transfer.TransferType)
+	transfers    *TransferList // 传输列表
+	onTransfer   func(sources []string, targetPanel *FilePanel, transferType transfer.TransferType)
 	remoteFS     transfer.RemoteFS
+	transferOpts transfer.TransferOptions
+	peer         *FilePanel
+	compare      *compareState
+	watchers     []*transfer.Watcher
+	editSession  *transfer.EditSession // 外部编辑器打开的远程文件
+	rowHeight    float32               // 列表项的高度，与列表一样取第一个创建的列表项，用于定位拖放目标
+	dragging     []transfer.FileInfo   // 正在拖动的文件
+	dragPos      fyne.Position         // 拖动的最新位置
+	header       *fyne.Container       // 列标题
+	columns      columnSettings        // 列宽、隐藏的列和排序方式
+	columnsKey   string                // 保存列设置使用的面板标识
+	statusConn   *widget.Label         // 状态栏：连接信息
+	statusCounts *widget.Label         // 状态栏：项目和选中统计
+	statusSpace  *widget.Label         // 状态栏：可用空间
+	latencyStop  chan struct{}         // 停止测量连接延迟
+	keymap       keymap                // 快捷键
+	history      navHistory            // 导航历史
+
+	backAction       *widget.ToolbarAction // 后退按钮
+	forwardAction    *widget.ToolbarAction // 前进按钮
+	breadcrumb       *fyne.Container       // 面包屑路径栏
+	breadcrumbScroll *container.Scroll
+	completion       *pathCompletion // 路径补全
+
+	tabs         *PanelTabs           // 面板所在的一侧标签页，可以为nil
+	tab          *container.TabItem   // 面板所在的标签页
+	remoteConfig *transfer.SFTPConfig // 当前连接的连接信息
+	pending      *transfer.SFTPConfig // 恢复后尚未重新连接的远程标签页的连接信息
 }
 
 // FileListItem 自定义列表项
@@ -40,6 +75,10 @@ type FileListItem struct {
 	name     *widget.Label
 	size     *widget.Label
 	modTime  *widget.Label
+	fileType *widget.Label
+	mode     *widget.Label
+	owner    *widget.Label
+	status   *widget.Label
 	selected bool
 }
 
@@ -66,8 +105,15 @@ func NewFileListItem(panel *FilePanel, file transfer.FileInfo, index int) *FileL
 
 	// 创建标签
 	item.name = widget.NewLabel(file.Name)
-	item.size = widget.NewLabel(formatSize(file.Size))
+	item.size = widget.NewLabel(transfer.FormatSize(file.Size))
 	item.modTime = widget.NewLabel(file.ModTime.Format("2006-01-02 15:04:05"))
+	item.fileType = widget.NewLabel(fileType(file))
+	item.mode = widget.NewLabel(file.Mode.String())
+	item.owner = widget.NewLabel(file.Owner)
+	item.status = widget.NewLabel("")
+	for _, label := range []*widget.Label{item.name, item.size, item.modTime, item.fileType, item.mode, item.owner} {
+		label.Truncation = fyne.TextTruncateEllipsis
+	}
 
 	item.ExtendBaseWidget(item)
 	return item
@@ -87,8 +133,12 @@ func (i *FileListItem) CreateRenderer() fyne.WidgetRenderer {
 	bg := canvas.NewRectangle(bgColor)
 	bg.Hide()
 
-	// 创建内容容器，添加内边距
-	content := container.NewHBox(i.icon, i.name, i.size, i.modTime)
+	// 创建内容容器，按列宽排列，添加内边距
+	nameCell := container.NewBorder(nil, nil, i.icon, nil, i.name)
+	content := &fyne.Container{
+		Layout:  &columnLayout{panel: i.panel},
+		Objects: []fyne.CanvasObject{nameCell, i.size, i.modTime, i.fileType, i.mode, i.owner, i.status},
+	}
 	container := container.NewPadded(content)
 
 	// 创建渲染器
@@ -131,38 +181,43 @@ func (r *FileListItemRenderer) Objects() []fyne.CanvasObject {
 func (r *FileListItemRenderer) Destroy() {
 }
 
-// Tapped 处理点击事件
+// Tapped 处理点击事件：Ctrl 切换选中，Shift 范围选择，单击目录进入
 func (i *FileListItem) Tapped(_ *fyne.PointEvent) {
-	i.panel.selectedItem = -1
-	i.panel.list.Refresh()
-
-	i.panel.selectedItem = i.index
-	i.selected = true
-	i.Refresh()
+	p := i.panel
+	if i.index >= len(p.currentFiles) {
+		return
+	}
+	p.window.Canvas().Focus(p.list)
 
-	if i.file.IsDir {
-		i.panel.SetPath(i.file.Path)
-		i.panel.selectedItem = -1
-		i.selected = false
-		i.Refresh()
+	modifiers := currentModifiers()
+	switch {
+	case modifiers&fyne.KeyModifierShift != 0:
+		p.selectRange(i.index)
+	case isToggleModifier(modifiers):
+		p.toggleSelect(i.index)
+	case i.file.IsDir:
+		p.SetPath(i.file.Path)
+	default:
+		p.selectOnly(i.index)
 	}
 }
 
-// TappedSecondary 处理右键点击事件
+// TappedSecondary 处理右键点击事件，点击未选中的项时只选中该项
 func (i *FileListItem) TappedSecondary(e *fyne.PointEvent) {
-	i.panel.selectedItem = -1
-	i.panel.list.Refresh()
-
-	i.panel.selectedItem = i.index
-	i.selected = true
-	i.Refresh()
+	p := i.panel
+	if i.index >= len(p.currentFiles) {
+		return
+	}
+	if !p.isSelected(i.index) {
+		p.selectOnly(i.index)
+	}
 
-	i.panel.showContextMenu(i.file, e.AbsolutePosition)
+	p.showContextMenu(p.selectedFiles(), e.AbsolutePosition)
 }
 
 // MinSize 返回最小尺寸
 func (i *FileListItem) MinSize() fyne.Size {
-	return fyne.NewSize(400, 40)
+	return fyne.NewSize(fyne.Max(400, i.panel.columns.totalWidth()), 40)
 }
 
 // NewFilePanel 创建新的文件面板
@@ -170,221 +225,243 @@ func NewFilePanel(window fyne.Window) *FilePanel {
 	panel := &FilePanel{
 		window:       window,
 		fileSystem:   transfer.NewFileSystem(""),
-		selectedItem: -1,
+		selected:     make(map[string]bool),
+		cursor:       -1,
+		anchor:       -1,
+		transferOpts: transfer.DefaultTransferOptions(),
+		columns:      defaultColumnSettings(),
+		keymap:       panelKeymap(window),
 	}
 
 	// 创建进度条
 	panel.progressBar = widget.NewProgressBar()
 	panel.progressBar.Hide()
 
-	// 创建传输管理器
-	panel.transferMgr = transfer.NewTransferManager(func(progress transfer.TransferProgress) {
-		panel.progressBar.Value = progress.Percentage / 100
-		if progress.IsCompleted {
-			panel.progressBar.Hide()
-			panel.RefreshFiles()
-		} else {
-			panel.progressBar.Show()
-		}
-	})
-
 	// 创建路径输入框
-	panel.pathEntry = widget.NewEntry()
+	panel.pathEntry = newPathInput(panel)
 	panel.pathEntry.SetText(panel.fileSystem.GetCurrentPath())
 	panel.pathEntry.OnSubmitted = func(path string) {
-		panel.SetPath(path)
+		panel.SetPath(panel.expandHome(path))
+		panel.Focus()
+	}
+
+	// 创建快速过滤输入框
+	panel.filterEntry = widget.NewEntry()
+	panel.filterEntry.SetPlaceHolder("快速过滤当前目录")
+	panel.filterEntry.OnChanged = func(string) {
+		panel.applyListing()
 	}
 
 	// 创建文件列表
-	panel.list = &widget.List{
-		Length: func() int {
-			return len(panel.currentFiles)
-		},
-		CreateItem: func() fyne.CanvasObject {
-			return NewFileListItem(panel, transfer.FileInfo{}, 0)
-		},
-		UpdateItem: func(id widget.ListItemID, item fyne.CanvasObject) {
-			if id >= len(panel.currentFiles) {
-				return
-			}
-			fileItem := item.(*FileListItem)
-			fileItem.file = panel.currentFiles[id]
-			fileItem.index = int(id)
-			fileItem.selected = int(id) == panel.selectedItem
-
-			// 更新图标
-			if fileItem.file.IsDir {
-				fileItem.icon.SetResource(theme.FolderIcon())
-			} else {
-				fileItem.icon.SetResource(theme.FileIcon())
-			}
+	panel.list = newFileList(panel)
+	panel.list.Length = func() int {
+		return len(panel.currentFiles)
+	}
+	panel.list.CreateItem = func() fyne.CanvasObject {
+		item := NewFileListItem(panel, transfer.FileInfo{}, 0)
+		if panel.rowHeight == 0 {
+			panel.rowHeight = item.MinSize().Height
+		}
+		return item
+	}
+	panel.list.UpdateItem = func(id widget.ListItemID, item fyne.CanvasObject) {
+		if id >= len(panel.currentFiles) {
+			return
+		}
+		fileItem := item.(*FileListItem)
+		fileItem.file = panel.currentFiles[id]
+		fileItem.index = int(id)
+		fileItem.selected = panel.isSelected(int(id))
 
-			// 更新标签
-			fileItem.name.SetText(fileItem.file.Name)
-			fileItem.size.SetText(formatSize(fileItem.file.Size))
-			fileItem.modTime.SetText(fileItem.file.ModTime.Format("2006-01-02 15:04:05"))
-		},
-		OnUnselected: func(id widget.ListItemID) {
-			panel.selectedItem = -1
-		},
+		// 更新图标
+		if fileItem.file.IsDir {
+			fileItem.icon.SetResource(theme.FolderIcon())
+		} else {
+			fileItem.icon.SetResource(theme.FileIcon())
+		}
+
+		// 更新标签
+		fileItem.name.SetText(fileItem.file.Name)
+		fileItem.size.SetText(transfer.FormatSize(fileItem.file.Size))
+		fileItem.modTime.SetText(fileItem.file.ModTime.Format("2006-01-02 15:04:05"))
+		fileItem.fileType.SetText(fileType(fileItem.file))
+		fileItem.mode.SetText(fileItem.file.Mode.String())
+		fileItem.owner.SetText(fileItem.file.Owner)
+
+		// 更新比较结果
+		fileItem.status.SetText("")
+		if panel.compare != nil {
+			if status, ok := panel.compare.status(panel, fileItem.file); ok {
+				fileItem.status.Importance = compareImportance(status)
+				fileItem.status.SetText(status.String())
+			}
+		}
+		fileItem.Refresh()
 	}
 
+	// 导航按钮
+	panel.backAction = widget.NewToolbarAction(theme.NavigateBackIcon(), panel.GoBack)
+	panel.forwardAction = widget.NewToolbarAction(theme.NavigateNextIcon(), panel.GoForward)
+
 	// 创建工具栏
 	toolbar := widget.NewToolbar(
 		// 添加连接按钮
-		widget.NewToolbarAction(theme.ComputerIcon(), func() {
-			connectDialog := NewConnectDialog(panel.window, func(config *transfer.SFTPConfig) {
-				// 创建SFTP文件系统
-				remoteFS := transfer.NewSFTPFileSystem(config)
-
-				// 连接服务器
-				if err := remoteFS.Connect(); err != nil {
-					dialog.ShowError(err, panel.window)
-					return
-				}
-
-				// 保存远程文件系统
-				if panel.remoteFS != nil {
-					if err := panel.remoteFS.Close(); err != nil {
-						dialog.ShowError(fmt.Errorf("关闭连接失败: %v", err), panel.window)
-					}
-				}
-				panel.remoteFS = remoteFS
-				panel.fileSystem.SetRemoteFS(remoteFS)
-
-				// 切换到远程根目录
-				panel.SetPath("/")
-			})
-			connectDialog.Show()
+		widget.NewToolbarAction(theme.ComputerIcon(), panel.showConnectDialog),
+		// 标签页
+		widget.NewToolbarAction(theme.ContentAddIcon(), func() {
+			if panel.tabs != nil {
+				pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(panel.breadcrumbScroll)
+				panel.tabs.showTabMenu(panel, pos)
+			}
 		}),
 		widget.NewToolbarSeparator(),
 		// 添加返回上一级按钮
-		widget.NewToolbarAction(theme.NavigateBackIcon(), func() {
-			currentPath := panel.fileSystem.GetCurrentPath()
-			parentPath := filepath.Dir(currentPath)
-			panel.SetPath(parentPath)
+		panel.backAction,
+		panel.forwardAction,
+		widget.NewToolbarAction(theme.MoveUpIcon(), panel.goParent),
+		widget.NewToolbarAction(theme.HistoryIcon(), func() {
+			pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(panel.breadcrumbScroll)
+			panel.showRecentMenu(pos)
 		}),
 		widget.NewToolbarSeparator(),
 		widget.NewToolbarAction(theme.ViewRefreshIcon(), func() {
 			panel.RefreshFiles()
 		}),
-		widget.NewToolbarAction(theme.FolderNewIcon(), func() {
-			entry := widget.NewEntry()
-			entry.SetPlaceHolder("输入文件夹名称")
-			dialog.ShowForm("新建文件夹",
-				"创建",
-				"取消",
-				[]*widget.FormItem{
-					widget.NewFormItem("名称", entry),
-				},
-				func(confirm bool) {
-					if confirm {
-						path := filepath.Join(panel.fileSystem.GetCurrentPath(), entry.Text)
-						if err := panel.fileSystem.CreateDirectory(path); err != nil {
-							dialog.ShowError(err, panel.window)
-						} else {
-							panel.RefreshFiles()
-						}
-					}
-				},
-				panel.window,
-			)
+		widget.NewToolbarAction(theme.FolderNewIcon(), panel.showMkdirDialog),
+		// 选择
+		widget.NewToolbarAction(theme.CheckButtonCheckedIcon(), func() {
+			pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(panel.list)
+			panel.showSelectMenu(pos)
+		}),
+		widget.NewToolbarSeparator(),
+		// 比较目录
+		widget.NewToolbarAction(theme.VisibilityIcon(), func() {
+			panel.toggleCompare()
+		}),
+		// 目录同步
+		widget.NewToolbarAction(theme.MediaReplayIcon(), func() {
+			panel.showSyncDialog()
+		}),
+		// 监视目录并自动上传
+		widget.NewToolbarAction(theme.UploadIcon(), func() {
+			panel.showWatchDialog()
+		}),
+		// 搜索
+		widget.NewToolbarAction(theme.SearchIcon(), func() {
+			NewSearchDialog(panel.window, panel).Show()
+		}),
+		// 远程内容搜索
+		widget.NewToolbarAction(theme.FileTextIcon(), func() {
+			if panel.remoteFS == nil {
+				dialog.ShowError(fmt.Errorf("内容搜索仅支持远程目录"), panel.window)
+				return
+			}
+			NewGrepDialog(panel.window, panel.remoteFS, panel.GetCurrentPath()).Show()
+		}),
+		// 终端
+		widget.NewToolbarAction(theme.DesktopIcon(), func() {
+			panel.openTerminal(panel.GetCurrentPath())
+		}),
+		// 传输设置
+		widget.NewToolbarAction(theme.SettingsIcon(), func() {
+			panel.showTransferSettings()
 		}),
 	)
 
+	// 创建列标题
+	panel.header = panel.newColumnHeader()
+	panel.refreshColumns()
+
 	// 组合所有元素
 	panel.container = container.NewBorder(
 		container.NewVBox(
 			panel.pathEntry,
+			panel.completion.box,
+			panel.newBreadcrumb(),
 			toolbar,
 			panel.progressBar,
+			container.NewBorder(nil, nil, widget.NewIcon(theme.SearchIcon()), nil, panel.filterEntry),
+			panel.header,
 		),
-		nil, nil, nil,
+		panel.newStatusBar(),
+		nil, nil,
 		container.NewScroll(panel.list),
 	)
 
 	// 初始加载文件列表
-	panel.RefreshFiles()
+	panel.resetPath(panel.GetCurrentPath())
 
 	return panel
 }
 
-// showContextMenu 显示右键菜单
-func (p *FilePanel) showContextMenu(file transfer.FileInfo, pos fyne.Position) {
+// showContextMenu 显示右键菜单，复制、剪切、删除和属性作用于全部选中项
+func (p *FilePanel) showContextMenu(files []transfer.FileInfo, pos fyne.Position) {
+	if len(files) == 0 {
+		return
+	}
+	file := files[0]
+	single := len(files) == 1
+
 	// 创建菜单项
 	var menuItems []*fyne.MenuItem
 
 	// 打开/进入
-	if file.IsDir {
+	if single && file.IsDir {
 		menuItems = append(menuItems, fyne.NewMenuItem("进入", func() {
 			p.SetPath(file.Path)
 		}))
-	} else {
+		if p.remoteFS != nil {
+			menuItems = append(menuItems, fyne.NewMenuItem("在此打开终端", func() {
+				p.openTerminal(file.Path)
+			}))
+		}
+	} else if single {
 		menuItems = append(menuItems, fyne.NewMenuItem("打开", func() {
-			// 这里可以添加打开文件的逻辑
-			dialog.ShowInformation("提示", "此功能暂未实现", p.window)
+			ShowPreview(p.fileSystem, file.Path, 0, p.window)
+		}))
+		menuItems = append(menuItems, fyne.NewMenuItem("编辑", func() {
+			ShowEditor(p.fileSystem, file.Path, p.window)
+		}))
+		menuItems = append(menuItems, fyne.NewMenuItem("打开方式...", func() {
+			p.showOpenWith(file)
 		}))
 	}
 
 	// 复制
 	menuItems = append(menuItems, fyne.NewMenuItem("复制", func() {
-		if p.onTransfer != nil {
-			p.onTransfer(file.Path, p, transfer.Copy)
-		}
+		p.transferFiles(files, transfer.Copy)
 	}))
 
-	// 剪切
-	menuItems = append(menuItems, fyne.NewMenuItem("剪切", func() {
-		if p.onTransfer != nil {
-			p.onTransfer(file.Path, p, transfer.Move)
-		}
+	// 复制差异
+	if p.compare != nil {
+		menuItems = append(menuItems, fyne.NewMenuItem("复制差异到对侧", func() {
+			p.copyDifferences(files)
+		}))
+	}
+
+	// 移动
+	menuItems = append(menuItems, fyne.NewMenuItem("移动", func() {
+		p.transferFiles(files, transfer.Move)
 	}))
 
 	// 删除
 	menuItems = append(menuItems, fyne.NewMenuItem("删除", func() {
-		dialog.ShowConfirm("删除确认",
-			fmt.Sprintf("确定要删除 %s 吗？", file.Name),
-			func(confirm bool) {
-				if confirm {
-					if err := p.fileSystem.DeleteFile(file.Path); err != nil {
-						dialog.ShowError(err, p.window)
-					} else {
-						p.selectedItem = -1
-						p.RefreshFiles()
-					}
-				}
-			},
-			p.window,
-		)
+		p.confirmDelete(files)
 	}))
 
 	// 重命名
-	menuItems = append(menuItems, fyne.NewMenuItem("重命名", func() {
-		entry := widget.NewEntry()
-		entry.SetText(file.Name)
-		dialog.ShowForm("重命名",
-			"确定",
-			"取消",
-			[]*widget.FormItem{
-				widget.NewFormItem("新名称", entry),
-			},
-			func(confirm bool) {
-				if confirm {
-					oldPath := file.Path
-					newPath := filepath.Join(filepath.Dir(file.Path), entry.Text)
-					if err := os.Rename(oldPath, newPath); err != nil {
-						dialog.ShowError(err, p.window)
-					} else {
-						p.RefreshFiles()
-					}
-				}
-			},
-			p.window,
-		)
-	}))
+	if single {
+		menuItems = append(menuItems, fyne.NewMenuItem("重命名", func() {
+			p.showRenameDialog(file)
+		}))
+	}
 
 	// 属性
 	menuItems = append(menuItems, fyne.NewMenuItem("属性", func() {
+		if !single {
+			dialog.ShowInformation("文件属性", describeSelection(files), p.window)
+			return
+		}
 		info := fmt.Sprintf(
 			"名称：%s\n"+
 				"类型：%s\n"+
@@ -398,7 +475,7 @@ func (p *FilePanel) showContextMenu(file transfer.FileInfo, pos fyne.Position) {
 				}
 				return "文件"
 			}(),
-			formatSize(file.Size),
+			transfer.FormatSize(file.Size),
 			file.ModTime.Format("2006-01-02 15:04:05"),
 			file.Path,
 		)
@@ -410,18 +487,90 @@ func (p *FilePanel) showContextMenu(file transfer.FileInfo, pos fyne.Position) {
 	widget.ShowPopUpMenuAtPosition(menu, p.window.Canvas(), pos)
 }
 
+// filePaths 返回文件路径列表
+func filePaths(files []transfer.FileInfo) []string {
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.Path
+	}
+	return paths
+}
+
+// describeFiles 返回用于提示的名称：单个文件为文件名，多个为数量
+func describeFiles(files []transfer.FileInfo) string {
+	if len(files) == 1 {
+		return files[0].Name
+	}
+	return fmt.Sprintf("%d 个项目", len(files))
+}
+
+// describeSelection 汇总多个选中项的数量和大小
+func describeSelection(files []transfer.FileInfo) string {
+	var dirs, regular int
+	var size int64
+	for _, file := range files {
+		if file.IsDir {
+			dirs++
+		} else {
+			regular++
+			size += file.Size
+		}
+	}
+	return fmt.Sprintf(
+		"已选中：%d 个项目\n"+
+			"文件夹：%d 个\n"+
+			"文件：%d 个\n"+
+			"文件总大小：%s（不含文件夹内容）",
+		len(files), dirs, regular, transfer.FormatSize(size),
+	)
+}
+
 // GetContainer 返回面板的容器
 func (p *FilePanel) GetContainer() fyne.CanvasObject {
 	return p.container
 }
 
-// SetPath 设置当前路径
+// SetPath 进入指定路径并记录导航历史
 func (p *FilePanel) SetPath(path string) {
+	p.history.visit(p.GetCurrentPath(), path)
+	p.showPath(path)
+}
+
+// showPath 显示指定路径，并清空快速过滤
+func (p *FilePanel) showPath(path string) {
 	p.fileSystem.SetCurrentPath(path)
 	p.pathEntry.SetText(path)
+	p.hideCompletion()
+	p.filterEntry.SetText("")
+	p.updateBreadcrumb()
+	p.updateNavigation()
 	p.RefreshFiles()
 }
 
+// Title 返回标签页标题：远程为连接名称，本地为“本地”
+func (p *FilePanel) Title() string {
+	switch {
+	case p.remoteConfig != nil:
+		return p.remoteConfig.DisplayName()
+	case p.pending != nil:
+		return p.pending.DisplayName() + "（未连接）"
+	default:
+		return "本地"
+	}
+}
+
+// updateTitle 连接变化后更新所在标签页
+func (p *FilePanel) updateTitle() {
+	if p.tabs != nil {
+		p.tabs.update(p)
+	}
+}
+
+// paths 返回当前文件系统的路径规则
+func (p *FilePanel) paths() transfer.Paths {
+	return p.fileSystem.Paths()
+}
+
 // GetCurrentPath 获取当前路径
 func (p *FilePanel) GetCurrentPath() string {
 	return p.fileSystem.GetCurrentPath()
@@ -434,67 +583,519 @@ func (p *FilePanel) RefreshFiles() {
 		dialog.ShowError(err, p.window)
 		return
 	}
-	p.selectedItem = -1
-	p.currentFiles = files
-	p.list.Refresh()
+	p.listing = files
+	p.applyListing()
+	p.updateDiskSpace()
+}
+
+// applyListing 按比较模式和快速过滤条件生成显示的文件列表
+func (p *FilePanel) applyListing() {
+	files := append([]transfer.FileInfo(nil), p.listing...)
+	p.currentFiles = filterQuick(p.filterCompare(files), p.filterEntry.Text)
+	sortFiles(p.currentFiles, p.columns)
+	p.pruneSelection()
+	p.refreshSelection()
+}
+
+// filterQuick 按快速过滤文本筛选文件：含通配符时按通配符匹配，否则按包含匹配，不区分大小写
+func filterQuick(files []transfer.FileInfo, text string) []transfer.FileInfo {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "" {
+		return files
+	}
+	filtered := files[:0]
+	for _, file := range files {
+		name := strings.ToLower(file.Name)
+		var ok bool
+		if strings.ContainsAny(text, "*?[") {
+			ok, _ = path.Match(text, name)
+		} else {
+			ok = strings.Contains(name, text)
+		}
+		if ok {
+			filtered = append(filtered, file)
+		}
+	}
+	return filtered
 }
 
 // SetTransferCallback 设置传输回调
-func (p *FilePanel) SetTransferCallback(callback func(source string, targetPanel *FilePanel, transferType transfer.TransferType)) {
+func (p *FilePanel) SetTransferCallback(callback func(sources []string, targetPanel *FilePanel, transferType transfer.TransferType)) {
 	p.onTransfer = callback
 }
 
-// HandleTransfer 处理文件传输
-func (p *FilePanel) HandleTransfer(sourcePath string, transferType transfer.TransferType) error {
-	targetPath := filepath.Join(p.GetCurrentPath(), filepath.Base(sourcePath))
+// HandleTransfer 将一批文件作为一个任务传输到本面板的当前目录，单项失败不影响其余项
+func (p *FilePanel) HandleTransfer(sourcePaths []string, transferType transfer.TransferType) error {
+	return p.transferInto(sourcePaths, p.GetCurrentPath(), transferType)
+}
+
+// transferInto 将对侧面板中的一批文件作为一个任务加入传输队列，传输到本面板的 targetDir 目录
+func (p *FilePanel) transferInto(sourcePaths []string, targetDir string, transferType transfer.TransferType) error {
+	sourceRules := transfer.LocalPaths
+	if sourcePanel := p.getSourcePanel(); sourcePanel != nil {
+		sourceRules = sourcePanel.paths()
+	}
+	items := make([]copyItem, len(sourcePaths))
+	for i, sourcePath := range sourcePaths {
+		items[i] = copyItem{source: sourcePath, target: p.paths().Join(targetDir, sourceRules.Base(sourcePath))}
+	}
+	return p.transferTo(items, transferType, nil)
+}
 
+// transferTo 将源面板中的一批文件作为一个任务加入传输队列，传输到本面板的指定路径；done 在任务结束时调用，可以为nil。
+// 复制和移动都使用源面板的过滤规则，与确认时显示的规则一致
+func (p *FilePanel) transferTo(items []copyItem, transferType transfer.TransferType, done func(error)) error {
+	sourcePanel := p.getSourcePanel()
+	if sourcePanel == nil {
+		return fmt.Errorf("不支持本地文件传输")
+	}
+	// 未连接的远程标签页没有文件系统，不能当作本地目录
+	if err := p.checkConnected(); err != nil {
+		return err
+	}
+	if err := sourcePanel.checkConnected(); err != nil {
+		return err
+	}
+	if sourcePanel.remoteFS != nil && p.remoteFS != nil {
+		// 上传和下载的一端必须是本地路径
+		return fmt.Errorf("不支持在两个远程面板之间直接传输，请先下载到本地")
+	}
+	rules := sourcePanel.transferOpts.Filters
 	switch transferType {
 	case transfer.Copy:
-		if p.remoteFS != nil {
-			return p.remoteFS.UploadFile(sourcePath, targetPath, func(current, total int64) {
-				p.progressBar.Value = float64(current) / float64(total)
-				p.progressBar.Show()
-				if current == total {
-					p.progressBar.Hide()
-					p.RefreshFiles()
-				}
+		p.queueCopy(items, sourcePanel.remoteFS, rules, false, done)
+		return nil
+	case transfer.Move:
+		// 每项复制成功后按同样的过滤规则删除源，被规则排除、没有复制的内容不会删除；部分失败时保留源
+		p.queueCopy(items, sourcePanel.remoteFS, rules, true, func(err error) {
+			sourcePanel.RefreshFiles()
+			if done != nil {
+				done(err)
+			}
+		})
+		return nil
+	default:
+		return fmt.Errorf("未知的传输类型")
+	}
+}
+
+// deleteWithRules 按指定的过滤规则删除文件或目录，remoteFS 为nil时删除本地文件
+func deleteWithRules(remoteFS transfer.RemoteFS, path string, rules transfer.FilterRules) error {
+	if remoteFS == nil {
+		fileSystem := transfer.NewFileSystem(filepath.Dir(path))
+		fileSystem.SetFilterRules(rules)
+		return fileSystem.DeleteFile(path)
+	}
+	return withShared(remoteFS, func(worker transfer.RemoteFS) error {
+		setFilterRules(worker, rules)
+		return worker.DeleteFile(path)
+	})
+}
+
+// queueDelete 将删除作为一个任务加入传输队列，按本面板的过滤规则删除，不阻塞界面；单项失败不影响其余项
+func (p *FilePanel) queueDelete(files []transfer.FileInfo) {
+	remoteFS := p.remoteFS
+	rules := p.transferOpts.Filters
+	deleteEach := func(stop <-chan struct{}, progress func(current, total int64), remove func(path string) error) error {
+		var errs []error
+		for _, file := range files {
+			select {
+			case <-stop:
+				return transfer.ErrCanceled
+			default:
+			}
+			if err := remove(file.Path); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", file.Path, err))
+			}
+			// 每项删除后报告一次，使暂停可以生效
+			progress(0, 0)
+		}
+		return errors.Join(errs...)
+	}
+	run := func(stop <-chan struct{}, progress func(current, total int64)) error {
+		if remoteFS == nil {
+			return deleteEach(stop, progress, func(path string) error {
+				return deleteWithRules(nil, path, rules)
 			})
-		} else {
-			sourcePanel := p.getSourcePanel()
-			if sourcePanel != nil && sourcePanel.remoteFS != nil {
-				return sourcePanel.remoteFS.DownloadFile(sourcePath, targetPath, func(current, total int64) {
-					p.progressBar.Value = float64(current) / float64(total)
-					p.progressBar.Show()
-					if current == total {
-						p.progressBar.Hide()
-						p.RefreshFiles()
-					}
-				})
+		}
+		return withShared(remoteFS, func(worker transfer.RemoteFS) error {
+			setFilterRules(worker, rules)
+			return deleteEach(stop, progress, worker.DeleteFile)
+		})
+	}
+	finish := func(err error) {
+		p.RefreshFiles()
+		if err != nil && !errors.Is(err, transfer.ErrCanceled) {
+			dialog.ShowError(err, p.window)
+		}
+	}
+
+	if p.transfers == nil {
+		finish(run(nil, func(current, total int64) {}))
+		return
+	}
+	source := "删除 " + files[0].Path
+	if len(files) > 1 {
+		source = fmt.Sprintf("删除 %s 等 %d 项", files[0].Path, len(files))
+	}
+	p.transfers.Add(source, "", -1, p, run, finish)
+}
+
+// setFilterRules 设置共享通道的过滤规则，不影响面板的连接
+func setFilterRules(worker transfer.RemoteFS, rules transfer.FilterRules) {
+	opts := worker.GetTransferOptions()
+	opts.Filters = rules
+	worker.SetTransferOptions(opts)
+}
+
+// copyItem 批量复制中的一项
+type copyItem struct {
+	source string
+	target string
+}
+
+// queueCopy 将一批复制作为一个任务加入传输队列：本面板为远程时上传，源为远程时下载，否则在本地复制。
+// sourceRemote 为源所在的远程连接，本地源为nil，两端不能都是远程；rules 为递归复制时的过滤规则；
+// move 为 true 时每项复制成功后按同样的规则删除源
+func (p *FilePanel) queueCopy(items []copyItem, sourceRemote transfer.RemoteFS, rules transfer.FilterRules, move bool, done func(error)) {
+	if len(items) == 0 {
+		return
+	}
+	var reportFS transfer.RemoteFS
+	var run func(stop <-chan struct{}, progress func(current, total int64)) error
+	var measure func() (int64, error)
+	// removeLocal 移动时删除复制成功的本地源
+	removeLocal := func(item copyItem) error {
+		return deleteWithRules(nil, item.source, rules)
+	}
+	measureLocal := func() (int64, error) {
+		var total int64
+		for _, item := range items {
+			size, err := localItemSize(item.source, rules)
+			if err != nil {
+				return 0, err
 			}
-			return fmt.Errorf("不支持本地文件传输")
+			total += size
+		}
+		return total, nil
+	}
+
+	switch {
+	case p.remoteFS != nil:
+		remoteFS := p.remoteFS
+		reportFS = remoteFS
+		run = func(stop <-chan struct{}, progress func(current, total int64)) error {
+			return withShared(remoteFS, func(worker transfer.RemoteFS) error {
+				setFilterRules(worker, rules)
+				worker.SetStop(stop)
+				return p.runCopyItems(items, transfer.Upload, true, func(item copyItem) error {
+					return worker.UploadFile(item.source, item.target, progress)
+				}, move, removeLocal)
+			})
+		}
+		measure = measureLocal
+	case sourceRemote != nil:
+		reportFS = sourceRemote
+		run = func(stop <-chan struct{}, progress func(current, total int64)) error {
+			return withShared(sourceRemote, func(worker transfer.RemoteFS) error {
+				setFilterRules(worker, rules)
+				worker.SetStop(stop)
+				return p.runCopyItems(items, transfer.Download, true, func(item copyItem) error {
+					return worker.DownloadFile(item.source, item.target, progress)
+				}, move, func(item copyItem) error {
+					return worker.DeleteFile(item.source)
+				})
+			})
+		}
+		measure = func() (total int64, err error) {
+			err = withShared(sourceRemote, func(worker transfer.RemoteFS) error {
+				setFilterRules(worker, rules)
+				for _, item := range items {
+					info, err := worker.Stat(item.source)
+					if err != nil {
+						return err
+					}
+					size := info.Size
+					if info.IsDir {
+						if size, err = worker.TreeSize(item.source); err != nil {
+							return err
+						}
+					}
+					total += size
+				}
+				return nil
+			})
+			return total, err
 		}
-	case transfer.Move:
-		return fmt.Errorf("暂不支持移动文件")
 	default:
-		return fmt.Errorf("未知的传输类型")
+		run = func(stop <-chan struct{}, progress func(current, total int64)) error {
+			manager := transfer.NewTransferManager(func(tp transfer.TransferProgress) {
+				progress(tp.TransferredSize, tp.TotalSize)
+			})
+			manager.SetFilterRules(rules)
+			manager.SetStop(stop)
+			return p.runCopyItems(items, transfer.Upload, false, func(item copyItem) error {
+				return manager.Transfer(item.source, filepath.Dir(item.target), transfer.Copy)
+			}, move, removeLocal)
+		}
+		measure = measureLocal
+	}
+
+	bar := p.transferProgress()
+	runWithBar := func(stop <-chan struct{}, progress func(current, total int64)) error {
+		return run(stop, func(current, total int64) {
+			progress(current, total)
+			bar(current, total)
+		})
+	}
+	finish := func(err error) {
+		p.progressBar.Hide()
+		p.RefreshFiles()
+		var report *transfer.TransferReport
+		if errors.As(err, &report) && reportFS != nil {
+			p.showTransferReport(reportFS, report, bar)
+		}
+		if done != nil {
+			done(err)
+		}
+	}
+
+	if p.transfers == nil {
+		// 没有传输列表时直接传输
+		err := runWithBar(nil, func(current, total int64) {})
+		finish(err)
+		return
+	}
+	source := items[0].source
+	if len(items) > 1 {
+		source = fmt.Sprintf("%s 等 %d 项", source, len(items))
+	}
+	id := p.transfers.Add(source, items[0].target, -1, p, runWithBar, finish)
+	// 总大小在后台统计，目录需要遍历；完成后列表显示大小和剩余时间，统计失败时保持未知
+	go func() {
+		if total, err := measure(); err == nil {
+			p.transfers.SetSize(id, total)
+		}
+	}()
+}
+
+// localItemSize 返回本地文件的大小，目录为其中符合过滤规则的文件总大小
+func localItemSize(path string, rules transfer.FilterRules) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	if info.IsDir() {
+		return transfer.LocalTreeSize(path, rules)
+	}
+	return info.Size(), nil
+}
+
+// runCopyItems 依次复制每一项，单项失败不影响其余项，取消或连接断开时立即返回。
+// move 为 true 时一项完整复制后调用 remove 删除源；远程批量传输的失败项合并为传输报告以便重试，其余错误合并返回
+func (p *FilePanel) runCopyItems(items []copyItem, direction transfer.TransferDirection, remote bool, copyOne func(item copyItem) error, move bool, remove func(item copyItem) error) error {
+	report := &transfer.TransferReport{}
+	var errs []error
+	for _, item := range items {
+		err := copyOne(item)
+		var sub *transfer.TransferReport
+		switch {
+		case errors.Is(err, transfer.ErrCanceled) || errors.Is(err, transfer.ErrClosed):
+			return err
+		case err == nil:
+			report.Succeeded++
+			if !move {
+				continue
+			}
+			if err := remove(item); err != nil {
+				err = fmt.Errorf("已复制到 %s，但删除源失败: %v", item.target, err)
+				dialog.ShowError(err, p.window)
+				errs = append(errs, err)
+			}
+		case errors.As(err, &sub):
+			// 目录部分失败，合并其报告
+			report.Succeeded += sub.Succeeded
+			report.Failures = append(report.Failures, sub.Failures...)
+		case remote && len(items) > 1:
+			report.Failures = append(report.Failures, transfer.TransferFailure{
+				Source:    item.source,
+				Target:    item.target,
+				Direction: direction,
+				Err:       err,
+			})
+		case len(items) > 1:
+			errs = append(errs, fmt.Errorf("%s: %v", item.source, err))
+		default:
+			errs = append(errs, err)
+		}
+	}
+	if report.HasFailures() {
+		errs = append(errs, report)
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+// withShared 在共享同一 SSH 连接的新通道上执行传输：不阻塞面板的目录列表，
+// 任务开始后关闭标签页也不会中断传输
+func withShared(remoteFS transfer.RemoteFS, fn func(worker transfer.RemoteFS) error) error {
+	worker, err := remoteFS.Share()
+	if err != nil {
+		return err
+	}
+	defer worker.Close()
+	return fn(worker)
+}
+
+// SetTransferList 设置两侧面板共用的传输列表
+func (p *FilePanel) SetTransferList(transfers *TransferList) {
+	p.transfers = transfers
+}
+
+// transferProgress 返回更新本面板进度条的进度回调
+func (p *FilePanel) transferProgress() func(current, total int64) {
+	return func(current, total int64) {
+		p.progressBar.Value = float64(current) / float64(total)
+		p.progressBar.Show()
+		if current == total {
+			p.progressBar.Hide()
+			p.RefreshFiles()
+		}
+	}
+}
+
+// handleTransferResult 处理传输结果，部分失败时显示传输报告
+func (p *FilePanel) handleTransferResult(remoteFS transfer.RemoteFS, err error, progress func(current, total int64)) error {
+	var report *transfer.TransferReport
+	if !errors.As(err, &report) {
+		return err
+	}
+	p.progressBar.Hide()
+	p.RefreshFiles()
+	p.showTransferReport(remoteFS, report, progress)
+	return nil
+}
+
+// showTransferReport 显示传输报告，可仅重试失败项
+func (p *FilePanel) showTransferReport(remoteFS transfer.RemoteFS, report *transfer.TransferReport, progress func(current, total int64)) {
+	failures := widget.NewList(
+		func() int {
+			return len(report.Failures)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			failure := report.Failures[id]
+			item.(*widget.Label).SetText(fmt.Sprintf("%s: %v", failure.Source, failure.Err))
+		},
+	)
+
+	summary := widget.NewLabel(fmt.Sprintf("成功 %d 个，失败 %d 个", report.Succeeded, len(report.Failures)))
+	content := container.NewBorder(summary, nil, nil, nil, failures)
+
+	reportDialog := dialog.NewCustomConfirm("传输报告", "仅重试失败项", "关闭", content, func(retry bool) {
+		if !retry {
+			return
+		}
+		err := remoteFS.RetryFailures(report, progress)
+		if err := p.handleTransferResult(remoteFS, err, progress); err != nil {
+			dialog.ShowError(err, p.window)
+			return
+		}
+		p.RefreshFiles()
+	}, p.window)
+	reportDialog.Resize(fyne.NewSize(600, 400))
+	reportDialog.Show()
+}
+
+// SetPeer 设置对侧面板
+func (p *FilePanel) SetPeer(peer *FilePanel) {
+	p.peer = peer
+}
+
+// checkConnected 恢复后尚未重新连接的远程标签页不能传输或操作文件，返回提示先连接的错误
+func (p *FilePanel) checkConnected() error {
+	if p.pending != nil {
+		return fmt.Errorf("%s 未连接，请先连接", p.pending.DisplayName())
 	}
+	return nil
 }
 
 // getSourcePanel 获取源面板
 func (p *FilePanel) getSourcePanel() *FilePanel {
-	return nil
+	return p.peer
+}
+
+// localRemotePanels 返回本面板与对侧面板中的本地面板和远程面板
+func (p *FilePanel) localRemotePanels() (*FilePanel, *FilePanel, error) {
+	if err := p.checkConnected(); err != nil {
+		return nil, nil, err
+	}
+	if p.peer != nil {
+		if err := p.peer.checkConnected(); err != nil {
+			return nil, nil, err
+		}
+	}
+	localPanel, remotePanel := p.peer, p
+	if p.remoteFS == nil {
+		localPanel, remotePanel = p, p.peer
+	}
+	if localPanel == nil || remotePanel == nil || remotePanel.remoteFS == nil || localPanel.remoteFS != nil {
+		return nil, nil, fmt.Errorf("需要一侧为本地目录、另一侧为远程目录")
+	}
+	return localPanel, remotePanel, nil
+}
+
+// showSyncDialog 在本地面板与远程面板之间打开目录同步对话框
+func (p *FilePanel) showSyncDialog() {
+	localPanel, remotePanel, err := p.localRemotePanels()
+	if err != nil {
+		dialog.ShowError(err, p.window)
+		return
+	}
+
+	remoteFS := remotePanel.remoteFS
+	progress := p.transferProgress()
+	NewSyncDialog(p.window, remoteFS, localPanel.GetCurrentPath(), remotePanel.GetCurrentPath(), progress, func(err error) {
+		localPanel.RefreshFiles()
+		remotePanel.RefreshFiles()
+		if err := p.handleTransferResult(remoteFS, err, progress); err != nil {
+			dialog.ShowError(err, p.window)
+		}
+	}).Show()
+}
+
+// showWatchDialog 监视本地面板的目录，并自动上传到远程面板的目录
+func (p *FilePanel) showWatchDialog() {
+	localPanel, remotePanel, err := p.localRemotePanels()
+	if err != nil {
+		dialog.ShowError(err, p.window)
+		return
+	}
+
+	NewWatchDialog(p.window, remotePanel.remoteFS, localPanel.GetCurrentPath(), remotePanel.GetCurrentPath(), func(watcher *transfer.Watcher) {
+		remotePanel.watchers = append(remotePanel.watchers, watcher)
+	}).Show()
 }
 
-// formatSize 格式化文件大小显示
-func formatSize(size int64) string {
-	const unit = 1024
-	if size < unit {
-		return fmt.Sprintf("%d B", size)
+// Close 结束面板的会话：停止目录监视，清理外部编辑的临时文件并断开连接
+func (p *FilePanel) Close() {
+	p.stopLatencyMonitor()
+	p.stopWatchers()
+	p.closeEditSession()
+	if p.remoteFS != nil {
+		p.remoteFS.Close()
+		p.remoteFS = nil
 	}
-	div, exp := int64(unit), 0
-	for n := size / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+}
+
+// stopWatchers 停止本面板连接上的所有目录监视
+func (p *FilePanel) stopWatchers() {
+	for _, watcher := range p.watchers {
+		watcher.Close()
 	}
-	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+	p.watchers = nil
 }
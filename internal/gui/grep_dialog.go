@@ -0,0 +1,162 @@
+package gui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// GrepDialog 表示远程内容搜索对话框
+type GrepDialog struct {
+	window   fyne.Window
+	remoteFS transfer.RemoteFS
+	root     string
+}
+
+// NewGrepDialog 创建新的内容搜索对话框
+func NewGrepDialog(window fyne.Window, remoteFS transfer.RemoteFS, root string) *GrepDialog {
+	return &GrepDialog{
+		window:   window,
+		remoteFS: remoteFS,
+		root:     root,
+	}
+}
+
+// Show 显示搜索条件对话框
+func (d *GrepDialog) Show() {
+	rootEntry := widget.NewEntry()
+	rootEntry.SetText(d.root)
+
+	patternEntry := widget.NewEntry()
+	patternEntry.SetPlaceHolder("要搜索的内容")
+	regexCheck := widget.NewCheck("使用正则表达式", nil)
+	ignoreCaseCheck := widget.NewCheck("忽略大小写", nil)
+
+	includeEntry := widget.NewEntry()
+	includeEntry.SetPlaceHolder("如 *.conf，为空表示全部文件")
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("搜索目录", rootEntry),
+		widget.NewFormItem("内容", patternEntry),
+		widget.NewFormItem("", regexCheck),
+		widget.NewFormItem("", ignoreCaseCheck),
+		widget.NewFormItem("文件名", includeEntry),
+	}
+
+	formDialog := dialog.NewForm(
+		"搜索文件内容",
+		"搜索",
+		"取消",
+		items,
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+			if patternEntry.Text == "" {
+				dialog.ShowError(fmt.Errorf("搜索内容不能为空"), d.window)
+				return
+			}
+			d.start(rootEntry.Text, transfer.GrepOptions{
+				Pattern:    patternEntry.Text,
+				Regex:      regexCheck.Checked,
+				IgnoreCase: ignoreCaseCheck.Checked,
+				Include:    strings.TrimSpace(includeEntry.Text),
+			})
+		},
+		d.window,
+	)
+	formDialog.Resize(fyne.NewSize(500, 320))
+	formDialog.Show()
+}
+
+// start 开始搜索，并打开结果窗口，结果边搜索边显示
+func (d *GrepDialog) start(root string, options transfer.GrepOptions) {
+	fileSystem := transfer.NewFileSystem(root)
+	fileSystem.SetRemoteFS(d.remoteFS)
+
+	var matches []transfer.GrepMatch
+	var mu sync.Mutex
+
+	resultList := widget.NewList(
+		func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(matches)
+		},
+		func() fyne.CanvasObject {
+			location := widget.NewLabel("")
+			location.TextStyle = fyne.TextStyle{Bold: true}
+			preview := widget.NewLabel("")
+			preview.TextStyle = fyne.TextStyle{Monospace: true}
+			preview.Truncation = fyne.TextTruncateEllipsis
+			return container.NewBorder(nil, nil, location, nil, preview)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			mu.Lock()
+			defer mu.Unlock()
+			if id >= len(matches) {
+				return
+			}
+			match := matches[id]
+			row := item.(*fyne.Container)
+			row.Objects[1].(*widget.Label).SetText(fmt.Sprintf("%s:%d", match.Path, match.Line))
+			row.Objects[0].(*widget.Label).SetText(strings.TrimSpace(match.Text))
+		},
+	)
+
+	resultWindow := fyne.CurrentApp().NewWindow("内容搜索：" + options.Pattern)
+	resultList.OnSelected = func(id widget.ListItemID) {
+		mu.Lock()
+		match := matches[id]
+		mu.Unlock()
+		resultList.Unselect(id)
+		ShowPreview(fileSystem, match.Path, match.Line, resultWindow)
+	}
+
+	status := widget.NewLabel("正在搜索...")
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	stopSearch := func() {
+		stopOnce.Do(func() { close(stop) })
+	}
+	stopButton := widget.NewButton("停止", stopSearch)
+
+	resultWindow.SetOnClosed(stopSearch)
+	resultWindow.SetContent(container.NewBorder(
+		status,
+		container.NewHBox(widget.NewLabel("单击结果预览文件并定位到匹配行"), stopButton),
+		nil, nil,
+		resultList,
+	))
+	resultWindow.Resize(fyne.NewSize(900, 500))
+	resultWindow.Show()
+
+	go func() {
+		started := time.Now()
+		err := d.remoteFS.Grep(root, options, func(match transfer.GrepMatch) {
+			mu.Lock()
+			matches = append(matches, match)
+			count := len(matches)
+			mu.Unlock()
+			status.SetText(fmt.Sprintf("正在搜索... 已找到 %d 处", count))
+			resultList.Refresh()
+		}, stop)
+
+		mu.Lock()
+		count := len(matches)
+		mu.Unlock()
+		stopButton.Disable()
+		if err != nil {
+			status.SetText(fmt.Sprintf("搜索失败: %v", err))
+			return
+		}
+		status.SetText(fmt.Sprintf("搜索结束，找到 %d 处，用时 %s", count, time.Since(started).Round(time.Millisecond)))
+	}()
+}
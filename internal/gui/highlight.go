@@ -0,0 +1,215 @@
+package gui
+
+import (
+	"image/color"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// syntaxRules 一种语言的高亮规则
+type syntaxRules struct {
+	keywords     map[string]bool
+	lineComments []string
+	blockComment [2]string
+	quotes       string
+}
+
+// words 将空格分隔的单词转换为集合
+func words(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(text) {
+		set[word] = true
+	}
+	return set
+}
+
+var (
+	goSyntax = &syntaxRules{
+		keywords:     words("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var true false nil iota"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+	}
+	cSyntax = &syntaxRules{
+		keywords:     words("auto break case catch char class const continue default delete do double else enum export extends extern false final finally float for function if implements import int interface let long namespace new null private protected public return short signed static struct super switch this throw true try typedef union unsigned var void volatile while"),
+		lineComments: []string{"//"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "\"'`",
+	}
+	pythonSyntax = &syntaxRules{
+		keywords:     words("and as assert async await break class continue def del elif else except False finally for from global if import in is lambda None nonlocal not or pass raise return True try while with yield"),
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+	}
+	shellSyntax = &syntaxRules{
+		keywords:     words("if then else elif fi case esac for while until do done in function return local export set unset echo exit source"),
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+	}
+	configSyntax = &syntaxRules{
+		keywords:     words("true false yes no on off null"),
+		lineComments: []string{"#", ";"},
+		quotes:       "\"'",
+	}
+	sqlSyntax = &syntaxRules{
+		keywords:     words("select from where insert into values update set delete create table drop alter index and or not null join left right inner outer on group by order having limit as distinct primary key SELECT FROM WHERE INSERT INTO VALUES UPDATE SET DELETE CREATE TABLE DROP ALTER INDEX AND OR NOT NULL JOIN LEFT RIGHT INNER OUTER ON GROUP BY ORDER HAVING LIMIT AS DISTINCT PRIMARY KEY"),
+		lineComments: []string{"--"},
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       "'\"",
+	}
+	jsonSyntax = &syntaxRules{
+		keywords: words("true false null"),
+		quotes:   "\"",
+	}
+)
+
+// syntaxByExt 按扩展名选择高亮规则
+var syntaxByExt = map[string]*syntaxRules{
+	".go": goSyntax,
+	".c":  cSyntax, ".h": cSyntax, ".cpp": cSyntax, ".hpp": cSyntax, ".cc": cSyntax,
+	".java": cSyntax, ".js": cSyntax, ".ts": cSyntax, ".rs": cSyntax, ".cs": cSyntax, ".php": cSyntax,
+	".py": pythonSyntax,
+	".sh": shellSyntax, ".bash": shellSyntax, ".zsh": shellSyntax,
+	".yaml": configSyntax, ".yml": configSyntax, ".toml": configSyntax, ".ini": configSyntax,
+	".conf": configSyntax, ".cfg": configSyntax, ".properties": configSyntax, ".env": configSyntax,
+	".sql":  sqlSyntax,
+	".json": jsonSyntax,
+}
+
+// syntaxFor 返回文件对应的高亮规则，未知类型返回nil
+func syntaxFor(name string) *syntaxRules {
+	if rules, ok := syntaxByExt[strings.ToLower(filepath.Ext(name))]; ok {
+		return rules
+	}
+	switch strings.ToLower(filepath.Base(name)) {
+	case "dockerfile", "makefile", ".bashrc", ".profile", ".gitignore", ".xftpignore":
+		return shellSyntax
+	}
+	return nil
+}
+
+// highlightStyles 各类记号的颜色
+type highlightStyles struct {
+	keyword, str, comment, number widget.TextGridStyle
+}
+
+// newHighlightStyles 按当前主题创建记号样式
+func newHighlightStyles() highlightStyles {
+	style := func(c color.Color) widget.TextGridStyle {
+		return &widget.CustomTextGridStyle{FGColor: c}
+	}
+	return highlightStyles{
+		keyword: style(theme.PrimaryColor()),
+		str:     style(theme.SuccessColor()),
+		comment: style(theme.DisabledColor()),
+		number:  style(theme.WarningColor()),
+	}
+}
+
+// highlightRows 将文本转换为带语法高亮的 TextGrid 行，rules 为nil时不高亮
+func highlightRows(text string, rules *syntaxRules) []widget.TextGridRow {
+	styles := newHighlightStyles()
+	lines := strings.Split(text, "\n")
+	rows := make([]widget.TextGridRow, len(lines))
+	inBlock := false
+
+	for i, line := range lines {
+		runes := []rune(strings.ReplaceAll(line, "\t", "    "))
+		cells := make([]widget.TextGridCell, len(runes))
+		for j, r := range runes {
+			cells[j].Rune = r
+		}
+		rows[i].Cells = cells
+		if rules == nil {
+			continue
+		}
+
+		setStyle := func(from, to int, style widget.TextGridStyle) {
+			for k := from; k < to && k < len(cells); k++ {
+				cells[k].Style = style
+			}
+		}
+		pos := 0
+		for pos < len(runes) {
+			switch {
+			case inBlock:
+				end := pos
+				for end < len(runes) && !hasPrefixAt(runes, end, rules.blockComment[1]) {
+					end++
+				}
+				if end < len(runes) {
+					end += len([]rune(rules.blockComment[1]))
+					inBlock = false
+				}
+				setStyle(pos, end, styles.comment)
+				pos = end
+			case rules.blockComment[0] != "" && hasPrefixAt(runes, pos, rules.blockComment[0]):
+				inBlock = true
+				setStyle(pos, pos+len([]rune(rules.blockComment[0])), styles.comment)
+				pos += len([]rune(rules.blockComment[0]))
+			case hasAnyPrefixAt(runes, pos, rules.lineComments):
+				setStyle(pos, len(runes), styles.comment)
+				pos = len(runes)
+			case strings.ContainsRune(rules.quotes, runes[pos]):
+				end := pos + 1
+				for end < len(runes) && runes[end] != runes[pos] {
+					if runes[end] == '\\' {
+						end++
+					}
+					end++
+				}
+				setStyle(pos, end+1, styles.str)
+				pos = end + 1
+			case unicode.IsDigit(runes[pos]):
+				end := pos
+				for end < len(runes) && (unicode.IsDigit(runes[end]) || unicode.IsLetter(runes[end]) || runes[end] == '.') {
+					end++
+				}
+				setStyle(pos, end, styles.number)
+				pos = end
+			case isWordRune(runes[pos]):
+				end := pos
+				for end < len(runes) && isWordRune(runes[end]) {
+					end++
+				}
+				if rules.keywords[string(runes[pos:end])] {
+					setStyle(pos, end, styles.keyword)
+				}
+				pos = end
+			default:
+				pos++
+			}
+		}
+	}
+	return rows
+}
+
+// hasPrefixAt 判断 runes 从 pos 开始是否为 prefix
+func hasPrefixAt(runes []rune, pos int, prefix string) bool {
+	for _, r := range prefix {
+		if pos >= len(runes) || runes[pos] != r {
+			return false
+		}
+		pos++
+	}
+	return prefix != ""
+}
+
+// hasAnyPrefixAt 判断 runes 从 pos 开始是否为任一前缀
+func hasAnyPrefixAt(runes []rune, pos int, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if hasPrefixAt(runes, pos, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isWordRune 判断是否为标识符字符
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,130 @@
+package gui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	historyLimit = 100 // 后退、前进列表的最大长度
+	recentLimit  = 15  // 最近位置的最大数量
+)
+
+// navHistory 面板的导航历史
+type navHistory struct {
+	back    []string // 后退列表，最后一项为上一个位置
+	forward []string // 前进列表，最后一项为下一个位置
+	recent  []string // 最近访问的位置，最近的在前
+}
+
+// visit 记录从 from 跳转到 to，清空前进列表
+func (h *navHistory) visit(from, to string) {
+	h.addRecent(to)
+	if from == to {
+		return
+	}
+	if from != "" {
+		h.back = pushLimited(h.back, from)
+	}
+	h.forward = nil
+}
+
+// goBack 返回后退的目标位置，并将当前位置放入前进列表
+func (h *navHistory) goBack(current string) (string, bool) {
+	if len(h.back) == 0 {
+		return "", false
+	}
+	target := h.back[len(h.back)-1]
+	h.back = h.back[:len(h.back)-1]
+	h.forward = pushLimited(h.forward, current)
+	h.addRecent(target)
+	return target, true
+}
+
+// goForward 返回前进的目标位置，并将当前位置放入后退列表
+func (h *navHistory) goForward(current string) (string, bool) {
+	if len(h.forward) == 0 {
+		return "", false
+	}
+	target := h.forward[len(h.forward)-1]
+	h.forward = h.forward[:len(h.forward)-1]
+	h.back = pushLimited(h.back, current)
+	h.addRecent(target)
+	return target, true
+}
+
+// addRecent 将位置移到最近位置列表的最前面
+func (h *navHistory) addRecent(path string) {
+	recent := []string{path}
+	for _, item := range h.recent {
+		if item != path && len(recent) < recentLimit {
+			recent = append(recent, item)
+		}
+	}
+	h.recent = recent
+}
+
+// pushLimited 追加一项，超出 historyLimit 时丢弃最早的项
+func pushLimited(list []string, item string) []string {
+	list = append(list, item)
+	if len(list) > historyLimit {
+		list = list[len(list)-historyLimit:]
+	}
+	return list
+}
+
+// GoBack 后退到上一个位置
+func (p *FilePanel) GoBack() {
+	if target, ok := p.history.goBack(p.GetCurrentPath()); ok {
+		p.showPath(target)
+	}
+}
+
+// GoForward 前进到下一个位置
+func (p *FilePanel) GoForward() {
+	if target, ok := p.history.goForward(p.GetCurrentPath()); ok {
+		p.showPath(target)
+	}
+}
+
+// resetPath 切换文件系统后清空导航历史并打开 path
+func (p *FilePanel) resetPath(path string) {
+	p.history = navHistory{}
+	p.history.addRecent(path)
+	p.showPath(path)
+}
+
+// updateNavigation 按导航历史启用或禁用后退、前进按钮
+func (p *FilePanel) updateNavigation() {
+	setEnabled(p.backAction, len(p.history.back) > 0)
+	setEnabled(p.forwardAction, len(p.history.forward) > 0)
+}
+
+// setEnabled 启用或禁用工具栏按钮
+func setEnabled(action *widget.ToolbarAction, enabled bool) {
+	if action == nil {
+		return
+	}
+	if enabled {
+		action.Enable()
+	} else {
+		action.Disable()
+	}
+}
+
+// showRecentMenu 显示最近访问位置的下拉菜单
+func (p *FilePanel) showRecentMenu(pos fyne.Position) {
+	var items []*fyne.MenuItem
+	for _, location := range p.history.recent {
+		location := location
+		item := fyne.NewMenuItem(location, func() {
+			p.SetPath(location)
+		})
+		item.Checked = location == p.GetCurrentPath()
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return
+	}
+	widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", items...), p.window.Canvas(), pos)
+}
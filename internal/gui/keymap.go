@@ -0,0 +1,266 @@
+package gui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+// 可绑定快捷键的面板操作
+const (
+	actionOpen    = "open"    // 进入目录或打开文件
+	actionParent  = "parent"  // 返回上一级目录
+	actionBack    = "back"    // 后退
+	actionForward = "forward" // 前进
+	actionSwitch  = "switch"  // 切换到对侧面板
+	actionCopy    = "copy"    // 复制到对侧面板
+	actionMove    = "move"    // 移动到对侧面板
+	actionMkdir   = "mkdir"   // 新建文件夹
+	actionDelete  = "delete"  // 删除
+	actionRename  = "rename"  // 重命名
+	actionRefresh = "refresh" // 刷新
+	actionPath    = "path"    // 编辑路径
+
+	actionNewTab       = "newtab"       // 新建标签页
+	actionCloseTab     = "closetab"     // 关闭标签页
+	actionDuplicateTab = "duplicatetab" // 复制标签页
+	actionNextTab      = "nexttab"      // 下一个标签页
+	actionPrevTab      = "prevtab"      // 上一个标签页
+
+	actionTerminal = "terminal" // 在当前目录打开终端
+)
+
+// defaultKeymap 默认快捷键，多个按键用逗号分隔，Ctrl 在 macOS 上对应 Command
+var defaultKeymap = map[string]string{
+	actionOpen:    "Return, KP_Enter",
+	actionParent:  "BackSpace",
+	actionBack:    "Alt+Left",
+	actionForward: "Alt+Right",
+	actionSwitch:  "Tab",
+	actionCopy:    "F5",
+	actionMove:    "F6",
+	actionMkdir:   "F7",
+	actionDelete:  "F8, Delete",
+	actionRename:  "F2",
+	actionRefresh: "Ctrl+R",
+	actionPath:    "Ctrl+L",
+
+	actionNewTab:       "Ctrl+T",
+	actionCloseTab:     "Ctrl+W",
+	actionDuplicateTab: "Ctrl+D",
+	actionNextTab:      "Ctrl+Tab",
+	actionPrevTab:      "Ctrl+Shift+Tab",
+
+	actionTerminal: "Ctrl+`",
+}
+
+// keyBinding 按键及修饰键的组合
+type keyBinding struct {
+	key      fyne.KeyName
+	modifier fyne.KeyModifier
+}
+
+// keymap 按键组合到操作的映射
+type keymap map[keyBinding]string
+
+// keyNames 可用于快捷键的按键名称，按小写查找
+var keyNames = func() map[string]fyne.KeyName {
+	names := map[string]fyne.KeyName{}
+	for _, key := range []fyne.KeyName{
+		fyne.KeyEscape, fyne.KeyReturn, fyne.KeyTab, fyne.KeyBackspace, fyne.KeyInsert,
+		fyne.KeyDelete, fyne.KeyRight, fyne.KeyLeft, fyne.KeyDown, fyne.KeyUp,
+		fyne.KeyPageUp, fyne.KeyPageDown, fyne.KeyHome, fyne.KeyEnd, fyne.KeyEnter, fyne.KeySpace,
+		fyne.KeyApostrophe, fyne.KeyComma, fyne.KeyMinus, fyne.KeyPeriod, fyne.KeySlash,
+		fyne.KeyBackslash, fyne.KeyLeftBracket, fyne.KeyRightBracket, fyne.KeySemicolon,
+		fyne.KeyEqual, fyne.KeyAsterisk, fyne.KeyPlus, fyne.KeyBackTick,
+	} {
+		names[strings.ToLower(string(key))] = key
+	}
+	for i := 1; i <= 12; i++ {
+		key := fyne.KeyName(fmt.Sprintf("F%d", i))
+		names[strings.ToLower(string(key))] = key
+	}
+	for c := 'A'; c <= 'Z'; c++ {
+		names[strings.ToLower(string(c))] = fyne.KeyName(string(c))
+	}
+	for c := '0'; c <= '9'; c++ {
+		names[string(c)] = fyne.KeyName(string(c))
+	}
+	// 常用别名
+	names["enter"] = fyne.KeyReturn
+	names["backspace"] = fyne.KeyBackspace
+	names["del"] = fyne.KeyDelete
+	names["esc"] = fyne.KeyEscape
+	names["pageup"] = fyne.KeyPageUp
+	names["pagedown"] = fyne.KeyPageDown
+	return names
+}()
+
+// parseKeyBinding 解析 "Ctrl+Shift+F5" 形式的按键组合
+func parseKeyBinding(text string) (keyBinding, error) {
+	text = strings.TrimSpace(text)
+	var modifiers []string
+	key := text
+	if strings.HasSuffix(text, "++") {
+		key = "+"
+		modifiers = strings.Split(strings.TrimSuffix(text, "++"), "+")
+	} else if i := strings.LastIndex(text, "+"); i > 0 {
+		key = text[i+1:]
+		modifiers = strings.Split(text[:i], "+")
+	}
+
+	var binding keyBinding
+	name, ok := keyNames[strings.ToLower(strings.TrimSpace(key))]
+	if !ok {
+		return binding, fmt.Errorf("未知的按键 %q", text)
+	}
+	binding.key = name
+	for _, modifier := range modifiers {
+		switch strings.ToLower(strings.TrimSpace(modifier)) {
+		case "ctrl", "control":
+			binding.modifier |= fyne.KeyModifierShortcutDefault
+		case "shift":
+			binding.modifier |= fyne.KeyModifierShift
+		case "alt", "option":
+			binding.modifier |= fyne.KeyModifierAlt
+		case "super", "cmd", "command", "win":
+			binding.modifier |= fyne.KeyModifierSuper
+		default:
+			return binding, fmt.Errorf("未知的修饰键 %q", text)
+		}
+	}
+	return binding, nil
+}
+
+// buildKeymap 由操作到按键的配置生成按键映射，同一按键只能绑定一个操作
+func buildKeymap(config map[string]string) (keymap, error) {
+	actions := make([]string, 0, len(config))
+	for action := range config {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+
+	result := keymap{}
+	var errs []error
+	for _, action := range actions {
+		if _, ok := defaultKeymap[action]; !ok {
+			errs = append(errs, fmt.Errorf("未知的操作 %q", action))
+			continue
+		}
+		for _, text := range strings.Split(config[action], ",") {
+			if strings.TrimSpace(text) == "" {
+				continue
+			}
+			binding, err := parseKeyBinding(text)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", action, err))
+				continue
+			}
+			if other, ok := result[binding]; ok {
+				errs = append(errs, fmt.Errorf("%s: 按键 %s 已绑定到 %s", action, strings.TrimSpace(text), other))
+				continue
+			}
+			result[binding] = action
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// keymapPath 返回快捷键配置文件路径
+func keymapPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "xftp798", "keymap.json"), nil
+}
+
+// loadKeymap 读取快捷键配置，未配置的操作使用默认按键，空字符串表示不绑定；
+// 配置文件不存在时写入默认配置，方便用户修改
+func loadKeymap() (keymap, error) {
+	config := make(map[string]string, len(defaultKeymap))
+	for action, keys := range defaultKeymap {
+		config[action] = keys
+	}
+
+	path, err := keymapPath()
+	if err != nil {
+		return buildKeymap(config)
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		saveDefaultKeymap(path)
+		return buildKeymap(config)
+	}
+	if err != nil {
+		km, _ := buildKeymap(config)
+		return km, fmt.Errorf("读取快捷键配置失败: %v", err)
+	}
+
+	var custom map[string]string
+	if err := json.Unmarshal(data, &custom); err != nil {
+		km, _ := buildKeymap(config)
+		return km, fmt.Errorf("解析快捷键配置失败: %v", err)
+	}
+	for action, keys := range custom {
+		config[action] = keys
+	}
+	km, err := buildKeymap(config)
+	if err != nil {
+		return km, fmt.Errorf("快捷键配置 %s 有误:\n%v", path, err)
+	}
+	return km, nil
+}
+
+// saveDefaultKeymap 写入默认快捷键配置，失败时忽略
+func saveDefaultKeymap(path string) {
+	data, err := json.MarshalIndent(defaultKeymap, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	os.WriteFile(path, data, 0644)
+}
+
+var (
+	keymapOnce   sync.Once
+	loadedKeymap keymap
+)
+
+// panelKeymap 返回两侧面板共用的快捷键映射，首次调用时读取配置并提示其中的错误
+func panelKeymap(window fyne.Window) keymap {
+	keymapOnce.Do(func() {
+		km, err := loadKeymap()
+		if err != nil {
+			dialog.ShowError(err, window)
+		}
+		loadedKeymap = km
+	})
+	return loadedKeymap
+}
+
+// action 返回按键组合绑定的操作
+func (m keymap) action(key fyne.KeyName, modifier fyne.KeyModifier) (string, bool) {
+	action, ok := m[keyBinding{key: key, modifier: modifier}]
+	return action, ok
+}
+
+// bindsTab 是否有操作绑定了 Tab 或 Shift+Tab；带 Ctrl 等修饰键的 Tab 按快捷键传递，不影响焦点切换
+func (m keymap) bindsTab() bool {
+	for binding := range m {
+		if binding.key == fyne.KeyTab && binding.modifier&^fyne.KeyModifierShift == 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,419 @@
+package gui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// PanelTabs 一侧的标签页，每个标签页是独立的文件面板，拥有各自的连接、路径、历史和选择
+type PanelTabs struct {
+	window    fyne.Window
+	tabs      *container.DocTabs
+	panels    []*FilePanel // 与标签页一一对应
+	peer      *PanelTabs   // 对侧的标签页
+	transfers *TransferList
+	dock      *DockTabs // 打开终端的位置
+	key       string    // 保存标签页和列设置使用的标识
+
+	transferOpts transfer.TransferOptions // 本侧所有标签页共用的传输选项
+}
+
+// savedTab 保存的标签页：远程标签页记录连接信息（不含密码）
+type savedTab struct {
+	Path   string      `json:"path"`
+	Remote *tabProfile `json:"remote,omitempty"`
+}
+
+// tabProfile 保存的连接信息
+type tabProfile struct {
+	Name     string `json:"name,omitempty"`
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+}
+
+// savedTabSet 一侧保存的全部标签页
+type savedTabSet struct {
+	Active int        `json:"active"`
+	Tabs   []savedTab `json:"tabs"`
+}
+
+// NewPanelTabs 创建一侧的标签页，并恢复上次退出时的标签页；
+// 远程标签页恢复为未连接状态，选择“连接”时预先填写上次的连接信息
+func NewPanelTabs(window fyne.Window, key string, transfers *TransferList) *PanelTabs {
+	t := &PanelTabs{
+		window:       window,
+		transfers:    transfers,
+		key:          key,
+		transferOpts: transfer.DefaultTransferOptions(),
+	}
+	t.tabs = container.NewDocTabs()
+	t.tabs.CreateTab = func() *container.TabItem {
+		return t.newTab(t.newPanel())
+	}
+	t.tabs.CloseIntercept = func(item *container.TabItem) {
+		if panel := t.panelOf(item); panel != nil {
+			t.closeTab(panel)
+		}
+	}
+	t.tabs.OnUnselected = func(item *container.TabItem) {
+		// 比较模式只在两侧当前的标签页之间进行
+		if panel := t.panelOf(item); panel != nil && panel.compare != nil {
+			panel.stopCompare()
+		}
+	}
+	t.tabs.OnSelected = func(item *container.TabItem) {
+		t.updatePeers()
+		if panel := t.panelOf(item); panel != nil {
+			panel.Focus()
+		}
+	}
+
+	t.restore()
+	if len(t.panels) == 0 {
+		t.tabs.Append(t.newTab(t.newPanel()))
+	}
+	return t
+}
+
+// newPanel 创建属于本侧的文件面板
+func (t *PanelTabs) newPanel() *FilePanel {
+	panel := NewFilePanel(t.window)
+	panel.tabs = t
+	panel.SetTransferList(t.transfers)
+	panel.SetColumnsKey(t.key)
+	panel.applyTransferOptions(t.transferOpts)
+	panel.SetTransferCallback(func(sources []string, sourcePanel *FilePanel, transferType transfer.TransferType) {
+		if sourcePanel.peer == nil {
+			return
+		}
+		if err := sourcePanel.peer.HandleTransfer(sources, transferType); err != nil {
+			dialog.ShowError(err, t.window)
+		}
+	})
+	return panel
+}
+
+// newTab 为面板创建标签页
+func (t *PanelTabs) newTab(panel *FilePanel) *container.TabItem {
+	t.panels = append(t.panels, panel)
+	// 内容放在 Stack 中，连接后替换为文件面板
+	panel.tab = container.NewTabItem(panel.Title(), container.NewStack(t.tabContent(panel)))
+	panel.tab.Icon = tabIcon(panel)
+	return panel.tab
+}
+
+// tabContent 标签页的内容：尚未重新连接的远程标签页显示连接按钮，否则为文件面板
+func (t *PanelTabs) tabContent(panel *FilePanel) fyne.CanvasObject {
+	if panel.pending == nil {
+		return panel.GetContainer()
+	}
+	label := widget.NewLabel(fmt.Sprintf("%s 未连接", panel.pending.DisplayName()))
+	label.Alignment = fyne.TextAlignCenter
+	connect := widget.NewButtonWithIcon("连接", theme.ComputerIcon(), panel.showConnectDialog)
+	connect.Importance = widget.HighImportance
+	return container.NewVBox(layout.NewSpacer(), label, container.NewCenter(connect), layout.NewSpacer())
+}
+
+// tabIcon 标签页图标，区分本地和远程
+func tabIcon(panel *FilePanel) fyne.Resource {
+	if panel.remoteFS != nil || panel.pending != nil {
+		return theme.ComputerIcon()
+	}
+	return theme.FolderIcon()
+}
+
+// update 连接状态变化后更新面板所在标签页的标题、图标和内容
+func (t *PanelTabs) update(panel *FilePanel) {
+	if panel.tab == nil {
+		return
+	}
+	panel.tab.Text = panel.Title()
+	panel.tab.Icon = tabIcon(panel)
+	body := panel.tab.Content.(*fyne.Container)
+	body.Objects = []fyne.CanvasObject{t.tabContent(panel)}
+	body.Refresh()
+	t.tabs.Refresh()
+	t.updatePeers()
+}
+
+// panelOf 返回标签页对应的面板
+func (t *PanelTabs) panelOf(item *container.TabItem) *FilePanel {
+	for _, panel := range t.panels {
+		if panel.tab == item {
+			return panel
+		}
+	}
+	return nil
+}
+
+// SetPeer 设置对侧的标签页
+func (t *PanelTabs) SetPeer(peer *PanelTabs) {
+	t.peer = peer
+	t.updatePeers()
+}
+
+// updatePeers 将两侧所有面板的对侧面板设为对侧当前的标签页
+func (t *PanelTabs) updatePeers() {
+	if t.peer == nil {
+		return
+	}
+	for _, panel := range t.panels {
+		panel.SetPeer(t.peer.Active())
+	}
+	for _, panel := range t.peer.panels {
+		panel.SetPeer(t.Active())
+	}
+}
+
+// SetTransferOptions 设置本侧的传输选项并应用到所有标签页，之后新建的标签页也使用这些选项
+func (t *PanelTabs) SetTransferOptions(opts transfer.TransferOptions) {
+	t.transferOpts = opts
+	for _, panel := range t.panels {
+		panel.applyTransferOptions(opts)
+	}
+}
+
+// SetDock 设置打开终端的下方标签页
+func (t *PanelTabs) SetDock(dock *DockTabs) {
+	t.dock = dock
+}
+
+// Active 返回当前标签页的面板
+func (t *PanelTabs) Active() *FilePanel {
+	return t.panelOf(t.tabs.Selected())
+}
+
+// GetContainer 返回标签页容器
+func (t *PanelTabs) GetContainer() fyne.CanvasObject {
+	return t.tabs
+}
+
+// Focus 将键盘焦点移到当前标签页的文件列表
+func (t *PanelTabs) Focus() {
+	if panel := t.Active(); panel != nil {
+		panel.Focus()
+	}
+}
+
+// selectPanel 切换到面板所在的标签页
+func (t *PanelTabs) selectPanel(panel *FilePanel) {
+	if panel.tab != nil {
+		t.tabs.Select(panel.tab)
+	}
+}
+
+// NewTab 新建本地标签页并切换过去
+func (t *PanelTabs) NewTab() {
+	t.tabs.Append(t.newTab(t.newPanel()))
+	t.tabs.SelectIndex(len(t.tabs.Items) - 1)
+}
+
+// duplicateTab 复制标签页：远程标签页使用相同的连接信息重新连接，并打开相同的目录
+func (t *PanelTabs) duplicateTab(source *FilePanel) {
+	panel := t.newPanel()
+	switch {
+	case source.remoteConfig != nil:
+		config := *source.remoteConfig
+		config.InitialDir = source.GetCurrentPath()
+		if err := panel.connect(&config); err != nil {
+			panel.Close()
+			dialog.ShowError(err, t.window)
+			return
+		}
+	case source.pending != nil:
+		config := *source.pending
+		panel.pending = &config
+	default:
+		panel.resetPath(source.GetCurrentPath())
+	}
+	t.tabs.Append(t.newTab(panel))
+	t.tabs.SelectIndex(len(t.tabs.Items) - 1)
+}
+
+// closeTab 关闭标签页并断开其连接；有进行中的传输时先确认，关闭最后一个标签页时新建本地标签页
+func (t *PanelTabs) closeTab(panel *FilePanel) {
+	closeTab := func() {
+		panel.Close()
+		index := -1
+		for i, p := range t.panels {
+			if p == panel {
+				index = i
+			}
+		}
+		if index < 0 {
+			return
+		}
+		t.panels = append(t.panels[:index], t.panels[index+1:]...)
+		t.tabs.Remove(panel.tab)
+		if len(t.panels) == 0 {
+			t.tabs.Append(t.newTab(t.newPanel()))
+		}
+		if selected := t.tabs.SelectedIndex(); selected >= len(t.tabs.Items) {
+			t.tabs.SelectIndex(len(t.tabs.Items) - 1)
+		}
+		// 删除当前标签页时 DocTabs 不会通知选择变化
+		t.updatePeers()
+		t.Focus()
+	}
+
+	if active := t.transfers.activeFor(panel); active > 0 {
+		dialog.ShowConfirm("关闭标签页",
+			fmt.Sprintf("%s 还有 %d 个传输任务未完成，关闭后尚未开始的任务将失败。确定要关闭吗？", panel.Title(), active),
+			func(confirm bool) {
+				if confirm {
+					closeTab()
+				}
+			},
+			t.window,
+		)
+		return
+	}
+	closeTab()
+}
+
+// cycleTab 切换到后 step 个标签页，到达末尾时回到开头
+func (t *PanelTabs) cycleTab(step int) {
+	count := len(t.tabs.Items)
+	if count < 2 {
+		return
+	}
+	t.tabs.SelectIndex((t.tabs.SelectedIndex() + step + count) % count)
+}
+
+// showTabMenu 显示标签页操作菜单
+func (t *PanelTabs) showTabMenu(panel *FilePanel, pos fyne.Position) {
+	menu := fyne.NewMenu("",
+		fyne.NewMenuItem("新建标签页", t.NewTab),
+		fyne.NewMenuItem("复制标签页", func() {
+			t.duplicateTab(panel)
+		}),
+		fyne.NewMenuItem("关闭标签页", func() {
+			t.closeTab(panel)
+		}),
+	)
+	widget.ShowPopUpMenuAtPosition(menu, t.window.Canvas(), pos)
+}
+
+// Close 保存标签页并结束全部面板的会话
+func (t *PanelTabs) Close() {
+	// 窗口已关闭，无法提示保存失败
+	_ = t.save()
+	for _, panel := range t.panels {
+		panel.Close()
+	}
+}
+
+// tabsPath 返回标签页配置文件路径
+func tabsPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "xftp798", "tabs.json"), nil
+}
+
+// loadAllTabs 读取两侧保存的标签页，文件不存在时返回空映射
+func loadAllTabs() (map[string]savedTabSet, error) {
+	all := make(map[string]savedTabSet)
+	path, err := tabsPath()
+	if err != nil {
+		return all, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取标签页失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("解析标签页失败: %v", err)
+	}
+	return all, nil
+}
+
+// save 保存本侧的标签页
+func (t *PanelTabs) save() error {
+	all, err := loadAllTabs()
+	if err != nil {
+		// 已损坏的文件直接覆盖
+		all = make(map[string]savedTabSet)
+	}
+	set := savedTabSet{Active: t.tabs.SelectedIndex()}
+	for _, panel := range t.panels {
+		tab := savedTab{Path: panel.GetCurrentPath()}
+		config := panel.remoteConfig
+		if config == nil {
+			config = panel.pending
+		}
+		if config != nil {
+			if panel.pending != nil {
+				tab.Path = panel.pending.InitialDir
+			}
+			tab.Remote = &tabProfile{
+				Name:     config.Name,
+				Host:     config.Host,
+				Port:     config.Port,
+				Username: config.Username,
+			}
+		}
+		set.Tabs = append(set.Tabs, tab)
+	}
+	all[t.key] = set
+
+	path, err := tabsPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// restore 恢复本侧上次保存的标签页
+func (t *PanelTabs) restore() {
+	all, err := loadAllTabs()
+	if err != nil {
+		dialog.ShowError(err, t.window)
+		return
+	}
+	set, ok := all[t.key]
+	if !ok {
+		return
+	}
+	for _, tab := range set.Tabs {
+		panel := t.newPanel()
+		if tab.Remote != nil {
+			panel.pending = &transfer.SFTPConfig{
+				Name:       tab.Remote.Name,
+				Host:       tab.Remote.Host,
+				Port:       tab.Remote.Port,
+				Username:   tab.Remote.Username,
+				InitialDir: tab.Path,
+			}
+		} else if info, err := os.Stat(tab.Path); err == nil && info.IsDir() {
+			panel.resetPath(tab.Path)
+		}
+		t.tabs.Append(t.newTab(panel))
+	}
+	if set.Active >= 0 && set.Active < len(t.tabs.Items) {
+		t.tabs.SelectIndex(set.Active)
+	}
+}
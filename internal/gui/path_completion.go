@@ -0,0 +1,391 @@
+package gui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	completionCacheTTL = 30 * time.Second // 目录列表缓存的有效期
+	completionLimit    = 8                // 最多显示的候选数量
+)
+
+// cachedDir 缓存的目录列表
+type cachedDir struct {
+	names []string // 子目录名称，已排序
+	at    time.Time
+}
+
+// pathCompletion 路径输入框的目录名补全
+type pathCompletion struct {
+	box       *fyne.Container // 输入框下方的候选列表
+	dir       string          // 候选所在目录，即输入内容中最后一个分隔符及之前的部分
+	matches   []string        // 匹配的子目录名称
+	highlight int             // 高亮的候选，-1表示无
+	pressing  bool            // 正在点击候选，输入框失去焦点时不隐藏候选列表
+
+	mu    sync.Mutex
+	cache map[string]cachedDir
+	home  string
+}
+
+// pathInput 支持 Tab 补全和候选列表的路径输入框
+type pathInput struct {
+	widget.Entry
+	panel   *FilePanel
+	focused bool
+}
+
+// newPathInput 创建路径输入框
+func newPathInput(panel *FilePanel) *pathInput {
+	input := &pathInput{panel: panel}
+	input.ExtendBaseWidget(input)
+	panel.completion = &pathCompletion{
+		box:       container.NewVBox(),
+		highlight: -1,
+		cache:     make(map[string]cachedDir),
+	}
+	panel.completion.box.Hide()
+	input.OnChanged = func(text string) {
+		if input.focused {
+			panel.updateCompletion(text)
+		}
+	}
+	return input
+}
+
+// AcceptsTab Tab 键用于补全
+func (e *pathInput) AcceptsTab() bool {
+	return true
+}
+
+// FocusGained 获得焦点
+func (e *pathInput) FocusGained() {
+	e.focused = true
+	e.Entry.FocusGained()
+}
+
+// FocusLost 失去焦点时隐藏候选列表，点击候选引起的除外
+func (e *pathInput) FocusLost() {
+	e.focused = false
+	e.Entry.FocusLost()
+	if !e.panel.completion.pressing {
+		e.panel.hideCompletion()
+	}
+}
+
+// TypedKey Tab 补全，上下键选择候选，Enter 进入选中的候选，Esc 关闭候选列表或返回文件列表
+func (e *pathInput) TypedKey(event *fyne.KeyEvent) {
+	p, c := e.panel, e.panel.completion
+	switch event.Name {
+	case fyne.KeyTab:
+		p.completePath()
+		return
+	case fyne.KeyDown, fyne.KeyUp:
+		if c.box.Visible() && len(c.matches) > 0 {
+			step := 1
+			if event.Name == fyne.KeyUp {
+				step = -1
+			}
+			p.highlightCompletion((c.highlight + step + len(c.matches)) % len(c.matches))
+			return
+		}
+	case fyne.KeyEscape:
+		if c.box.Visible() {
+			p.hideCompletion()
+		} else {
+			p.pathEntry.SetText(p.GetCurrentPath())
+			p.Focus()
+		}
+		return
+	case fyne.KeyReturn, fyne.KeyEnter:
+		if c.box.Visible() && c.highlight >= 0 && c.highlight < len(c.matches) {
+			e.SetText(c.dir + c.matches[c.highlight])
+		}
+	}
+	e.Entry.TypedKey(event)
+}
+
+// separators 返回当前文件系统的路径分隔符，第一个为规范分隔符
+func (p *FilePanel) separators() string {
+	return p.paths().Separators()
+}
+
+// expandHome 将开头的 ~ 展开为本地或远程的主目录
+func (p *FilePanel) expandHome(text string) string {
+	if text != "~" && (len(text) < 2 || text[0] != '~' || !strings.ContainsRune(p.separators(), rune(text[1]))) {
+		return text
+	}
+	c := p.completion
+	c.mu.Lock()
+	home := c.home
+	c.mu.Unlock()
+	if home == "" {
+		dir, err := p.fileSystem.HomeDir()
+		if err != nil {
+			return text
+		}
+		home = dir
+		c.mu.Lock()
+		c.home = home
+		c.mu.Unlock()
+	}
+	return home + text[1:]
+}
+
+// splitCompletion 将输入拆分为目录部分（含末尾分隔符）和待补全的名称前缀
+func (p *FilePanel) splitCompletion(text string) (string, string) {
+	i := strings.LastIndexAny(text, p.separators())
+	if i < 0 {
+		return "", text
+	}
+	return text[:i+1], text[i+1:]
+}
+
+// listSubdirs 返回目录下的子目录名称，优先使用缓存
+func (p *FilePanel) listSubdirs(dir string) ([]string, bool) {
+	c := p.completion
+	c.mu.Lock()
+	cached, ok := c.cache[dir]
+	c.mu.Unlock()
+	if ok && time.Since(cached.at) < completionCacheTTL {
+		return cached.names, true
+	}
+
+	files, err := p.fileSystem.ListFiles(dir)
+	if err != nil {
+		return nil, false
+	}
+	var names []string
+	for _, file := range files {
+		if file.IsDir {
+			names = append(names, file.Name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return naturalCompare(names[i], names[j]) < 0
+	})
+	c.mu.Lock()
+	c.cache[dir] = cachedDir{names: names, at: time.Now()}
+	c.mu.Unlock()
+	return names, true
+}
+
+// cachedSubdirs 只从缓存中获取子目录名称
+func (p *FilePanel) cachedSubdirs(dir string) ([]string, bool) {
+	c := p.completion
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.cache[dir]
+	if !ok || time.Since(cached.at) >= completionCacheTTL {
+		return nil, false
+	}
+	return cached.names, true
+}
+
+// resetCompletion 切换文件系统后清空缓存和主目录
+func (p *FilePanel) resetCompletion() {
+	c := p.completion
+	c.mu.Lock()
+	c.cache = make(map[string]cachedDir)
+	c.home = ""
+	c.mu.Unlock()
+	p.hideCompletion()
+}
+
+// matchSubdirs 筛选以 prefix 开头的子目录，以 . 开头的目录只在前缀也以 . 开头时显示
+func matchSubdirs(names []string, prefix string) []string {
+	var matches []string
+	for _, name := range names {
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".") {
+			continue
+		}
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, name)
+		}
+	}
+	return matches
+}
+
+// updateCompletion 输入变化后更新候选列表，目录未缓存时在后台读取
+func (p *FilePanel) updateCompletion(text string) {
+	dir, prefix := p.splitCompletion(text)
+	if dir == "" {
+		p.hideCompletion()
+		return
+	}
+	if names, ok := p.cachedSubdirs(p.expandHome(dir)); ok {
+		p.showCompletion(dir, matchSubdirs(names, prefix))
+		return
+	}
+	go func() {
+		names, ok := p.listSubdirs(p.expandHome(dir))
+		if !ok {
+			return
+		}
+		// 输入已变化时丢弃结果
+		current, prefix := p.splitCompletion(p.pathEntry.Text)
+		if current != dir || !p.pathEntry.focused {
+			return
+		}
+		p.showCompletion(dir, matchSubdirs(names, prefix))
+	}()
+}
+
+// completePath 补全输入：唯一匹配时补全整个目录名，多个匹配时补全公共前缀，无法继续补全时依次选择候选
+func (p *FilePanel) completePath() {
+	c := p.completion
+	text := p.pathEntry.Text
+	dir, prefix := p.splitCompletion(text)
+	if dir == "" {
+		return
+	}
+	names, ok := p.listSubdirs(p.expandHome(dir))
+	if !ok {
+		return
+	}
+	matches := matchSubdirs(names, prefix)
+	switch {
+	case len(matches) == 0:
+		p.hideCompletion()
+	case len(matches) == 1:
+		p.setPathText(dir + matches[0] + p.separators()[:1])
+	default:
+		if common := commonPrefix(matches); len(common) > len(prefix) {
+			p.setPathText(dir + common)
+			return
+		}
+		if c.dir != dir || !c.box.Visible() {
+			p.showCompletion(dir, matches)
+		}
+		p.highlightCompletion((c.highlight + 1) % len(c.matches))
+	}
+}
+
+// commonPrefix 返回所有名称的公共前缀
+func commonPrefix(names []string) string {
+	prefix := names[0]
+	for _, name := range names[1:] {
+		for !strings.HasPrefix(name, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	// 避免截断多字节字符
+	return strings.ToValidUTF8(prefix, "")
+}
+
+// setPathText 设置输入内容并将光标移到末尾
+func (p *FilePanel) setPathText(text string) {
+	p.pathEntry.SetText(text)
+	p.pathEntry.CursorColumn = len([]rune(text))
+	p.pathEntry.Refresh()
+}
+
+// showCompletion 显示候选列表
+func (p *FilePanel) showCompletion(dir string, matches []string) {
+	c := p.completion
+	c.dir, c.matches, c.highlight = dir, matches, -1
+	if len(matches) == 0 {
+		p.hideCompletion()
+		return
+	}
+	p.renderCompletion()
+	c.box.Show()
+}
+
+// renderCompletion 重建候选列表，高亮项保持可见
+func (p *FilePanel) renderCompletion() {
+	c := p.completion
+	start := 0
+	if c.highlight >= completionLimit {
+		start = c.highlight - completionLimit + 1
+	}
+	end := start + completionLimit
+	if end > len(c.matches) {
+		end = len(c.matches)
+	}
+
+	objects := make([]fyne.CanvasObject, 0, end-start+1)
+	for i := start; i < end; i++ {
+		objects = append(objects, newCompletionItem(p, i))
+	}
+	if hidden := len(c.matches) - (end - start); hidden > 0 {
+		more := widget.NewLabel(fmt.Sprintf("还有 %d 项…", hidden))
+		more.Importance = widget.LowImportance
+		objects = append(objects, more)
+	}
+	c.box.Objects = objects
+	c.box.Refresh()
+}
+
+// highlightCompletion 高亮第 index 个候选
+func (p *FilePanel) highlightCompletion(index int) {
+	p.completion.highlight = index
+	p.renderCompletion()
+}
+
+// hideCompletion 隐藏候选列表
+func (p *FilePanel) hideCompletion() {
+	c := p.completion
+	c.matches, c.highlight = nil, -1
+	c.box.Hide()
+}
+
+// applyCompletion 选择候选并进入该目录
+func (p *FilePanel) applyCompletion(index int) {
+	c := p.completion
+	if index < 0 || index >= len(c.matches) {
+		return
+	}
+	target := c.dir + c.matches[index]
+	p.hideCompletion()
+	p.SetPath(p.paths().Clean(p.expandHome(target)))
+}
+
+// completionItem 候选列表中的一项
+type completionItem struct {
+	widget.Label
+	panel *FilePanel
+	index int
+}
+
+// newCompletionItem 创建候选项
+func newCompletionItem(panel *FilePanel, index int) *completionItem {
+	item := &completionItem{panel: panel, index: index}
+	item.ExtendBaseWidget(item)
+	c := panel.completion
+	item.SetText(c.dir + c.matches[index])
+	item.Truncation = fyne.TextTruncateEllipsis
+	if index == c.highlight {
+		item.TextStyle = fyne.TextStyle{Bold: true}
+		item.Importance = widget.HighImportance
+	}
+	return item
+}
+
+// Tapped 点击候选进入该目录
+func (i *completionItem) Tapped(*fyne.PointEvent) {
+	i.panel.applyCompletion(i.index)
+}
+
+// MouseDown 标记正在点击候选，避免输入框失去焦点时先隐藏候选列表
+func (i *completionItem) MouseDown(*desktop.MouseEvent) {
+	i.panel.completion.pressing = true
+}
+
+// MouseUp 鼠标松开：在候选外松开时不会进入目录，输入框已失去焦点则隐藏候选列表
+func (i *completionItem) MouseUp(*desktop.MouseEvent) {
+	p := i.panel
+	p.completion.pressing = false
+	if !p.pathEntry.focused {
+		p.hideCompletion()
+	}
+}
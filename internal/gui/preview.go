@@ -0,0 +1,291 @@
+package gui
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// 预览读取的大小上限
+const (
+	previewTextLimit  = 1 << 20  // 文本和十六进制预览
+	previewImageLimit = 20 << 20 // 图片预览
+	previewHexLimit   = 64 << 10 // 十六进制视图显示的字节数
+)
+
+// 预览模式
+const (
+	previewText  = "文本"
+	previewImage = "图片"
+	previewHex   = "十六进制"
+)
+
+// previewEncodings 可选的文本编码，顺序即下拉框顺序
+var previewEncodings = []struct {
+	name     string
+	encoding encoding.Encoding
+}{
+	{"UTF-8", nil},
+	{"GBK", simplifiedchinese.GBK},
+	{"GB18030", simplifiedchinese.GB18030},
+	{"Big5", traditionalchinese.Big5},
+	{"Shift_JIS", japanese.ShiftJIS},
+	{"ISO-8859-1", charmap.ISO8859_1},
+	{"UTF-16LE", unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)},
+	{"UTF-16BE", unicode.UTF16(unicode.BigEndian, unicode.UseBOM)},
+}
+
+// imageExtensions 按图片预览的扩展名
+var imageExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true,
+}
+
+// PreviewWindow 表示文件预览窗口
+type PreviewWindow struct {
+	window    fyne.Window
+	path      string
+	data      []byte
+	truncated bool
+	line      int // 打开时定位并高亮的行，0表示不定位
+	encoding  string
+	content   *fyne.Container
+	status    *widget.Label
+}
+
+// ShowPreview 预览本地或远程文件，只读取大小上限以内的内容；line 大于0时定位到该行
+func ShowPreview(fileSystem *transfer.FileSystem, path string, line int, parent fyne.Window) {
+	limit := int64(previewTextLimit)
+	if imageExtensions[strings.ToLower(filepath.Ext(path))] {
+		limit = previewImageLimit
+	}
+
+	// 在独立的通道上读取，不受面板切换连接的影响
+	detached, err := fileSystem.Detach()
+	if err != nil {
+		dialog.ShowError(err, parent)
+		return
+	}
+	defer detached.Close()
+	reader, err := detached.Open(path)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("打开文件失败: %v", err), parent)
+		return
+	}
+	defer reader.Close()
+	// 多读一个字节用于判断是否超过上限
+	data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("读取文件失败: %v", err), parent)
+		return
+	}
+
+	p := &PreviewWindow{
+		window:    fyne.CurrentApp().NewWindow("预览：" + path),
+		path:      path,
+		data:      data,
+		truncated: int64(len(data)) > limit,
+		line:      line,
+		encoding:  previewEncodings[0].name,
+		content:   container.NewStack(),
+		status:    widget.NewLabel(""),
+	}
+	if p.truncated {
+		p.data = data[:limit]
+	}
+	p.show()
+}
+
+// show 创建并显示预览窗口
+func (p *PreviewWindow) show() {
+	var encodingNames []string
+	for _, e := range previewEncodings {
+		encodingNames = append(encodingNames, e.name)
+	}
+	encodingSelect := widget.NewSelect(encodingNames, nil)
+	encodingSelect.SetSelected(p.encoding)
+	encodingSelect.OnChanged = func(name string) {
+		p.encoding = name
+		p.render(previewText)
+	}
+
+	modeSelect := widget.NewRadioGroup([]string{previewText, previewImage, previewHex}, nil)
+	modeSelect.Horizontal = true
+	modeSelect.OnChanged = func(mode string) {
+		if mode == previewText {
+			encodingSelect.Enable()
+		} else {
+			encodingSelect.Disable()
+		}
+		p.render(mode)
+	}
+
+	toolbar := container.NewHBox(modeSelect, widget.NewLabel("编码"), encodingSelect)
+	p.window.SetContent(container.NewBorder(toolbar, p.status, nil, nil, p.content))
+	p.window.Resize(fyne.NewSize(900, 600))
+	modeSelect.SetSelected(p.detectMode())
+	p.window.Show()
+}
+
+// detectMode 按扩展名和内容选择默认预览模式
+func (p *PreviewWindow) detectMode() string {
+	if imageExtensions[strings.ToLower(filepath.Ext(p.path))] {
+		return previewImage
+	}
+	probe := p.data
+	if len(probe) > 8000 {
+		probe = probe[:8000]
+	}
+	if bytes.IndexByte(probe, 0) >= 0 {
+		return previewHex
+	}
+	return previewText
+}
+
+// render 按模式显示内容
+func (p *PreviewWindow) render(mode string) {
+	var object fyne.CanvasObject
+	var err error
+	switch mode {
+	case previewImage:
+		object, err = p.renderImage()
+	case previewHex:
+		object = p.renderHex()
+	default:
+		object, err = p.renderText()
+	}
+	if err != nil {
+		object = widget.NewLabel(err.Error())
+	}
+	p.content.Objects = []fyne.CanvasObject{object}
+	p.content.Refresh()
+
+	status := fmt.Sprintf("%s，已读取 %s", mode, transfer.FormatSize(int64(len(p.data))))
+	if p.truncated {
+		status += "（超过预览上限，只显示前面部分）"
+	}
+	p.status.SetText(status)
+}
+
+// renderText 按所选编码解码并显示带语法高亮的文本
+func (p *PreviewWindow) renderText() (fyne.CanvasObject, error) {
+	text, err := decodeText(p.data, p.encoding)
+	if err != nil {
+		return nil, err
+	}
+	grid := widget.NewTextGrid()
+	grid.Rows = highlightRows(strings.ReplaceAll(text, "\r\n", "\n"), syntaxFor(p.path))
+	grid.ShowLineNumbers = true
+
+	if p.line > 0 && p.line <= len(grid.Rows) {
+		grid.SetRowStyle(p.line-1, &widget.CustomTextGridStyle{BGColor: theme.SelectionColor()})
+	}
+	scroll := container.NewScroll(grid)
+	if p.line > 0 {
+		// 将定位行滚动到窗口上方三分之一处
+		rowHeight := fyne.MeasureText("M", theme.TextSize(), fyne.TextStyle{Monospace: true}).Height
+		if offset := float32(p.line-1)*rowHeight - p.window.Canvas().Size().Height/3; offset > 0 {
+			scroll.Offset = fyne.NewPos(0, offset)
+		}
+	}
+	return scroll, nil
+}
+
+// renderImage 解码并显示 PNG、JPEG、GIF、BMP 图片
+func (p *PreviewWindow) renderImage() (fyne.CanvasObject, error) {
+	if p.truncated {
+		return nil, fmt.Errorf("图片超过预览上限 %s", transfer.FormatSize(previewImageLimit))
+	}
+	img, format, err := image.Decode(bytes.NewReader(p.data))
+	if err != nil {
+		return nil, fmt.Errorf("无法解码图片: %v", err)
+	}
+	bounds := img.Bounds()
+	picture := canvas.NewImageFromImage(img)
+	picture.FillMode = canvas.ImageFillContain
+	picture.ScaleMode = canvas.ImageScaleSmooth
+	info := widget.NewLabel(fmt.Sprintf("%s  %d × %d", strings.ToUpper(format), bounds.Dx(), bounds.Dy()))
+	return container.NewBorder(info, nil, nil, nil, picture), nil
+}
+
+// renderHex 以 hexdump -C 的格式显示前 previewHexLimit 字节
+func (p *PreviewWindow) renderHex() fyne.CanvasObject {
+	data := p.data
+	if len(data) > previewHexLimit {
+		data = data[:previewHexLimit]
+	}
+	grid := widget.NewTextGridFromString(formatHex(data))
+	return container.NewScroll(grid)
+}
+
+// decodeText 按编码名称解码文本
+func decodeText(data []byte, name string) (string, error) {
+	for _, e := range previewEncodings {
+		if e.name != name {
+			continue
+		}
+		if e.encoding == nil {
+			return strings.ToValidUTF8(string(data), string(utf8.RuneError)), nil
+		}
+		decoded, err := e.encoding.NewDecoder().Bytes(data)
+		if err != nil {
+			return "", fmt.Errorf("按 %s 解码失败: %v", name, err)
+		}
+		return string(decoded), nil
+	}
+	return "", fmt.Errorf("不支持的编码: %s", name)
+}
+
+// formatHex 格式化为每行16字节的十六进制视图
+func formatHex(data []byte) string {
+	var b strings.Builder
+	for offset := 0; offset < len(data); offset += 16 {
+		end := offset + 16
+		if end > len(data) {
+			end = len(data)
+		}
+		line := data[offset:end]
+		fmt.Fprintf(&b, "%08x  ", offset)
+		for i := 0; i < 16; i++ {
+			if i < len(line) {
+				fmt.Fprintf(&b, "%02x ", line[i])
+			} else {
+				b.WriteString("   ")
+			}
+			if i == 7 {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(" |")
+		for _, c := range line {
+			if c >= 0x20 && c < 0x7f {
+				b.WriteByte(c)
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteString("|\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,273 @@
+package gui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// searchDateLayout 搜索对话框中日期的输入格式
+const searchDateLayout = "2006-01-02"
+
+// SearchDialog 表示递归搜索对话框
+type SearchDialog struct {
+	window fyne.Window
+	panel  *FilePanel
+}
+
+// NewSearchDialog 创建新的搜索对话框，在面板的当前目录下搜索
+func NewSearchDialog(window fyne.Window, panel *FilePanel) *SearchDialog {
+	return &SearchDialog{
+		window: window,
+		panel:  panel,
+	}
+}
+
+// Show 显示搜索条件对话框
+func (d *SearchDialog) Show() {
+	rootEntry := widget.NewEntry()
+	rootEntry.SetText(d.panel.GetCurrentPath())
+
+	patternEntry := widget.NewEntry()
+	patternEntry.SetPlaceHolder("如 *.conf，不含通配符时按包含匹配")
+	regexCheck := widget.NewCheck("使用正则表达式", nil)
+
+	typeSelect := widget.NewSelect([]string{"全部", "文件", "文件夹"}, nil)
+	typeSelect.SetSelectedIndex(0)
+
+	minSizeEntry := widget.NewEntry()
+	minSizeEntry.SetPlaceHolder("如 10K")
+	maxSizeEntry := widget.NewEntry()
+	maxSizeEntry.SetPlaceHolder("如 100M")
+
+	afterEntry := widget.NewEntry()
+	afterEntry.SetPlaceHolder(searchDateLayout)
+	beforeEntry := widget.NewEntry()
+	beforeEntry.SetPlaceHolder(searchDateLayout)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("搜索目录", rootEntry),
+		widget.NewFormItem("名称", patternEntry),
+		widget.NewFormItem("", regexCheck),
+		widget.NewFormItem("类型", typeSelect),
+		widget.NewFormItem("最小大小", minSizeEntry),
+		widget.NewFormItem("最大大小", maxSizeEntry),
+		widget.NewFormItem("修改日期从", afterEntry),
+		widget.NewFormItem("修改日期到", beforeEntry),
+	}
+
+	formDialog := dialog.NewForm(
+		"搜索文件",
+		"搜索",
+		"取消",
+		items,
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+			options := transfer.SearchOptions{
+				Pattern: strings.TrimSpace(patternEntry.Text),
+				Regex:   regexCheck.Checked,
+				Type:    transfer.SearchType(typeSelect.SelectedIndex()),
+			}
+			var err error
+			if options.MinSize, err = transfer.ParseSize(minSizeEntry.Text); err == nil {
+				options.MaxSize, err = transfer.ParseSize(maxSizeEntry.Text)
+			}
+			if err == nil {
+				options.After, err = parseSearchDate(afterEntry.Text, false)
+			}
+			if err == nil {
+				options.Before, err = parseSearchDate(beforeEntry.Text, true)
+			}
+			if err != nil {
+				dialog.ShowError(err, d.window)
+				return
+			}
+			d.start(rootEntry.Text, options)
+		},
+		d.window,
+	)
+	formDialog.Resize(fyne.NewSize(500, 450))
+	formDialog.Show()
+}
+
+// start 开始搜索，并打开结果窗口，结果边搜索边显示
+func (d *SearchDialog) start(root string, options transfer.SearchOptions) {
+	panel := d.panel
+	fileSystem := transfer.NewFileSystem(root)
+	if panel.remoteFS != nil {
+		fileSystem.SetRemoteFS(panel.remoteFS)
+	}
+	fileSystem.SetFilterRules(panel.transferOpts.Filters)
+
+	var results []transfer.FileInfo
+	checked := make(map[string]bool)
+	var mu sync.Mutex
+
+	resultList := widget.NewList(
+		func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(results)
+		},
+		func() fyne.CanvasObject {
+			return container.NewBorder(nil, nil, widget.NewCheck("", nil), nil, widget.NewLabel(""))
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			mu.Lock()
+			defer mu.Unlock()
+			if id >= len(results) {
+				return
+			}
+			file := results[id]
+			row := item.(*fyne.Container)
+			label := row.Objects[0].(*widget.Label)
+			check := row.Objects[1].(*widget.Check)
+			text := file.Path
+			if !file.IsDir {
+				text += "  " + transfer.FormatSize(file.Size)
+			}
+			label.SetText(text + "  " + file.ModTime.Format("2006-01-02 15:04:05"))
+			check.OnChanged = nil
+			check.SetChecked(checked[file.Path])
+			check.OnChanged = func(on bool) {
+				mu.Lock()
+				defer mu.Unlock()
+				checked[file.Path] = on
+			}
+		},
+	)
+
+	// checkedFiles 返回勾选的结果
+	checkedFiles := func() []transfer.FileInfo {
+		mu.Lock()
+		defer mu.Unlock()
+		var files []transfer.FileInfo
+		for _, file := range results {
+			if checked[file.Path] {
+				files = append(files, file)
+			}
+		}
+		return files
+	}
+
+	resultWindow := fyne.CurrentApp().NewWindow("搜索：" + root)
+	status := widget.NewLabel("正在搜索...")
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	stopSearch := func() {
+		stopOnce.Do(func() { close(stop) })
+	}
+
+	copyButton := widget.NewButton("复制到对侧", func() {
+		files := checkedFiles()
+		if len(files) == 0 || panel.onTransfer == nil {
+			return
+		}
+		panel.confirmFilters(files, "复制", func() {
+			panel.onTransfer(filePaths(files), panel, transfer.Copy)
+		})
+	})
+	deleteButton := widget.NewButton("删除", func() {
+		files := checkedFiles()
+		if len(files) == 0 {
+			return
+		}
+		dialog.ShowConfirm("删除确认", fmt.Sprintf("确定要删除 %s 吗？", describeFiles(files)), func(confirm bool) {
+			if !confirm {
+				return
+			}
+			var errs []error
+			deleted := make(map[string]bool)
+			for _, file := range files {
+				if err := fileSystem.DeleteFile(file.Path); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %v", file.Path, err))
+				} else {
+					deleted[file.Path] = true
+				}
+			}
+			mu.Lock()
+			remaining := results[:0]
+			for _, file := range results {
+				if !deleted[file.Path] {
+					remaining = append(remaining, file)
+				}
+			}
+			results = remaining
+			mu.Unlock()
+			resultList.Refresh()
+			panel.RefreshFiles()
+			if len(errs) > 0 {
+				dialog.ShowError(errors.Join(errs...), resultWindow)
+			}
+		}, resultWindow)
+	})
+	locateButton := widget.NewButton("打开所在目录", func() {
+		files := checkedFiles()
+		if len(files) == 0 {
+			return
+		}
+		panel.SetPath(panel.paths().Dir(files[0].Path))
+	})
+	stopButton := widget.NewButton("停止", stopSearch)
+
+	resultWindow.SetOnClosed(stopSearch)
+	resultWindow.SetContent(container.NewBorder(
+		status,
+		container.NewHBox(copyButton, deleteButton, locateButton, stopButton),
+		nil, nil,
+		resultList,
+	))
+	resultWindow.Resize(fyne.NewSize(800, 500))
+	resultWindow.Show()
+
+	go func() {
+		started := time.Now()
+		skipped, err := transfer.SearchFiles(fileSystem, root, options, func(file transfer.FileInfo) {
+			mu.Lock()
+			results = append(results, file)
+			count := len(results)
+			mu.Unlock()
+			status.SetText(fmt.Sprintf("正在搜索... 已找到 %d 个", count))
+			resultList.Refresh()
+		}, stop)
+
+		mu.Lock()
+		count := len(results)
+		mu.Unlock()
+		stopButton.Disable()
+		switch {
+		case err != nil:
+			status.SetText(fmt.Sprintf("搜索失败: %v", err))
+		case skipped > 0:
+			status.SetText(fmt.Sprintf("搜索结束，找到 %d 个，用时 %s，%d 个目录无法读取", count, time.Since(started).Round(time.Millisecond), skipped))
+		default:
+			status.SetText(fmt.Sprintf("搜索结束，找到 %d 个，用时 %s", count, time.Since(started).Round(time.Millisecond)))
+		}
+	}()
+}
+
+// parseSearchDate 解析日期，endOfDay 为 true 时取当天结束时刻，空字符串表示不限
+func parseSearchDate(text string, endOfDay bool) (time.Time, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.ParseInLocation(searchDateLayout, text, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("无效的日期: %s，格式应为 %s", text, searchDateLayout)
+	}
+	if endOfDay {
+		date = date.Add(24*time.Hour - time.Nanosecond)
+	}
+	return date, nil
+}
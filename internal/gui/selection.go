@@ -0,0 +1,263 @@
+package gui
+
+import (
+	"path"
+	"strings"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+// fileList 支持键盘多选的文件列表
+type fileList struct {
+	widget.List
+	panel *FilePanel
+}
+
+// newFileList 创建文件列表
+func newFileList(panel *FilePanel) *fileList {
+	list := &fileList{panel: panel}
+	list.ExtendBaseWidget(list)
+	return list
+}
+
+// TypedKey 先执行快捷键绑定的操作，否则处理键盘选择：上下移动，Shift 扩展范围，空格切换选中
+func (l *fileList) TypedKey(event *fyne.KeyEvent) {
+	p := l.panel
+	if action, ok := p.keymap.action(event.Name, currentModifiers()); ok {
+		p.runAction(action)
+		return
+	}
+	if len(p.currentFiles) == 0 {
+		return
+	}
+
+	cursor := p.cursor
+	switch event.Name {
+	case fyne.KeyUp:
+		cursor--
+	case fyne.KeyDown:
+		cursor++
+	case fyne.KeyHome:
+		cursor = 0
+	case fyne.KeyEnd:
+		cursor = len(p.currentFiles) - 1
+	case fyne.KeySpace:
+		if p.cursor >= 0 {
+			p.toggleSelect(p.cursor)
+		}
+		return
+	default:
+		return
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	if cursor >= len(p.currentFiles) {
+		cursor = len(p.currentFiles) - 1
+	}
+
+	if currentModifiers()&fyne.KeyModifierShift != 0 {
+		p.selectRange(cursor)
+	} else {
+		p.selectOnly(cursor)
+	}
+	l.ScrollTo(cursor)
+}
+
+// TypedShortcut 执行快捷键绑定的操作，未绑定时处理 Ctrl+A 全选
+func (l *fileList) TypedShortcut(shortcut fyne.Shortcut) {
+	if key, ok := shortcut.(fyne.KeyboardShortcut); ok {
+		if action, ok := l.panel.keymap.action(key.Key(), key.Mod()); ok {
+			l.panel.runAction(action)
+			return
+		}
+	}
+	if _, ok := shortcut.(*fyne.ShortcutSelectAll); ok {
+		l.panel.SelectAll()
+	}
+}
+
+// AcceptsTab 绑定了 Tab 键时由列表处理，否则 Tab 在控件之间切换焦点
+func (l *fileList) AcceptsTab() bool {
+	return l.panel.keymap.bindsTab()
+}
+
+// currentModifiers 返回当前按下的修饰键
+func currentModifiers() fyne.KeyModifier {
+	if d, ok := fyne.CurrentApp().Driver().(desktop.Driver); ok {
+		return d.CurrentKeyModifiers()
+	}
+	return 0
+}
+
+// isToggleModifier 是否按下了切换选中的修饰键（Ctrl，macOS 上为 Command）
+func isToggleModifier(modifiers fyne.KeyModifier) bool {
+	return modifiers&(fyne.KeyModifierControl|fyne.KeyModifierSuper) != 0
+}
+
+// isSelected 判断第 index 项是否选中
+func (p *FilePanel) isSelected(index int) bool {
+	return index >= 0 && index < len(p.currentFiles) && p.selected[p.currentFiles[index].Path]
+}
+
+// selectOnly 只选中第 index 项
+func (p *FilePanel) selectOnly(index int) {
+	p.selected = map[string]bool{p.currentFiles[index].Path: true}
+	p.cursor, p.anchor = index, index
+	p.refreshSelection()
+}
+
+// toggleSelect 切换第 index 项的选中状态
+func (p *FilePanel) toggleSelect(index int) {
+	path := p.currentFiles[index].Path
+	if p.selected[path] {
+		delete(p.selected, path)
+	} else {
+		p.selected[path] = true
+	}
+	p.cursor, p.anchor = index, index
+	p.refreshSelection()
+}
+
+// selectRange 选中从锚点到 index 之间的所有项
+func (p *FilePanel) selectRange(index int) {
+	from, to := p.anchor, index
+	if from < 0 || from >= len(p.currentFiles) {
+		from = index
+	}
+	if from > to {
+		from, to = to, from
+	}
+	p.selected = make(map[string]bool)
+	for i := from; i <= to; i++ {
+		p.selected[p.currentFiles[i].Path] = true
+	}
+	p.cursor = index
+	p.refreshSelection()
+}
+
+// SelectAll 选中全部项
+func (p *FilePanel) SelectAll() {
+	p.selected = make(map[string]bool)
+	for _, file := range p.currentFiles {
+		p.selected[file.Path] = true
+	}
+	p.refreshSelection()
+}
+
+// InvertSelection 反选
+func (p *FilePanel) InvertSelection() {
+	inverted := make(map[string]bool)
+	for _, file := range p.currentFiles {
+		if !p.selected[file.Path] {
+			inverted[file.Path] = true
+		}
+	}
+	p.selected = inverted
+	p.refreshSelection()
+}
+
+// ClearSelection 取消全部选中
+func (p *FilePanel) ClearSelection() {
+	p.selected = make(map[string]bool)
+	p.anchor = -1
+	p.refreshSelection()
+}
+
+// SelectPattern 按通配符选中文件名匹配的项，返回匹配数量
+func (p *FilePanel) SelectPattern(patterns []string, add bool) (int, error) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return 0, err
+		}
+	}
+	if !add {
+		p.selected = make(map[string]bool)
+	}
+	count := 0
+	for _, file := range p.currentFiles {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, file.Name); ok {
+				p.selected[file.Path] = true
+				count++
+				break
+			}
+		}
+	}
+	p.refreshSelection()
+	return count, nil
+}
+
+// selectedFiles 按列表顺序返回选中的文件
+func (p *FilePanel) selectedFiles() []transfer.FileInfo {
+	var files []transfer.FileInfo
+	for _, file := range p.currentFiles {
+		if p.selected[file.Path] {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// pruneSelection 刷新列表后去掉已不存在的选中项
+func (p *FilePanel) pruneSelection() {
+	pruned := make(map[string]bool)
+	for _, file := range p.currentFiles {
+		if p.selected[file.Path] {
+			pruned[file.Path] = true
+		}
+	}
+	p.selected = pruned
+	if len(pruned) == 0 || p.cursor >= len(p.currentFiles) {
+		p.cursor, p.anchor = -1, -1
+	}
+}
+
+// showSelectMenu 显示选择菜单
+func (p *FilePanel) showSelectMenu(pos fyne.Position) {
+	menu := fyne.NewMenu("",
+		fyne.NewMenuItem("全选", p.SelectAll),
+		fyne.NewMenuItem("反选", p.InvertSelection),
+		fyne.NewMenuItem("按模式选择...", p.showSelectPatternDialog),
+		fyne.NewMenuItem("取消选择", p.ClearSelection),
+	)
+	widget.ShowPopUpMenuAtPosition(menu, p.window.Canvas(), pos)
+}
+
+// showSelectPatternDialog 显示按模式选择对话框
+func (p *FilePanel) showSelectPatternDialog() {
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("如 *.log, data_??.csv")
+	addCheck := widget.NewCheck("添加到当前选择", nil)
+
+	dialog.ShowForm("按模式选择",
+		"选择",
+		"取消",
+		[]*widget.FormItem{
+			widget.NewFormItem("模式", entry),
+			widget.NewFormItem("", addCheck),
+		},
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+			patterns := splitPatterns(entry.Text)
+			if len(patterns) == 0 {
+				return
+			}
+			count, err := p.SelectPattern(patterns, addCheck.Checked)
+			if err != nil {
+				dialog.ShowError(err, p.window)
+				return
+			}
+			if count == 0 {
+				dialog.ShowInformation("按模式选择", "没有匹配 "+strings.Join(patterns, ", ")+" 的文件", p.window)
+			}
+		},
+		p.window,
+	)
+}
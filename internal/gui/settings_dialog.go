@@ -0,0 +1,223 @@
+package gui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showTransferSettings 显示传输设置对话框，设置同时作用于左右两侧的所有标签页
+func (p *FilePanel) showTransferSettings() {
+	opts := p.transferOpts
+	rules := opts.Filters
+
+	continueCheck := widget.NewCheck("出错时继续传输剩余文件", nil)
+	continueCheck.SetChecked(opts.ContinueOnError)
+
+	deltaCheck := widget.NewCheck("增量上传已存在的文件（服务器需支持 python）", nil)
+	deltaCheck.SetChecked(opts.Delta)
+
+	tarCheck := widget.NewCheck("目录使用 tar 流传输（大量小文件时更快，服务器需允许执行 tar）", nil)
+	tarCheck.SetChecked(opts.TarStream)
+
+	retryEntry := widget.NewEntry()
+	retryEntry.SetText(strconv.Itoa(opts.Retry.MaxAttempts))
+
+	includeEntry := widget.NewEntry()
+	includeEntry.SetPlaceHolder("如 *.go, src/*.js")
+	includeEntry.SetText(strings.Join(rules.Include, ", "))
+
+	excludeEntry := widget.NewEntry()
+	excludeEntry.SetPlaceHolder("如 .git, node_modules, *.o")
+	excludeEntry.SetText(strings.Join(rules.Exclude, ", "))
+
+	includeRegexEntry := widget.NewMultiLineEntry()
+	includeRegexEntry.SetPlaceHolder("每行一个正则表达式")
+	includeRegexEntry.SetText(strings.Join(rules.IncludeRegex, "\n"))
+
+	excludeRegexEntry := widget.NewMultiLineEntry()
+	excludeRegexEntry.SetPlaceHolder("每行一个正则表达式")
+	excludeRegexEntry.SetText(strings.Join(rules.ExcludeRegex, "\n"))
+
+	minSizeEntry := widget.NewEntry()
+	minSizeEntry.SetPlaceHolder("如 1K")
+	minSizeEntry.SetText(formatSizeRule(rules.MinSize))
+
+	maxSizeEntry := widget.NewEntry()
+	maxSizeEntry.SetPlaceHolder("如 100M")
+	maxSizeEntry.SetText(formatSizeRule(rules.MaxSize))
+
+	minAgeEntry := widget.NewEntry()
+	minAgeEntry.SetPlaceHolder("如 2h、7d")
+	minAgeEntry.SetText(formatAgeRule(rules.MinAge))
+
+	maxAgeEntry := widget.NewEntry()
+	maxAgeEntry.SetPlaceHolder("如 30d")
+	maxAgeEntry.SetText(formatAgeRule(rules.MaxAge))
+
+	ignoreCheck := widget.NewCheck("读取源目录下的 "+transfer.IgnoreFileName, nil)
+	ignoreCheck.SetChecked(rules.UseIgnoreFile)
+
+	settingsDialog := dialog.NewForm("传输设置",
+		"确定",
+		"取消",
+		[]*widget.FormItem{
+			widget.NewFormItem("最大尝试次数", retryEntry),
+			widget.NewFormItem("", continueCheck),
+			widget.NewFormItem("", deltaCheck),
+			widget.NewFormItem("", tarCheck),
+			widget.NewFormItem("包含", includeEntry),
+			widget.NewFormItem("排除", excludeEntry),
+			widget.NewFormItem("包含正则", includeRegexEntry),
+			widget.NewFormItem("排除正则", excludeRegexEntry),
+			widget.NewFormItem("最小大小", minSizeEntry),
+			widget.NewFormItem("最大大小", maxSizeEntry),
+			widget.NewFormItem("最小年龄", minAgeEntry),
+			widget.NewFormItem("最大年龄", maxAgeEntry),
+			widget.NewFormItem("", ignoreCheck),
+		},
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+			attempts, err := strconv.Atoi(retryEntry.Text)
+			if err != nil || attempts < 1 {
+				dialog.ShowError(fmt.Errorf("尝试次数必须是正整数"), p.window)
+				return
+			}
+
+			newRules := transfer.FilterRules{
+				Include:       splitPatterns(includeEntry.Text),
+				Exclude:       splitPatterns(excludeEntry.Text),
+				IncludeRegex:  splitLines(includeRegexEntry.Text),
+				ExcludeRegex:  splitLines(excludeRegexEntry.Text),
+				UseIgnoreFile: ignoreCheck.Checked,
+			}
+			if newRules.MinSize, err = transfer.ParseSize(minSizeEntry.Text); err == nil {
+				newRules.MaxSize, err = transfer.ParseSize(maxSizeEntry.Text)
+			}
+			if err == nil {
+				newRules.MinAge, err = transfer.ParseAge(minAgeEntry.Text)
+			}
+			if err == nil {
+				newRules.MaxAge, err = transfer.ParseAge(maxAgeEntry.Text)
+			}
+			if err == nil {
+				// 提前编译，尽早发现无效的规则
+				_, err = transfer.NewFilter(newRules, nil)
+			}
+			if err != nil {
+				dialog.ShowError(err, p.window)
+				return
+			}
+
+			opts.Retry.MaxAttempts = attempts
+			opts.ContinueOnError = continueCheck.Checked
+			opts.Delta = deltaCheck.Checked
+			opts.TarStream = tarCheck.Checked
+			opts.Filters = newRules
+			if p.tabs == nil {
+				p.applyTransferOptions(opts)
+				if p.peer != nil {
+					p.peer.applyTransferOptions(opts)
+				}
+				return
+			}
+			p.tabs.SetTransferOptions(opts)
+			if p.tabs.peer != nil {
+				p.tabs.peer.SetTransferOptions(opts)
+			}
+		},
+		p.window,
+	)
+	settingsDialog.Resize(fyne.NewSize(500, 600))
+	settingsDialog.Show()
+}
+
+// applyTransferOptions 应用传输选项到面板的文件系统和远程连接
+func (p *FilePanel) applyTransferOptions(opts transfer.TransferOptions) {
+	p.transferOpts = opts
+	p.fileSystem.SetFilterRules(opts.Filters)
+	if p.remoteFS != nil {
+		p.remoteFS.SetTransferOptions(opts)
+	}
+}
+
+// describeFilters 返回对所选目录生效的过滤规则说明，没有目录或未设置规则时返回空字符串
+func (p *FilePanel) describeFilters(files []transfer.FileInfo) (string, error) {
+	if p.transferOpts.Filters.IsEmpty() {
+		return "", nil
+	}
+	var descriptions []string
+	seen := make(map[string]bool)
+	for _, file := range files {
+		if !file.IsDir {
+			continue
+		}
+		filter, err := p.fileSystem.LoadFilter(file.Path)
+		if err != nil {
+			return "", err
+		}
+		// 各目录的规则通常相同，只显示一次
+		if description := filter.Describe(); !seen[description] {
+			seen[description] = true
+			descriptions = append(descriptions, description)
+		}
+	}
+	return strings.Join(descriptions, "\n\n"), nil
+}
+
+// confirmFilters 处理目录前显示生效的过滤规则，确认后执行 run
+func (p *FilePanel) confirmFilters(files []transfer.FileInfo, action string, run func()) {
+	rules, err := p.describeFilters(files)
+	if err != nil {
+		dialog.ShowError(err, p.window)
+		return
+	}
+	if rules == "" {
+		run()
+		return
+	}
+	dialog.ShowConfirm(action+"确认",
+		fmt.Sprintf("将按以下过滤规则%s %s：\n\n%s", action, describeFiles(files), rules),
+		func(confirm bool) {
+			if confirm {
+				run()
+			}
+		},
+		p.window,
+	)
+}
+
+// splitLines 按行拆分，忽略空行
+func splitLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// formatSizeRule 格式化大小规则，0表示不限
+func formatSizeRule(size int64) string {
+	if size == 0 {
+		return ""
+	}
+	return strconv.FormatInt(size, 10)
+}
+
+// formatAgeRule 格式化时间规则，0表示不限
+func formatAgeRule(age time.Duration) string {
+	if age == 0 {
+		return ""
+	}
+	return age.String()
+}
@@ -0,0 +1,231 @@
+package gui
+
+import (
+	"fmt"
+	"strings"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// runAction 执行快捷键绑定的操作，未连接的标签页只能切换面板和标签页
+func (p *FilePanel) runAction(action string) {
+	switch action {
+	case actionSwitch, actionNewTab, actionCloseTab, actionDuplicateTab, actionNextTab, actionPrevTab:
+	default:
+		if err := p.checkConnected(); err != nil {
+			dialog.ShowError(err, p.window)
+			return
+		}
+	}
+	switch action {
+	case actionOpen:
+		if p.cursor >= 0 && p.cursor < len(p.currentFiles) {
+			p.openItem(p.currentFiles[p.cursor])
+		}
+	case actionParent:
+		p.goParent()
+	case actionBack:
+		p.GoBack()
+	case actionForward:
+		p.GoForward()
+	case actionSwitch:
+		if p.peer != nil {
+			p.peer.Focus()
+		}
+	case actionCopy:
+		p.transferFiles(p.actionFiles(), transfer.Copy)
+	case actionMove:
+		p.transferFiles(p.actionFiles(), transfer.Move)
+	case actionMkdir:
+		p.showMkdirDialog()
+	case actionDelete:
+		p.confirmDelete(p.actionFiles())
+	case actionRename:
+		if files := p.actionFiles(); len(files) == 1 {
+			p.showRenameDialog(files[0])
+		}
+	case actionRefresh:
+		p.RefreshFiles()
+	case actionPath:
+		p.window.Canvas().Focus(p.pathEntry)
+		p.pathEntry.TypedShortcut(&fyne.ShortcutSelectAll{})
+	case actionTerminal:
+		p.openTerminal(p.GetCurrentPath())
+	case actionNewTab, actionCloseTab, actionDuplicateTab, actionNextTab, actionPrevTab:
+		p.runTabAction(action)
+	}
+}
+
+// runTabAction 执行标签页操作，面板不在标签页中时忽略
+func (p *FilePanel) runTabAction(action string) {
+	if p.tabs == nil {
+		return
+	}
+	switch action {
+	case actionNewTab:
+		p.tabs.NewTab()
+	case actionCloseTab:
+		p.tabs.closeTab(p)
+	case actionDuplicateTab:
+		p.tabs.duplicateTab(p)
+	case actionNextTab:
+		p.tabs.cycleTab(1)
+	case actionPrevTab:
+		p.tabs.cycleTab(-1)
+	}
+}
+
+// Focus 将键盘焦点移到文件列表
+func (p *FilePanel) Focus() {
+	p.window.Canvas().Focus(p.list)
+}
+
+// actionFiles 返回快捷键操作的对象：选中项，没有选中项时为光标所在项
+func (p *FilePanel) actionFiles() []transfer.FileInfo {
+	if files := p.selectedFiles(); len(files) > 0 {
+		return files
+	}
+	if p.cursor >= 0 && p.cursor < len(p.currentFiles) {
+		return []transfer.FileInfo{p.currentFiles[p.cursor]}
+	}
+	return nil
+}
+
+// openItem 进入目录，或在预览窗口中打开文件
+func (p *FilePanel) openItem(file transfer.FileInfo) {
+	if file.IsDir {
+		p.SetPath(file.Path)
+		return
+	}
+	ShowPreview(p.fileSystem, file.Path, 0, p.window)
+}
+
+// goParent 返回上一级目录，并将光标放在刚离开的目录上
+func (p *FilePanel) goParent() {
+	current := p.GetCurrentPath()
+	parent := p.paths().Dir(current)
+	if parent == current {
+		return
+	}
+	p.SetPath(parent)
+	for i, file := range p.currentFiles {
+		if file.Path == current {
+			p.selectOnly(i)
+			p.list.ScrollTo(i)
+			break
+		}
+	}
+}
+
+// transferFiles 将文件复制或移动到对侧面板的当前目录
+func (p *FilePanel) transferFiles(files []transfer.FileInfo, transferType transfer.TransferType) {
+	if len(files) == 0 || p.onTransfer == nil {
+		return
+	}
+	action := "复制"
+	if transferType == transfer.Move {
+		action = "移动"
+	}
+	p.confirmFilters(files, action, func() {
+		p.onTransfer(filePaths(files), p, transferType)
+	})
+}
+
+// showMkdirDialog 在当前目录下新建文件夹
+func (p *FilePanel) showMkdirDialog() {
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("输入文件夹名称")
+	dialog.ShowForm("新建文件夹",
+		"创建",
+		"取消",
+		[]*widget.FormItem{
+			widget.NewFormItem("名称", entry),
+		},
+		func(confirm bool) {
+			defer p.Focus()
+			if !confirm {
+				return
+			}
+			if err := validateName(entry.Text); err != nil {
+				dialog.ShowError(err, p.window)
+				return
+			}
+			path := p.paths().Join(p.GetCurrentPath(), entry.Text)
+			if err := p.fileSystem.CreateDirectory(path); err != nil {
+				dialog.ShowError(err, p.window)
+				return
+			}
+			p.RefreshFiles()
+		},
+		p.window,
+	)
+}
+
+// confirmDelete 确认后将删除加入传输队列，删除前显示生效的过滤规则
+func (p *FilePanel) confirmDelete(files []transfer.FileInfo) {
+	if len(files) == 0 {
+		return
+	}
+	message := fmt.Sprintf("确定要删除 %s 吗？", describeFiles(files))
+	if rules, err := p.describeFilters(files); err != nil {
+		dialog.ShowError(err, p.window)
+		return
+	} else if rules != "" {
+		message += "\n\n将按以下过滤规则删除：\n" + rules
+	}
+	dialog.ShowConfirm("删除确认",
+		message,
+		func(confirm bool) {
+			defer p.Focus()
+			if !confirm {
+				return
+			}
+			p.queueDelete(files)
+		},
+		p.window,
+	)
+}
+
+// showRenameDialog 重命名文件或目录
+func (p *FilePanel) showRenameDialog(file transfer.FileInfo) {
+	entry := widget.NewEntry()
+	entry.SetText(file.Name)
+	dialog.ShowForm("重命名",
+		"确定",
+		"取消",
+		[]*widget.FormItem{
+			widget.NewFormItem("新名称", entry),
+		},
+		func(confirm bool) {
+			defer p.Focus()
+			if !confirm || entry.Text == file.Name {
+				return
+			}
+			if err := validateName(entry.Text); err != nil {
+				dialog.ShowError(err, p.window)
+				return
+			}
+			newPath := p.paths().Join(p.paths().Dir(file.Path), entry.Text)
+			if err := p.fileSystem.Rename(file.Path, newPath); err != nil {
+				dialog.ShowError(err, p.window)
+				return
+			}
+			p.RefreshFiles()
+		},
+		p.window,
+	)
+}
+
+// validateName 检查新建或重命名使用的名称
+func validateName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("名称不能为空")
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("名称不能包含路径分隔符: %s", name)
+	}
+	return nil
+}
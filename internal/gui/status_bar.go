@@ -0,0 +1,137 @@
+package gui
+
+import (
+	"fmt"
+	"time"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// latencyInterval 测量连接延迟的间隔
+const latencyInterval = 10 * time.Second
+
+// newStatusBar 创建面板底部的状态栏：连接信息、项目和选中统计、可用空间
+func (p *FilePanel) newStatusBar() fyne.CanvasObject {
+	p.statusConn = widget.NewLabel("")
+	p.statusCounts = widget.NewLabel("")
+	p.statusSpace = widget.NewLabel("")
+	p.statusConn.Truncation = fyne.TextTruncateEllipsis
+	p.updateConnection(0)
+	return container.NewBorder(nil, nil, nil,
+		container.NewHBox(p.statusCounts, widget.NewSeparator(), p.statusSpace),
+		container.NewHBox(p.statusConn, layout.NewSpacer()),
+	)
+}
+
+// refreshSelection 选中项变化后刷新列表和状态栏
+func (p *FilePanel) refreshSelection() {
+	p.list.Refresh()
+	p.updateCounts()
+}
+
+// updateCounts 更新项目数和选中项统计
+func (p *FilePanel) updateCounts() {
+	dirs := 0
+	for _, file := range p.currentFiles {
+		if file.IsDir {
+			dirs++
+		}
+	}
+	text := fmt.Sprintf("%d 项（%d 个目录）", len(p.currentFiles), dirs)
+	if len(p.currentFiles) != len(p.listing) {
+		text += fmt.Sprintf("，已过滤 %d 项", len(p.listing)-len(p.currentFiles))
+	}
+
+	if selected := p.selectedFiles(); len(selected) > 0 {
+		var size int64
+		for _, file := range selected {
+			if !file.IsDir {
+				size += file.Size
+			}
+		}
+		text += fmt.Sprintf("  已选 %d 项，%s", len(selected), transfer.FormatSize(size))
+	}
+	p.statusCounts.SetText(text)
+}
+
+// updateDiskSpace 在后台获取当前目录所在文件系统的可用空间
+func (p *FilePanel) updateDiskSpace() {
+	fileSystem, dir := p.fileSystem, p.GetCurrentPath()
+	go func() {
+		space, err := fileSystem.DiskSpace(dir)
+		if dir != p.GetCurrentPath() {
+			// 已切换到其他目录
+			return
+		}
+		if err != nil {
+			p.statusSpace.SetText("可用空间未知")
+			return
+		}
+		p.statusSpace.SetText(fmt.Sprintf("可用 %s / 共 %s",
+			transfer.FormatSize(space.Free), transfer.FormatSize(space.Total)))
+	}()
+}
+
+// updateConnection 更新连接信息，latency 为0表示尚未测量
+func (p *FilePanel) updateConnection(latency time.Duration) {
+	if p.remoteFS == nil {
+		p.statusConn.SetText("本地")
+		return
+	}
+	info := p.remoteFS.ConnectionInfo()
+	text := info.String()
+	if info.Cipher != "" {
+		text += "  " + info.Cipher
+	}
+	if latency > 0 {
+		text += fmt.Sprintf("  延迟 %d ms", latency.Milliseconds())
+	}
+	p.statusConn.SetText(text)
+}
+
+// startLatencyMonitor 定期测量当前连接的延迟
+func (p *FilePanel) startLatencyMonitor() {
+	p.stopLatencyMonitor()
+	p.updateConnection(0)
+	if p.remoteFS == nil {
+		return
+	}
+
+	remoteFS := p.remoteFS
+	stop := make(chan struct{})
+	p.latencyStop = stop
+	go func() {
+		ticker := time.NewTicker(latencyInterval)
+		defer ticker.Stop()
+		for {
+			latency, err := remoteFS.Ping()
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err == nil {
+				p.updateConnection(latency)
+			} else {
+				p.statusConn.SetText(remoteFS.ConnectionInfo().String() + "  连接已断开")
+			}
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stopLatencyMonitor 停止测量延迟
+func (p *FilePanel) stopLatencyMonitor() {
+	if p.latencyStop != nil {
+		close(p.latencyStop)
+		p.latencyStop = nil
+	}
+}
@@ -0,0 +1,165 @@
+package gui
+
+import (
+	"fmt"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 同步模式选项，顺序与 transfer.SyncMode 一致
+var syncModeOptions = []string{
+	"上传镜像（本地 → 远程）",
+	"下载镜像（远程 → 本地）",
+	"双向同步",
+}
+
+// SyncDialog 表示目录同步对话框
+type SyncDialog struct {
+	window     fyne.Window
+	remoteFS   transfer.RemoteFS
+	localPath  string
+	remotePath string
+	progress   func(current, total int64)
+	onDone     func(error)
+}
+
+// NewSyncDialog 创建新的目录同步对话框
+func NewSyncDialog(window fyne.Window, remoteFS transfer.RemoteFS, localPath, remotePath string, progress func(current, total int64), onDone func(error)) *SyncDialog {
+	return &SyncDialog{
+		window:     window,
+		remoteFS:   remoteFS,
+		localPath:  localPath,
+		remotePath: remotePath,
+		progress:   progress,
+		onDone:     onDone,
+	}
+}
+
+// Show 显示同步设置对话框
+func (d *SyncDialog) Show() {
+	localEntry := widget.NewEntry()
+	localEntry.SetText(d.localPath)
+
+	remoteEntry := widget.NewEntry()
+	remoteEntry.SetText(d.remotePath)
+
+	modeSelect := widget.NewSelect(syncModeOptions, nil)
+	modeSelect.SetSelectedIndex(0)
+
+	deleteCheck := widget.NewCheck("删除目标端多余的文件（仅单向镜像）", nil)
+	checksumCheck := widget.NewCheck("大小相同时比较校验和（较慢）", nil)
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("本地目录", localEntry),
+		widget.NewFormItem("远程目录", remoteEntry),
+		widget.NewFormItem("模式", modeSelect),
+		widget.NewFormItem("", deleteCheck),
+		widget.NewFormItem("", checksumCheck),
+	}
+
+	formDialog := dialog.NewForm(
+		"目录同步",
+		"预览",
+		"取消",
+		items,
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+			if localEntry.Text == "" || remoteEntry.Text == "" {
+				dialog.ShowError(fmt.Errorf("本地目录和远程目录都必须填写"), d.window)
+				return
+			}
+
+			options := transfer.DefaultSyncOptions()
+			options.Mode = transfer.SyncMode(modeSelect.SelectedIndex())
+			options.DeleteExtras = deleteCheck.Checked
+			options.CompareChecksum = checksumCheck.Checked
+
+			plan, err := d.remoteFS.PlanSync(localEntry.Text, remoteEntry.Text, options)
+			if err != nil {
+				dialog.ShowError(err, d.window)
+				return
+			}
+			d.showPlan(plan)
+		},
+		d.window,
+	)
+	formDialog.Resize(fyne.NewSize(500, 350))
+	formDialog.Show()
+}
+
+// showPlan 显示同步计划（dry-run），确认后执行
+func (d *SyncDialog) showPlan(plan *transfer.SyncPlan) {
+	if len(plan.Actions) == 0 {
+		dialog.ShowInformation("目录同步", "两端已一致，无需同步", d.window)
+		return
+	}
+
+	actions := widget.NewList(
+		func() int {
+			return len(plan.Actions)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(formatSyncAction(plan.Actions[id]))
+		},
+	)
+
+	summary := widget.NewLabel(fmt.Sprintf(
+		"复制 %d，更新 %d，删除 %d，冲突 %d（冲突项不会执行）",
+		plan.Count(transfer.SyncCopy),
+		plan.Count(transfer.SyncUpdate),
+		plan.Count(transfer.SyncDelete),
+		plan.Count(transfer.SyncConflict),
+	))
+	header := container.NewVBox(summary)
+	if plan.Filter != nil {
+		header.Add(widget.NewLabel("过滤规则：\n" + plan.Filter.Describe()))
+	}
+	content := container.NewBorder(header, nil, nil, nil, actions)
+
+	planDialog := dialog.NewCustomConfirm("同步预览", "执行", "取消", content, func(confirm bool) {
+		if !confirm {
+			return
+		}
+		err := d.remoteFS.ExecuteSync(plan, d.progress)
+		if d.onDone != nil {
+			d.onDone(err)
+		}
+	}, d.window)
+	planDialog.Resize(fyne.NewSize(700, 500))
+	planDialog.Show()
+}
+
+// formatSyncAction 格式化同步动作的显示文本
+func formatSyncAction(action transfer.SyncAction) string {
+	arrow := ""
+	switch action.Type {
+	case transfer.SyncCopy, transfer.SyncUpdate:
+		arrow = "→ 远程"
+		if action.Direction == transfer.Download {
+			arrow = "→ 本地"
+		}
+	case transfer.SyncDelete:
+		arrow = "远程"
+		if action.Direction == transfer.Download {
+			arrow = "本地"
+		}
+	}
+
+	name := action.RelPath
+	if action.IsDir {
+		name += "/"
+	}
+	if arrow != "" {
+		name = arrow + " " + name
+	}
+	return fmt.Sprintf("[%s] %s（%s）", action.Type, name, action.Reason)
+}
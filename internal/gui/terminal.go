@@ -0,0 +1,645 @@
+package gui
+
+import (
+	"image/color"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// terminalRefresh 终端重绘间隔，合并短时间内的多次输出
+const terminalRefresh = 30 * time.Millisecond
+
+// terminalPalette 16 色调色板
+var terminalPalette = [16]color.NRGBA{
+	{0x00, 0x00, 0x00, 0xff}, {0xcd, 0x31, 0x31, 0xff}, {0x0d, 0xbc, 0x79, 0xff}, {0xe5, 0xe5, 0x10, 0xff},
+	{0x24, 0x72, 0xc8, 0xff}, {0xbc, 0x3f, 0xbc, 0xff}, {0x11, 0xa8, 0xcd, 0xff}, {0xe5, 0xe5, 0xe5, 0xff},
+	{0x66, 0x66, 0x66, 0xff}, {0xf1, 0x4c, 0x4c, 0xff}, {0x23, 0xd1, 0x8b, 0xff}, {0xf5, 0xf5, 0x43, 0xff},
+	{0x3b, 0x8e, 0xea, 0xff}, {0xd6, 0x70, 0xd6, 0xff}, {0x29, 0xb8, 0xdb, 0xff}, {0xff, 0xff, 0xff, 0xff},
+}
+
+// paletteColor 返回调色板颜色：0-15 为基本颜色，16-231 为 6×6×6 色立方，232-255 为灰度
+func paletteColor(c termColor) color.Color {
+	switch {
+	case c&termRGB != 0:
+		return color.NRGBA{R: uint8(c >> 16), G: uint8(c >> 8), B: uint8(c), A: 0xff}
+	case c < 16:
+		return terminalPalette[c]
+	case c < 232:
+		c -= 16
+		level := func(v termColor) uint8 {
+			if v == 0 {
+				return 0
+			}
+			return uint8(55 + v*40)
+		}
+		return color.NRGBA{R: level(c / 36), G: level(c / 6 % 6), B: level(c % 6), A: 0xff}
+	default:
+		gray := uint8(8 + (c-232)*10)
+		return color.NRGBA{R: gray, G: gray, B: gray, A: 0xff}
+	}
+}
+
+// terminalView 终端视图：显示 shell 输出，将键盘输入发送给 shell，支持选择复制、粘贴和回滚
+type terminalView struct {
+	widget.BaseWidget
+	window fyne.Window
+	grid   *widget.TextGrid
+
+	mu        sync.Mutex // 保护 screen、offset 和选择区域
+	screen    *termScreen
+	offset    int // 向上回滚的行数，0 表示显示最新内容
+	selFrom   termPos
+	selTo     termPos
+	selecting bool
+	hasSel    bool
+
+	shell   *transfer.Shell
+	focused bool
+	exited  bool // shell 已退出，不再显示光标
+	dirty   atomic.Bool
+	closed  chan struct{}
+	styles  map[termAttr]widget.TextGridStyle
+	onTitle func(title string, exited bool) // 标题变化或 shell 退出时调用
+}
+
+// newTerminalView 创建终端视图，并在后台打开远程 shell，dir 不为空时进入该目录
+func newTerminalView(window fyne.Window, remoteFS transfer.RemoteFS, dir string, onTitle func(title string, exited bool)) *terminalView {
+	v := &terminalView{
+		window:  window,
+		grid:    widget.NewTextGrid(),
+		screen:  newTermScreen(80, 24),
+		closed:  make(chan struct{}),
+		styles:  make(map[termAttr]widget.TextGridStyle),
+		onTitle: onTitle,
+	}
+	v.ExtendBaseWidget(v)
+
+	go v.refreshLoop()
+	go func() {
+		v.mu.Lock()
+		cols, rows := v.screen.cols, v.screen.rows
+		v.mu.Unlock()
+		shell, err := remoteFS.OpenShell(cols, rows, dir)
+		if err != nil {
+			v.output([]byte("\r\n" + err.Error() + "\r\n"))
+			v.finish()
+			return
+		}
+		v.mu.Lock()
+		select {
+		case <-v.closed:
+			// 打开期间标签页已关闭，close 时还没有可关闭的 shell
+			v.mu.Unlock()
+			shell.Close()
+			return
+		default:
+		}
+		v.shell = shell
+		// 打开期间终端大小可能已变化
+		if v.screen.cols != cols || v.screen.rows != rows {
+			shell.Resize(v.screen.cols, v.screen.rows)
+		}
+		v.mu.Unlock()
+		v.readLoop(shell)
+	}()
+	return v
+}
+
+// readLoop 读取 shell 输出直到退出
+func (v *terminalView) readLoop(shell *transfer.Shell) {
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := shell.Read(buf)
+		if n > 0 {
+			v.output(buf[:n])
+		}
+		if err != nil {
+			break
+		}
+	}
+	select {
+	case <-v.closed:
+		return
+	default:
+	}
+	v.output([]byte("\r\n[会话已结束]\r\n"))
+	v.finish()
+}
+
+// output 处理 shell 输出，回复终端查询
+func (v *terminalView) output(data []byte) {
+	v.mu.Lock()
+	title := v.screen.title
+	v.screen.write(data)
+	responses := v.screen.takeResponses()
+	titleChanged := v.screen.title != title
+	title = v.screen.title
+	exited := v.exited
+	v.mu.Unlock()
+	v.dirty.Store(true)
+	if len(responses) > 0 {
+		v.send(responses)
+	}
+	if titleChanged && v.onTitle != nil {
+		v.onTitle(title, exited)
+	}
+}
+
+// finish 标记 shell 已退出
+func (v *terminalView) finish() {
+	v.mu.Lock()
+	v.exited = true
+	v.shell = nil
+	title := v.screen.title
+	v.mu.Unlock()
+	v.dirty.Store(true)
+	if v.onTitle != nil {
+		v.onTitle(title, true)
+	}
+}
+
+// send 向 shell 发送输入
+func (v *terminalView) send(data []byte) {
+	v.mu.Lock()
+	shell := v.shell
+	v.mu.Unlock()
+	if shell == nil {
+		return
+	}
+	shell.Write(data)
+}
+
+// refreshLoop 定期将屏幕变化绘制到文本网格
+func (v *terminalView) refreshLoop() {
+	ticker := time.NewTicker(terminalRefresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-v.closed:
+			return
+		case <-ticker.C:
+			if v.dirty.Swap(false) {
+				v.render()
+			}
+		}
+	}
+}
+
+// render 按回滚位置、选择区域和光标生成文本网格的内容
+func (v *terminalView) render() {
+	v.mu.Lock()
+	s := v.screen
+	first := len(s.scrollback) - v.offset
+	selFrom, selTo := v.selFrom, v.selTo
+	if selTo.before(selFrom) {
+		selFrom, selTo = selTo, selFrom
+	}
+	selected := &widget.CustomTextGridStyle{FGColor: theme.ForegroundColor(), BGColor: theme.SelectionColor()}
+	cursor := &widget.CustomTextGridStyle{FGColor: theme.BackgroundColor(), BGColor: theme.ForegroundColor()}
+	rows := make([]widget.TextGridRow, s.rows)
+	for y := range rows {
+		line := s.line(first + y)
+		abs := s.trimmed + first + y
+		cells := make([]widget.TextGridCell, len(line))
+		for x, cell := range line {
+			if cell.r == 0 {
+				// 宽字符的后半格不设置背景，以免遮住字符
+				continue
+			}
+			cells[x] = widget.TextGridCell{Rune: cell.r, Style: v.style(cell.attr)}
+			pos := termPos{line: abs, col: x}
+			if v.hasSel && !pos.before(selFrom) && !selTo.before(pos) {
+				cells[x].Style = selected
+			}
+		}
+		if v.offset == 0 && y == s.cur.y && s.cursorVisible && !v.exited && s.cur.x < len(cells) {
+			if v.focused {
+				cells[s.cur.x].Style = cursor
+			} else {
+				cells[s.cur.x].Style = &widget.CustomTextGridStyle{BGColor: theme.DisabledColor()}
+			}
+		}
+		rows[y] = widget.TextGridRow{Cells: cells}
+	}
+	v.mu.Unlock()
+
+	v.grid.Rows = rows
+	v.grid.Refresh()
+}
+
+// style 返回字符属性对应的样式，相同属性共用一个样式
+func (v *terminalView) style(attr termAttr) widget.TextGridStyle {
+	if attr == defaultAttr {
+		return nil
+	}
+	if style, ok := v.styles[attr]; ok {
+		return style
+	}
+	var fg, bg color.Color
+	if attr.fg != termDefault {
+		fg = paletteColor(attr.fg)
+	}
+	if attr.bg != termDefault {
+		bg = paletteColor(attr.bg)
+	}
+	if attr.reverse {
+		if fg == nil {
+			fg = theme.ForegroundColor()
+		}
+		if bg == nil {
+			bg = theme.BackgroundColor()
+		}
+		fg, bg = bg, fg
+	}
+	style := &widget.CustomTextGridStyle{
+		FGColor:   fg,
+		BGColor:   bg,
+		TextStyle: fyne.TextStyle{Bold: attr.bold, Underline: attr.underline},
+	}
+	v.styles[attr] = style
+	return style
+}
+
+// cellSize 返回一个字符的大小，与文本网格的计算方式一致
+func (v *terminalView) cellSize() fyne.Size {
+	size := fyne.MeasureText("M", theme.TextSize(), fyne.TextStyle{Monospace: true})
+	return fyne.NewSize(float32(math.Round(float64(size.Width))), float32(math.Round(float64(size.Height))))
+}
+
+// resize 按视图大小调整终端的行列数并通知服务器
+func (v *terminalView) resize(size fyne.Size) {
+	cell := v.cellSize()
+	cols := max(int(size.Width/cell.Width), 1)
+	rows := max(int(size.Height/cell.Height), 1)
+	v.mu.Lock()
+	if cols == v.screen.cols && rows == v.screen.rows {
+		v.mu.Unlock()
+		return
+	}
+	v.screen.resize(cols, rows)
+	v.offset = min(v.offset, len(v.screen.scrollback))
+	shell := v.shell
+	v.mu.Unlock()
+	if shell != nil {
+		shell.Resize(cols, rows)
+	}
+	v.dirty.Store(true)
+}
+
+// scroll 向上（正数）或向下回滚 lines 行
+func (v *terminalView) scroll(lines int) {
+	v.mu.Lock()
+	v.offset = max(0, min(v.offset+lines, len(v.screen.scrollback)))
+	v.mu.Unlock()
+	v.dirty.Store(true)
+}
+
+// position 将视图内的坐标换算为选择区域的位置
+func (v *terminalView) position(pos fyne.Position) termPos {
+	cell := v.cellSize()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s := v.screen
+	y := max(0, min(int(pos.Y/cell.Height), s.rows-1))
+	x := max(0, min(int(pos.X/cell.Width), s.cols-1))
+	return termPos{line: s.trimmed + len(s.scrollback) - v.offset + y, col: x}
+}
+
+// selection 返回选中的文本
+func (v *terminalView) selection() string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !v.hasSel {
+		return ""
+	}
+	return v.screen.text(v.selFrom, v.selTo)
+}
+
+// clearSelection 取消选择
+func (v *terminalView) clearSelection() {
+	v.mu.Lock()
+	v.hasSel = false
+	v.mu.Unlock()
+	v.dirty.Store(true)
+}
+
+// copySelection 复制选中的文本，没有选择时返回 false
+func (v *terminalView) copySelection() bool {
+	text := v.selection()
+	if text == "" {
+		return false
+	}
+	v.window.Clipboard().SetContent(text)
+	return true
+}
+
+// paste 粘贴剪贴板内容，换行转换为回车；shell 开启括号粘贴模式时加上标记
+func (v *terminalView) paste() {
+	text := v.window.Clipboard().Content()
+	if text == "" {
+		return
+	}
+	text = strings.NewReplacer("\r\n", "\r", "\n", "\r").Replace(text)
+	v.mu.Lock()
+	bracketed := v.screen.bracketedPaste
+	v.mu.Unlock()
+	if bracketed {
+		text = "\x1b[200~" + text + "\x1b[201~"
+	}
+	v.input([]byte(text))
+}
+
+// clearScrollback 清空回滚缓冲
+func (v *terminalView) clearScrollback() {
+	v.mu.Lock()
+	v.screen.clearScrollback()
+	v.offset = 0
+	v.hasSel = false
+	v.mu.Unlock()
+	v.dirty.Store(true)
+}
+
+// input 发送用户输入，并回到最新内容
+func (v *terminalView) input(data []byte) {
+	v.mu.Lock()
+	scrolled := v.offset != 0
+	v.offset = 0
+	v.mu.Unlock()
+	if scrolled {
+		v.dirty.Store(true)
+	}
+	v.send(data)
+}
+
+// close 结束 shell 并停止刷新
+func (v *terminalView) close() {
+	select {
+	case <-v.closed:
+		return
+	default:
+	}
+	close(v.closed)
+	v.mu.Lock()
+	shell := v.shell
+	v.shell = nil
+	v.mu.Unlock()
+	if shell != nil {
+		shell.Close()
+	}
+}
+
+// CreateRenderer 创建渲染器
+func (v *terminalView) CreateRenderer() fyne.WidgetRenderer {
+	return &terminalRenderer{view: v, bg: canvas.NewRectangle(theme.BackgroundColor())}
+}
+
+// terminalRenderer 终端视图的渲染器
+type terminalRenderer struct {
+	view *terminalView
+	bg   *canvas.Rectangle
+}
+
+// Layout 文本网格填满视图，并按大小调整终端行列数
+func (r *terminalRenderer) Layout(size fyne.Size) {
+	r.bg.Resize(size)
+	r.view.grid.Resize(size)
+	r.view.resize(size)
+}
+
+// MinSize 至少显示一个字符
+func (r *terminalRenderer) MinSize() fyne.Size {
+	return r.view.cellSize()
+}
+
+// Refresh 刷新背景色和文本网格
+func (r *terminalRenderer) Refresh() {
+	r.bg.FillColor = theme.BackgroundColor()
+	r.bg.Refresh()
+	r.view.grid.Refresh()
+}
+
+// Objects 返回背景和文本网格
+func (r *terminalRenderer) Objects() []fyne.CanvasObject {
+	return []fyne.CanvasObject{r.bg, r.view.grid}
+}
+
+// Destroy 实现渲染器接口
+func (r *terminalRenderer) Destroy() {
+}
+
+// FocusGained 获得焦点
+func (v *terminalView) FocusGained() {
+	v.mu.Lock()
+	v.focused = true
+	v.mu.Unlock()
+	v.dirty.Store(true)
+}
+
+// FocusLost 失去焦点
+func (v *terminalView) FocusLost() {
+	v.mu.Lock()
+	v.focused = false
+	v.mu.Unlock()
+	v.dirty.Store(true)
+}
+
+// AcceptsTab Tab 键发送给 shell
+func (v *terminalView) AcceptsTab() bool {
+	return true
+}
+
+// TypedRune 发送输入的字符
+func (v *terminalView) TypedRune(r rune) {
+	buf := make([]byte, utf8.UTFMax)
+	v.input(buf[:utf8.EncodeRune(buf, r)])
+}
+
+// terminalKeys 特殊按键对应的输入序列
+var terminalKeys = map[fyne.KeyName]string{
+	fyne.KeyReturn:    "\r",
+	fyne.KeyEnter:     "\r",
+	fyne.KeyBackspace: "\x7f",
+	fyne.KeyTab:       "\t",
+	fyne.KeyEscape:    "\x1b",
+	fyne.KeyInsert:    "\x1b[2~",
+	fyne.KeyDelete:    "\x1b[3~",
+	fyne.KeyPageUp:    "\x1b[5~",
+	fyne.KeyPageDown:  "\x1b[6~",
+	fyne.KeyF1:        "\x1bOP",
+	fyne.KeyF2:        "\x1bOQ",
+	fyne.KeyF3:        "\x1bOR",
+	fyne.KeyF4:        "\x1bOS",
+	fyne.KeyF5:        "\x1b[15~",
+	fyne.KeyF6:        "\x1b[17~",
+	fyne.KeyF7:        "\x1b[18~",
+	fyne.KeyF8:        "\x1b[19~",
+	fyne.KeyF9:        "\x1b[20~",
+	fyne.KeyF10:       "\x1b[21~",
+	fyne.KeyF11:       "\x1b[23~",
+	fyne.KeyF12:       "\x1b[24~",
+}
+
+// cursorKeys 光标键的序列结尾，应用模式下以 ESC O 开头，否则以 ESC [ 开头
+var cursorKeys = map[fyne.KeyName]string{
+	fyne.KeyUp:    "A",
+	fyne.KeyDown:  "B",
+	fyne.KeyRight: "C",
+	fyne.KeyLeft:  "D",
+	fyne.KeyHome:  "H",
+	fyne.KeyEnd:   "F",
+}
+
+// TypedKey 发送特殊按键；Shift+PageUp/PageDown 查看回滚内容
+func (v *terminalView) TypedKey(event *fyne.KeyEvent) {
+	if currentModifiers()&fyne.KeyModifierShift != 0 {
+		v.mu.Lock()
+		page := v.screen.rows - 1
+		v.mu.Unlock()
+		switch event.Name {
+		case fyne.KeyPageUp:
+			v.scroll(page)
+			return
+		case fyne.KeyPageDown:
+			v.scroll(-page)
+			return
+		}
+	}
+	if final, ok := cursorKeys[event.Name]; ok {
+		v.mu.Lock()
+		prefix := "\x1b["
+		if v.screen.appCursor {
+			prefix = "\x1bO"
+		}
+		v.mu.Unlock()
+		v.input([]byte(prefix + final))
+		return
+	}
+	if seq, ok := terminalKeys[event.Name]; ok {
+		v.input([]byte(seq))
+	}
+}
+
+// TypedShortcut 处理 Ctrl 和 Alt 组合键：Ctrl+C 在有选择时复制，否则发送中断；
+// Ctrl+Shift+C/Ctrl+Shift+V 复制/粘贴，其余组合键转换为控制字符
+func (v *terminalView) TypedShortcut(shortcut fyne.Shortcut) {
+	switch shortcut.(type) {
+	case *fyne.ShortcutCopy:
+		if !v.copySelection() {
+			v.input([]byte{0x03})
+		}
+		return
+	case *fyne.ShortcutPaste:
+		v.paste()
+		return
+	}
+	ks, ok := shortcut.(fyne.KeyboardShortcut)
+	if !ok {
+		return
+	}
+	key, mod := ks.Key(), ks.Mod()
+	ctrl := mod&(fyne.KeyModifierControl|fyne.KeyModifierSuper) != 0
+	switch {
+	case ctrl && mod&fyne.KeyModifierShift != 0 && key == fyne.KeyC:
+		v.copySelection()
+	case ctrl && mod&fyne.KeyModifierShift != 0 && key == fyne.KeyV:
+		v.paste()
+	case ctrl:
+		if b, ok := controlByte(key); ok {
+			v.input([]byte{b})
+		}
+	case mod&fyne.KeyModifierAlt != 0 && len(key) == 1:
+		// Alt 组合键以 ESC 开头
+		text := strings.ToLower(string(key))
+		if mod&fyne.KeyModifierShift != 0 {
+			text = string(key)
+		}
+		v.input([]byte("\x1b" + text))
+	}
+}
+
+// controlByte 返回 Ctrl 组合键对应的控制字符
+func controlByte(key fyne.KeyName) (byte, bool) {
+	if len(key) == 1 && key[0] >= 'A' && key[0] <= 'Z' {
+		return key[0] - 'A' + 1, true
+	}
+	switch key {
+	case fyne.KeySpace, "2", "@":
+		return 0x00, true
+	case fyne.KeyLeftBracket:
+		return 0x1b, true
+	case fyne.KeyBackslash:
+		return 0x1c, true
+	case fyne.KeyRightBracket:
+		return 0x1d, true
+	case "6":
+		return 0x1e, true
+	case fyne.KeyMinus, fyne.KeySlash:
+		return 0x1f, true
+	}
+	return 0, false
+}
+
+// Tapped 单击获得焦点并取消选择
+func (v *terminalView) Tapped(*fyne.PointEvent) {
+	v.window.Canvas().Focus(v)
+	v.clearSelection()
+}
+
+// TappedSecondary 右键菜单：复制、粘贴和清空回滚
+func (v *terminalView) TappedSecondary(event *fyne.PointEvent) {
+	copyItem := fyne.NewMenuItem("复制", func() {
+		v.copySelection()
+	})
+	copyItem.Disabled = v.selection() == ""
+	menu := fyne.NewMenu("",
+		copyItem,
+		fyne.NewMenuItem("粘贴", v.paste),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("清空回滚", v.clearScrollback),
+	)
+	widget.ShowPopUpMenuAtPosition(menu, v.window.Canvas(), event.AbsolutePosition)
+}
+
+// Dragged 拖动鼠标选择文本
+func (v *terminalView) Dragged(event *fyne.DragEvent) {
+	from := v.position(event.Position.Subtract(event.Dragged))
+	to := v.position(event.Position)
+	v.mu.Lock()
+	if !v.selecting {
+		v.selecting = true
+		v.hasSel = true
+		v.selFrom = from
+	}
+	v.selTo = to
+	v.mu.Unlock()
+	v.dirty.Store(true)
+}
+
+// DragEnd 结束选择
+func (v *terminalView) DragEnd() {
+	v.mu.Lock()
+	v.selecting = false
+	v.mu.Unlock()
+}
+
+// Scrolled 滚轮查看回滚内容
+func (v *terminalView) Scrolled(event *fyne.ScrollEvent) {
+	lines := int(event.Scrolled.DY / v.cellSize().Height)
+	if lines == 0 && event.Scrolled.DY != 0 {
+		lines = 1
+		if event.Scrolled.DY < 0 {
+			lines = -1
+		}
+	}
+	v.scroll(lines)
+}
@@ -0,0 +1,743 @@
+package gui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	terminalScrollback = 5000 // 最多保留的回滚行数
+	terminalTabWidth   = 8
+)
+
+// termColor 终端颜色：termDefault 为默认颜色，0-255 为调色板，termRGB 标记的为 24 位颜色
+type termColor int32
+
+const (
+	termDefault termColor = -1
+	termRGB     termColor = 1 << 24
+)
+
+// termAttr 字符属性
+type termAttr struct {
+	fg, bg    termColor
+	bold      bool
+	reverse   bool
+	underline bool
+}
+
+// defaultAttr 默认属性
+var defaultAttr = termAttr{fg: termDefault, bg: termDefault}
+
+// termCell 屏幕上的一个字符，宽字符之后的一格 r 为 0
+type termCell struct {
+	r    rune
+	attr termAttr
+}
+
+// termCursor 光标位置和当前属性
+type termCursor struct {
+	x, y     int
+	attr     termAttr
+	wrapNext bool // 已写到行尾，下一个字符换行
+}
+
+// 转义序列解析状态
+const (
+	stateGround = iota
+	stateEscape
+	stateCharset // ESC ( 等之后跳过一个字节
+	stateCSI
+	stateOSC
+	stateOSCEscape
+)
+
+// termScreen 终端屏幕：解析 shell 输出的 VT100/xterm 控制序列并维护屏幕内容和回滚缓冲
+type termScreen struct {
+	cols, rows int
+	lines      [][]termCell
+	scrollback [][]termCell
+	trimmed    int // 已从回滚缓冲开头丢弃的行数，用于换算选择区域的行号
+	cur        termCursor
+	saved      termCursor
+	top        int // 滚动区域，包含两端
+	bottom     int
+
+	alt      bool         // 正在使用备用屏幕
+	mainSave [][]termCell // 使用备用屏幕时保存的主屏幕
+	mainCur  termCursor
+
+	autowrap       bool
+	appCursor      bool // 光标键使用应用模式
+	bracketedPaste bool
+	cursorVisible  bool
+	title          string
+	responses      []byte // 需要回复给服务器的数据，如光标位置报告
+
+	state   int
+	seq     []byte // 正在解析的 CSI 参数或 OSC 内容
+	pending []byte // 不完整的 UTF-8 字节
+}
+
+// newTermScreen 创建 cols×rows 的屏幕
+func newTermScreen(cols, rows int) *termScreen {
+	s := &termScreen{}
+	s.reset(cols, rows)
+	return s
+}
+
+// reset 恢复初始状态并清空屏幕，保留回滚缓冲
+func (s *termScreen) reset(cols, rows int) {
+	s.cols, s.rows = cols, rows
+	s.lines = make([][]termCell, rows)
+	for i := range s.lines {
+		s.lines[i] = blankLine(cols, defaultAttr)
+	}
+	s.cur = termCursor{attr: defaultAttr}
+	s.saved = s.cur
+	s.top, s.bottom = 0, rows-1
+	s.alt, s.mainSave = false, nil
+	s.autowrap, s.appCursor, s.bracketedPaste, s.cursorVisible = true, false, false, true
+	s.state = stateGround
+}
+
+// blankLine 返回一行空白，背景色取自 attr
+func blankLine(cols int, attr termAttr) []termCell {
+	line := make([]termCell, cols)
+	blank := termCell{r: ' ', attr: termAttr{fg: termDefault, bg: attr.bg}}
+	for i := range line {
+		line[i] = blank
+	}
+	return line
+}
+
+// blank 返回当前背景色的空白字符
+func (s *termScreen) blank() termCell {
+	return termCell{r: ' ', attr: termAttr{fg: termDefault, bg: s.cur.attr.bg}}
+}
+
+// write 处理一段 shell 输出
+func (s *termScreen) write(data []byte) {
+	for _, b := range data {
+		switch s.state {
+		case stateGround:
+			s.ground(b)
+		case stateEscape:
+			s.escape(b)
+		case stateCharset:
+			s.state = stateGround
+		case stateCSI:
+			switch {
+			case b >= 0x40 && b <= 0x7e:
+				s.csi(b)
+				s.state = stateGround
+			case b == 0x1b:
+				s.state = stateEscape
+			case b < 0x20:
+				// CSI 中的控制字符照常执行
+				s.control(b)
+			default:
+				s.seq = append(s.seq, b)
+			}
+		case stateOSC:
+			switch b {
+			case 0x07:
+				s.osc()
+			case 0x1b:
+				s.state = stateOSCEscape
+			default:
+				s.seq = append(s.seq, b)
+			}
+		case stateOSCEscape:
+			// ESC \ 结束 OSC
+			s.osc()
+			if b != '\\' {
+				s.escape(b)
+			}
+		}
+	}
+}
+
+// ground 处理普通字符和控制字符
+func (s *termScreen) ground(b byte) {
+	if b < 0x80 {
+		if len(s.pending) > 0 {
+			// 不完整的多字节字符
+			s.pending = s.pending[:0]
+			s.put(utf8.RuneError)
+		}
+		if b < 0x20 || b == 0x7f {
+			s.control(b)
+		} else {
+			s.put(rune(b))
+		}
+		return
+	}
+	s.pending = append(s.pending, b)
+	if utf8.FullRune(s.pending) || len(s.pending) >= utf8.UTFMax {
+		r, _ := utf8.DecodeRune(s.pending)
+		s.pending = s.pending[:0]
+		s.put(r)
+	}
+}
+
+// control 执行 C0 控制字符
+func (s *termScreen) control(b byte) {
+	switch b {
+	case 0x08: // BS
+		if s.cur.x > 0 {
+			s.cur.x--
+		}
+		s.cur.wrapNext = false
+	case 0x09: // HT
+		x := (s.cur.x/terminalTabWidth + 1) * terminalTabWidth
+		s.cur.x = min(x, s.cols-1)
+		s.cur.wrapNext = false
+	case 0x0a, 0x0b, 0x0c: // LF VT FF
+		s.index()
+	case 0x0d: // CR
+		s.cur.x = 0
+		s.cur.wrapNext = false
+	case 0x1b:
+		s.state = stateEscape
+	}
+}
+
+// escape 处理 ESC 之后的字节
+func (s *termScreen) escape(b byte) {
+	s.state = stateGround
+	switch b {
+	case '[':
+		s.state, s.seq = stateCSI, s.seq[:0]
+	case ']':
+		s.state, s.seq = stateOSC, s.seq[:0]
+	case '(', ')', '*', '+', '#':
+		s.state = stateCharset
+	case '7':
+		s.saved = s.cur
+	case '8':
+		s.cur = s.saved
+		s.clampCursor()
+	case 'D':
+		s.index()
+	case 'E':
+		s.cur.x = 0
+		s.index()
+	case 'M':
+		s.reverseIndex()
+	case 'c':
+		s.reset(s.cols, s.rows)
+	}
+}
+
+// put 在光标处写入字符
+func (s *termScreen) put(r rune) {
+	if s.cur.wrapNext && s.autowrap {
+		s.cur.x = 0
+		s.index()
+	}
+	s.cur.wrapNext = false
+	width := runeWidth(r)
+	if width == 0 {
+		// 组合字符，不单独占格
+		return
+	}
+	if width == 2 && s.cur.x == s.cols-1 && s.cols > 1 {
+		// 行尾放不下宽字符
+		if !s.autowrap {
+			return
+		}
+		s.lines[s.cur.y][s.cur.x] = s.blank()
+		s.cur.x = 0
+		s.index()
+	}
+	line := s.lines[s.cur.y]
+	line[s.cur.x] = termCell{r: r, attr: s.cur.attr}
+	if width == 2 && s.cur.x+1 < s.cols {
+		line[s.cur.x+1] = termCell{attr: s.cur.attr}
+	}
+	if next := s.cur.x + width; next < s.cols {
+		s.cur.x = next
+	} else {
+		s.cur.x = s.cols - 1
+		s.cur.wrapNext = true
+	}
+}
+
+// runeWidth 返回字符占用的列数：组合字符为 0，东亚宽字符和表情符号为 2
+func runeWidth(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me) || r == 0x200b:
+		return 0
+	case r >= 0x1100 && r <= 0x115f,
+		r >= 0x2e80 && r <= 0xa4cf && r != 0x303f,
+		r >= 0xac00 && r <= 0xd7a3,
+		r >= 0xf900 && r <= 0xfaff,
+		r >= 0xfe30 && r <= 0xfe4f,
+		r >= 0xff00 && r <= 0xff60,
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f,
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd:
+		return 2
+	}
+	return 1
+}
+
+// index 光标下移一行，位于滚动区域底部时向上滚动
+func (s *termScreen) index() {
+	s.cur.wrapNext = false
+	if s.cur.y == s.bottom {
+		s.scrollUp(1)
+	} else if s.cur.y < s.rows-1 {
+		s.cur.y++
+	}
+}
+
+// reverseIndex 光标上移一行，位于滚动区域顶部时向下滚动
+func (s *termScreen) reverseIndex() {
+	s.cur.wrapNext = false
+	if s.cur.y == s.top {
+		s.scrollDown(1)
+	} else if s.cur.y > 0 {
+		s.cur.y--
+	}
+}
+
+// scrollUp 滚动区域向上滚动 n 行，主屏幕整屏滚动时移出的行进入回滚缓冲
+func (s *termScreen) scrollUp(n int) {
+	n = min(n, s.bottom-s.top+1)
+	if s.top == 0 && !s.alt {
+		for _, line := range s.lines[:n] {
+			s.scrollback = append(s.scrollback, line)
+		}
+		if over := len(s.scrollback) - terminalScrollback; over > 0 {
+			s.scrollback = append([][]termCell(nil), s.scrollback[over:]...)
+			s.trimmed += over
+		}
+	}
+	region := s.lines[s.top : s.bottom+1]
+	copy(region, region[n:])
+	for i := len(region) - n; i < len(region); i++ {
+		region[i] = blankLine(s.cols, s.cur.attr)
+	}
+}
+
+// scrollDown 滚动区域向下滚动 n 行
+func (s *termScreen) scrollDown(n int) {
+	n = min(n, s.bottom-s.top+1)
+	region := s.lines[s.top : s.bottom+1]
+	copy(region[n:], region)
+	for i := 0; i < n; i++ {
+		region[i] = blankLine(s.cols, s.cur.attr)
+	}
+}
+
+// clampCursor 将光标限制在屏幕内
+func (s *termScreen) clampCursor() {
+	s.cur.x = max(0, min(s.cur.x, s.cols-1))
+	s.cur.y = max(0, min(s.cur.y, s.rows-1))
+	s.cur.wrapNext = false
+}
+
+// csiParams 解析 CSI 参数，返回私有标记和数字参数
+func (s *termScreen) csiParams() (byte, []int) {
+	seq := string(s.seq)
+	var private byte
+	if seq != "" && strings.ContainsRune("<=>?", rune(seq[0])) {
+		private, seq = seq[0], seq[1:]
+	}
+	// 去掉中间字节
+	seq = strings.TrimRight(seq, " !\"#$%&'()*+,-./")
+	var params []int
+	if seq != "" {
+		// 保留空参数，如 "CSI ;5H" 的行号使用默认值
+		for _, field := range strings.Split(strings.ReplaceAll(seq, ":", ";"), ";") {
+			n, _ := strconv.Atoi(field)
+			params = append(params, n)
+		}
+	}
+	return private, params
+}
+
+// csiParam 返回第 i 个参数，缺省或为 0 时返回 def
+func csiParam(params []int, i, def int) int {
+	if i < len(params) && params[i] != 0 {
+		return params[i]
+	}
+	return def
+}
+
+// csi 执行 CSI 控制序列
+func (s *termScreen) csi(final byte) {
+	private, params := s.csiParams()
+	n := csiParam(params, 0, 1)
+	switch final {
+	case 'A':
+		s.cur.y = max(s.cur.y-n, 0)
+		s.cur.wrapNext = false
+	case 'B', 'e':
+		s.cur.y = min(s.cur.y+n, s.rows-1)
+		s.cur.wrapNext = false
+	case 'C', 'a':
+		s.cur.x = min(s.cur.x+n, s.cols-1)
+		s.cur.wrapNext = false
+	case 'D':
+		s.cur.x = max(s.cur.x-n, 0)
+		s.cur.wrapNext = false
+	case 'E':
+		s.cur.x, s.cur.y = 0, min(s.cur.y+n, s.rows-1)
+		s.cur.wrapNext = false
+	case 'F':
+		s.cur.x, s.cur.y = 0, max(s.cur.y-n, 0)
+		s.cur.wrapNext = false
+	case 'G', '`':
+		s.cur.x = n - 1
+		s.clampCursor()
+	case 'd':
+		s.cur.y = n - 1
+		s.clampCursor()
+	case 'H', 'f':
+		s.cur.y, s.cur.x = csiParam(params, 0, 1)-1, csiParam(params, 1, 1)-1
+		s.clampCursor()
+	case 'J':
+		s.eraseDisplay(csiParam(params, 0, 0))
+	case 'K':
+		s.eraseLine(csiParam(params, 0, 0))
+	case '@':
+		line := s.lines[s.cur.y]
+		n = min(n, s.cols-s.cur.x)
+		copy(line[s.cur.x+n:], line[s.cur.x:])
+		s.fill(s.cur.y, s.cur.x, s.cur.x+n)
+	case 'P':
+		line := s.lines[s.cur.y]
+		n = min(n, s.cols-s.cur.x)
+		copy(line[s.cur.x:], line[s.cur.x+n:])
+		s.fill(s.cur.y, s.cols-n, s.cols)
+	case 'X':
+		s.fill(s.cur.y, s.cur.x, min(s.cur.x+n, s.cols))
+	case 'L', 'M':
+		if s.cur.y < s.top || s.cur.y > s.bottom {
+			return
+		}
+		top := s.top
+		s.top = s.cur.y
+		if final == 'L' {
+			s.scrollDown(n)
+		} else {
+			// 删除的行不进入回滚缓冲
+			alt := s.alt
+			s.alt = true
+			s.scrollUp(n)
+			s.alt = alt
+		}
+		s.top = top
+		s.cur.x = 0
+	case 'S':
+		s.scrollUp(n)
+	case 'T':
+		if private == 0 {
+			s.scrollDown(n)
+		}
+	case 'm':
+		if private == 0 {
+			s.sgr(params)
+		}
+	case 'r':
+		if private == 0 {
+			top, bottom := csiParam(params, 0, 1)-1, csiParam(params, 1, s.rows)-1
+			if top < bottom && bottom < s.rows {
+				s.top, s.bottom = top, bottom
+				s.cur.x, s.cur.y, s.cur.wrapNext = 0, 0, false
+			}
+		}
+	case 's':
+		if private == 0 {
+			s.saved = s.cur
+		}
+	case 'u':
+		if private == 0 {
+			s.cur = s.saved
+			s.clampCursor()
+		}
+	case 'h', 'l':
+		if private == '?' {
+			for _, mode := range params {
+				s.setMode(mode, final == 'h')
+			}
+		}
+	case 'n':
+		switch csiParam(params, 0, 0) {
+		case 5:
+			s.responses = append(s.responses, "\x1b[0n"...)
+		case 6:
+			s.responses = append(s.responses, fmt.Sprintf("\x1b[%d;%dR", s.cur.y+1, s.cur.x+1)...)
+		}
+	case 'c':
+		switch private {
+		case 0:
+			s.responses = append(s.responses, "\x1b[?1;2c"...)
+		case '>':
+			s.responses = append(s.responses, "\x1b[>0;0;0c"...)
+		}
+	}
+}
+
+// setMode 设置 DEC 私有模式
+func (s *termScreen) setMode(mode int, on bool) {
+	switch mode {
+	case 1:
+		s.appCursor = on
+	case 7:
+		s.autowrap = on
+	case 25:
+		s.cursorVisible = on
+	case 47, 1047, 1049:
+		if on == s.alt {
+			return
+		}
+		if on {
+			if mode == 1049 {
+				s.saved = s.cur
+			}
+			s.mainSave, s.mainCur = s.lines, s.cur
+			s.lines = make([][]termCell, s.rows)
+			for i := range s.lines {
+				s.lines[i] = blankLine(s.cols, defaultAttr)
+			}
+		} else {
+			s.lines = s.mainSave
+			s.mainSave = nil
+			if mode == 1049 {
+				s.cur = s.saved
+			} else {
+				s.cur = s.mainCur
+			}
+			s.clampCursor()
+		}
+		s.alt = on
+	case 2004:
+		s.bracketedPaste = on
+	}
+}
+
+// sgr 设置字符属性
+func (s *termScreen) sgr(params []int) {
+	if len(params) == 0 {
+		params = []int{0}
+	}
+	attr := &s.cur.attr
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		switch {
+		case p == 0:
+			*attr = defaultAttr
+		case p == 1:
+			attr.bold = true
+		case p == 4:
+			attr.underline = true
+		case p == 7:
+			attr.reverse = true
+		case p == 22:
+			attr.bold = false
+		case p == 24:
+			attr.underline = false
+		case p == 27:
+			attr.reverse = false
+		case p >= 30 && p <= 37:
+			attr.fg = termColor(p - 30)
+		case p == 39:
+			attr.fg = termDefault
+		case p >= 40 && p <= 47:
+			attr.bg = termColor(p - 40)
+		case p == 49:
+			attr.bg = termDefault
+		case p >= 90 && p <= 97:
+			attr.fg = termColor(p - 90 + 8)
+		case p >= 100 && p <= 107:
+			attr.bg = termColor(p - 100 + 8)
+		case p == 38 || p == 48:
+			// 38;5;n 调色板颜色，38;2;r;g;b 24 位颜色
+			var c termColor
+			switch {
+			case i+2 < len(params) && params[i+1] == 5:
+				c = termColor(params[i+2] & 0xff)
+				i += 2
+			case i+4 < len(params) && params[i+1] == 2:
+				c = termRGB | termColor((params[i+2]&0xff)<<16|(params[i+3]&0xff)<<8|params[i+4]&0xff)
+				i += 4
+			default:
+				return
+			}
+			if p == 38 {
+				attr.fg = c
+			} else {
+				attr.bg = c
+			}
+		}
+	}
+}
+
+// osc 处理 OSC 序列，只支持设置标题
+func (s *termScreen) osc() {
+	s.state = stateGround
+	code, text, ok := strings.Cut(string(s.seq), ";")
+	if ok && (code == "0" || code == "2") {
+		s.title = text
+	}
+}
+
+// fill 将第 y 行 [from, to) 列清为空白
+func (s *termScreen) fill(y, from, to int) {
+	blank := s.blank()
+	line := s.lines[y]
+	for x := from; x < to; x++ {
+		line[x] = blank
+	}
+}
+
+// eraseDisplay 擦除屏幕：0 光标到结尾，1 开头到光标，2 整屏，3 整屏和回滚缓冲
+func (s *termScreen) eraseDisplay(mode int) {
+	switch mode {
+	case 0:
+		s.fill(s.cur.y, s.cur.x, s.cols)
+		for y := s.cur.y + 1; y < s.rows; y++ {
+			s.fill(y, 0, s.cols)
+		}
+	case 1:
+		for y := 0; y < s.cur.y; y++ {
+			s.fill(y, 0, s.cols)
+		}
+		s.fill(s.cur.y, 0, s.cur.x+1)
+	case 2, 3:
+		for y := 0; y < s.rows; y++ {
+			s.fill(y, 0, s.cols)
+		}
+		if mode == 3 {
+			s.clearScrollback()
+		}
+	}
+}
+
+// eraseLine 擦除当前行：0 光标到行尾，1 行首到光标，2 整行
+func (s *termScreen) eraseLine(mode int) {
+	switch mode {
+	case 0:
+		s.fill(s.cur.y, s.cur.x, s.cols)
+	case 1:
+		s.fill(s.cur.y, 0, s.cur.x+1)
+	case 2:
+		s.fill(s.cur.y, 0, s.cols)
+	}
+}
+
+// clearScrollback 清空回滚缓冲
+func (s *termScreen) clearScrollback() {
+	s.trimmed += len(s.scrollback)
+	s.scrollback = nil
+}
+
+// resize 调整屏幕大小：行数减少时将光标以上多出的行移入回滚缓冲
+func (s *termScreen) resize(cols, rows int) {
+	if cols == s.cols && rows == s.rows {
+		return
+	}
+	resizeLines := func(lines [][]termCell) [][]termCell {
+		for i, line := range lines {
+			if len(line) > cols {
+				lines[i] = line[:cols]
+			} else if len(line) < cols {
+				lines[i] = append(line, blankLine(cols-len(line), defaultAttr)...)
+			}
+		}
+		for len(lines) < rows {
+			lines = append(lines, blankLine(cols, defaultAttr))
+		}
+		return lines
+	}
+
+	if drop := s.cur.y - (rows - 1); drop > 0 {
+		if !s.alt {
+			s.scrollback = append(s.scrollback, s.lines[:drop]...)
+		}
+		s.lines = s.lines[drop:]
+		s.cur.y -= drop
+	}
+	if len(s.lines) > rows {
+		s.lines = s.lines[:rows]
+	}
+	s.cols, s.rows = cols, rows
+	s.lines = resizeLines(s.lines)
+	if s.mainSave != nil {
+		if len(s.mainSave) > rows {
+			s.mainSave = s.mainSave[len(s.mainSave)-rows:]
+		}
+		s.mainSave = resizeLines(s.mainSave)
+	}
+	s.top, s.bottom = 0, rows-1
+	s.clampCursor()
+	s.saved.x, s.saved.y = min(s.saved.x, cols-1), min(s.saved.y, rows-1)
+}
+
+// lineCount 回滚缓冲和屏幕的总行数
+func (s *termScreen) lineCount() int {
+	return len(s.scrollback) + s.rows
+}
+
+// line 返回第 i 行，0 为回滚缓冲的第一行
+func (s *termScreen) line(i int) []termCell {
+	if i < len(s.scrollback) {
+		return s.scrollback[i]
+	}
+	return s.lines[i-len(s.scrollback)]
+}
+
+// takeResponses 取出需要回复给服务器的数据
+func (s *termScreen) takeResponses() []byte {
+	responses := s.responses
+	s.responses = nil
+	return responses
+}
+
+// termPos 选择区域的端点，line 为加上已丢弃行数后的行号，不随回滚缓冲的裁剪而变化
+type termPos struct {
+	line, col int
+}
+
+// before 是否在 other 之前
+func (p termPos) before(other termPos) bool {
+	return p.line < other.line || (p.line == other.line && p.col < other.col)
+}
+
+// text 返回 [from, to] 之间的文本，去掉行尾空白
+func (s *termScreen) text(from, to termPos) string {
+	if to.before(from) {
+		from, to = to, from
+	}
+	var lines []string
+	for abs := from.line; abs <= to.line; abs++ {
+		i := abs - s.trimmed
+		if i < 0 || i >= s.lineCount() {
+			continue
+		}
+		line := s.line(i)
+		start, end := 0, len(line)
+		if abs == from.line {
+			start = min(from.col, len(line))
+		}
+		if abs == to.line {
+			end = min(to.col+1, len(line))
+		}
+		var b strings.Builder
+		for _, cell := range line[start:max(start, end)] {
+			if cell.r != 0 {
+				b.WriteRune(cell.r)
+			}
+		}
+		lines = append(lines, strings.TrimRight(b.String(), " "))
+	}
+	return strings.Join(lines, "\n")
+}
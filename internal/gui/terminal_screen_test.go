@@ -0,0 +1,219 @@
+package gui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// screenRows 返回屏幕每一行的文本，去掉行尾空白
+func screenRows(s *termScreen) []string {
+	rows := make([]string, s.rows)
+	for y, line := range s.lines {
+		var b strings.Builder
+		for _, cell := range line {
+			if cell.r != 0 {
+				b.WriteRune(cell.r)
+			}
+		}
+		rows[y] = strings.TrimRight(b.String(), " ")
+	}
+	return rows
+}
+
+func TestTermScreenWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  []string // 分多次写入
+		rows   []string
+		x, y   int
+		scroll []string // 回滚缓冲的内容
+	}{
+		{name: "普通文本", input: []string{"hello"}, rows: []string{"hello", "", ""}, x: 5},
+		{name: "回车换行", input: []string{"ab\r\ncd"}, rows: []string{"ab", "cd", ""}, x: 2, y: 1},
+		{name: "只换行不回车", input: []string{"ab\ncd"}, rows: []string{"ab", "  cd", ""}, x: 4, y: 1},
+		{name: "行尾自动换行", input: []string{"0123456789ab"}, rows: []string{"0123456789", "ab", ""}, x: 2, y: 1},
+		{name: "写满一行后光标停在行尾", input: []string{"0123456789"}, rows: []string{"0123456789", "", ""}, x: 9},
+		{name: "关闭自动换行", input: []string{"\x1b[?7l0123456789ab"}, rows: []string{"012345678b", "", ""}, x: 9},
+		{name: "退格和制表符", input: []string{"abc\bX\tY"}, rows: []string{"abX     Y", "", ""}, x: 9},
+		{name: "滚动进入回滚缓冲", input: []string{"1\r\n2\r\n3\r\n4"}, rows: []string{"2", "3", "4"}, x: 1, y: 2, scroll: []string{"1"}},
+		{name: "光标定位", input: []string{"\x1b[2;3Hx\x1b[;5Hy"}, rows: []string{"    y", "  x", ""}, x: 5},
+		{name: "光标移动限制在屏幕内", input: []string{"\x1b[99;99Hx\x1b[99Ay"}, rows: []string{"         y", "", "         x"}, x: 9},
+		{name: "光标相对移动", input: []string{"\x1b[2B\x1b[3Cx\x1b[A\x1b[2Dy"}, rows: []string{"", "  y", "   x"}, x: 3, y: 1},
+		{name: "擦除到行尾", input: []string{"abcdef\x1b[3D\x1b[K"}, rows: []string{"abc", "", ""}, x: 3},
+		{name: "擦除到行首", input: []string{"abcdef\x1b[3D\x1b[1K"}, rows: []string{"    ef", "", ""}, x: 3},
+		{name: "擦除整屏", input: []string{"ab\r\ncd\x1b[2J"}, rows: []string{"", "", ""}, x: 2, y: 1},
+		{name: "擦除到屏幕末尾", input: []string{"ab\r\ncd\r\nef\x1b[2;2H\x1b[J"}, rows: []string{"ab", "c", ""}, x: 1, y: 1},
+		{name: "插入字符", input: []string{"abcd\x1b[1;2H\x1b[2@"}, rows: []string{"a  bcd", "", ""}, x: 1},
+		{name: "删除字符", input: []string{"abcdef\x1b[1;2H\x1b[2P"}, rows: []string{"adef", "", ""}, x: 1},
+		{name: "擦除字符", input: []string{"abcdef\x1b[1;2H\x1b[2X"}, rows: []string{"a  def", "", ""}, x: 1},
+		{name: "插入行", input: []string{"1\r\n2\r\n3\x1b[2;1H\x1b[L"}, rows: []string{"1", "", "2"}, y: 1},
+		{name: "删除行不进入回滚缓冲", input: []string{"1\r\n2\r\n3\x1b[1;1H\x1b[M"}, rows: []string{"2", "3", ""}},
+		{
+			name:  "滚动区域",
+			input: []string{"top\x1b[2;3r\x1b[3;1Ha\r\nb\r\nc"},
+			rows:  []string{"top", "b", "c"}, x: 1, y: 2,
+		},
+		{name: "反向换行", input: []string{"a\x1bM"}, rows: []string{"", "a", ""}, x: 1},
+		{name: "保存和恢复光标", input: []string{"ab\x1b7\r\ncd\x1b8X"}, rows: []string{"abX", "cd", ""}, x: 3},
+		{name: "宽字符占两格", input: []string{"中文a"}, rows: []string{"中文a", "", ""}, x: 5},
+		{name: "行尾放不下宽字符时换行", input: []string{"012345678中"}, rows: []string{"012345678", "中", ""}, x: 2, y: 1},
+		{name: "多字节字符分多次写入", input: []string{"\xe4\xb8", "\xad"}, rows: []string{"中", "", ""}, x: 2},
+		{name: "不完整的多字节字符", input: []string{"\xe4\xb8a"}, rows: []string{"�a", "", ""}, x: 2},
+		{name: "组合字符不占格", input: []string{"e\u0301x"}, rows: []string{"ex", "", ""}, x: 2},
+		{name: "转义序列分多次写入", input: []string{"ab\x1b", "[1", ";1Hx"}, rows: []string{"xb", "", ""}, x: 1},
+		{name: "CSI 中的控制字符", input: []string{"abcd\x1b[\r2Cx"}, rows: []string{"abxd", "", ""}, x: 3},
+		{name: "忽略字符集选择", input: []string{"\x1b(Bab"}, rows: []string{"ab", "", ""}, x: 2},
+		{name: "重置", input: []string{"ab\x1bc"}, rows: []string{"", "", ""}},
+		{
+			name:  "备用屏幕",
+			input: []string{"main\x1b[?1049h\x1b[Halt\x1b[?1049l"},
+			rows:  []string{"main", "", ""}, x: 4,
+		},
+		{
+			name:  "备用屏幕滚动不进入回滚缓冲",
+			input: []string{"\x1b[?1049h1\r\n2\r\n3\r\n4"},
+			rows:  []string{"2", "3", "4"}, x: 1, y: 2,
+		},
+		{name: "清除回滚缓冲", input: []string{"1\r\n2\r\n3\r\n4\x1b[3J"}, rows: []string{"", "", ""}, x: 1, y: 2},
+	}
+	for _, tt := range tests {
+		s := newTermScreen(10, 3)
+		for _, data := range tt.input {
+			s.write([]byte(data))
+		}
+		if got := screenRows(s); !reflect.DeepEqual(got, tt.rows) {
+			t.Errorf("%s: 屏幕为 %q，期望 %q", tt.name, got, tt.rows)
+		}
+		if s.cur.x != tt.x || s.cur.y != tt.y {
+			t.Errorf("%s: 光标在 (%d, %d)，期望 (%d, %d)", tt.name, s.cur.x, s.cur.y, tt.x, tt.y)
+		}
+		var scroll []string
+		for i := 0; i < len(s.scrollback); i++ {
+			scroll = append(scroll, strings.TrimRight(s.text(termPos{line: s.trimmed + i}, termPos{line: s.trimmed + i, col: s.cols}), " "))
+		}
+		if !reflect.DeepEqual(scroll, tt.scroll) {
+			t.Errorf("%s: 回滚缓冲为 %q，期望 %q", tt.name, scroll, tt.scroll)
+		}
+	}
+}
+
+func TestTermScreenSGR(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  termAttr
+	}{
+		{name: "默认", input: "", want: defaultAttr},
+		{name: "粗体下划线反显", input: "\x1b[1;4;7m", want: termAttr{fg: termDefault, bg: termDefault, bold: true, underline: true, reverse: true}},
+		{name: "关闭属性", input: "\x1b[1;4;7m\x1b[22;24;27m", want: defaultAttr},
+		{name: "基本颜色", input: "\x1b[31;42m", want: termAttr{fg: 1, bg: 2}},
+		{name: "高亮颜色", input: "\x1b[91;102m", want: termAttr{fg: 9, bg: 10}},
+		{name: "调色板颜色", input: "\x1b[38;5;200;48;5;17m", want: termAttr{fg: 200, bg: 17}},
+		{name: "24 位颜色", input: "\x1b[38;2;1;2;3m", want: termAttr{fg: termRGB | 0x010203, bg: termDefault}},
+		{name: "冒号分隔的颜色", input: "\x1b[38:5:100m", want: termAttr{fg: 100, bg: termDefault}},
+		{name: "不完整的扩展颜色", input: "\x1b[31m\x1b[38;5m", want: termAttr{fg: 1, bg: termDefault}},
+		{name: "恢复默认颜色", input: "\x1b[31;42m\x1b[39;49m", want: defaultAttr},
+		{name: "无参数重置", input: "\x1b[1;31m\x1b[m", want: defaultAttr},
+		{name: "私有参数不是 SGR", input: "\x1b[>4;1m", want: defaultAttr},
+	}
+	for _, tt := range tests {
+		s := newTermScreen(10, 3)
+		s.write([]byte(tt.input))
+		if s.cur.attr != tt.want {
+			t.Errorf("%s: 属性为 %+v，期望 %+v", tt.name, s.cur.attr, tt.want)
+		}
+	}
+}
+
+func TestTermScreenModesAndResponses(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		title     string
+		responses string
+		appCursor bool
+		paste     bool
+		hidden    bool
+	}{
+		{name: "以 BEL 结束的标题", input: "\x1b]0;user@host\x07", title: "user@host"},
+		{name: "以 ST 结束的标题", input: "\x1b]2;vim\x1b\\", title: "vim"},
+		{name: "忽略其他 OSC", input: "\x1b]7;file:///tmp\x07"},
+		{name: "状态报告", input: "\x1b[5n", responses: "\x1b[0n"},
+		{name: "光标位置报告", input: "\x1b[2;4H\x1b[6n", responses: "\x1b[2;4R"},
+		{name: "设备属性", input: "\x1b[c\x1b[>c", responses: "\x1b[?1;2c\x1b[>0;0;0c"},
+		{name: "光标键应用模式", input: "\x1b[?1h", appCursor: true},
+		{name: "括号粘贴模式", input: "\x1b[?2004h", paste: true},
+		{name: "隐藏光标", input: "\x1b[?25l", hidden: true},
+		{name: "多个模式", input: "\x1b[?1;2004h\x1b[?1l", paste: true},
+	}
+	for _, tt := range tests {
+		s := newTermScreen(10, 3)
+		s.write([]byte(tt.input))
+		if s.title != tt.title {
+			t.Errorf("%s: 标题为 %q，期望 %q", tt.name, s.title, tt.title)
+		}
+		if got := string(s.takeResponses()); got != tt.responses {
+			t.Errorf("%s: 回复为 %q，期望 %q", tt.name, got, tt.responses)
+		}
+		if s.appCursor != tt.appCursor || s.bracketedPaste != tt.paste || s.cursorVisible == tt.hidden {
+			t.Errorf("%s: 模式为 appCursor=%v paste=%v visible=%v", tt.name, s.appCursor, s.bracketedPaste, s.cursorVisible)
+		}
+	}
+}
+
+func TestTermScreenResize(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		cols, rows int
+		want       []string
+		scroll     int
+		x, y       int
+	}{
+		{name: "变宽", input: "abc", cols: 12, rows: 3, want: []string{"abc", "", ""}, x: 3},
+		{name: "变窄时截断", input: "0123456789", cols: 4, rows: 3, want: []string{"0123", "", ""}, x: 3},
+		{name: "变高", input: "a\r\nb", cols: 10, rows: 5, want: []string{"a", "b", "", "", ""}, x: 1, y: 1},
+		{name: "变矮时光标以上的行进入回滚缓冲", input: "a\r\nb\r\nc", cols: 10, rows: 2, want: []string{"b", "c"}, scroll: 1, x: 1, y: 1},
+		{name: "变矮时丢弃光标以下的空行", input: "a", cols: 10, rows: 1, want: []string{"a"}, x: 1},
+	}
+	for _, tt := range tests {
+		s := newTermScreen(10, 3)
+		s.write([]byte(tt.input))
+		s.resize(tt.cols, tt.rows)
+		if got := screenRows(s); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: 屏幕为 %q，期望 %q", tt.name, got, tt.want)
+		}
+		if len(s.scrollback) != tt.scroll || s.cur.x != tt.x || s.cur.y != tt.y {
+			t.Errorf("%s: 回滚 %d 行，光标 (%d, %d)，期望回滚 %d 行，光标 (%d, %d)",
+				tt.name, len(s.scrollback), s.cur.x, s.cur.y, tt.scroll, tt.x, tt.y)
+		}
+		for i, line := range s.lines {
+			if len(line) != tt.cols {
+				t.Errorf("%s: 第 %d 行宽度为 %d", tt.name, i, len(line))
+			}
+		}
+	}
+}
+
+func TestTermScreenText(t *testing.T) {
+	s := newTermScreen(10, 3)
+	s.write([]byte("1\r\n2\r\nhello 中文\r\nlast"))
+	tests := []struct {
+		name     string
+		from, to termPos
+		want     string
+	}{
+		{name: "单行", from: termPos{line: 2, col: 0}, to: termPos{line: 2, col: 4}, want: "hello"},
+		{name: "跨行", from: termPos{line: 1, col: 0}, to: termPos{line: 2, col: 2}, want: "2\nhel"},
+		{name: "反向选择", from: termPos{line: 2, col: 2}, to: termPos{line: 1, col: 0}, want: "2\nhel"},
+		{name: "包含回滚缓冲", from: termPos{line: 0, col: 0}, to: termPos{line: 0, col: 9}, want: "1"},
+		{name: "宽字符", from: termPos{line: 2, col: 6}, to: termPos{line: 2, col: 9}, want: "中文"},
+		{name: "超出范围的行", from: termPos{line: 3, col: 0}, to: termPos{line: 9, col: 9}, want: "last"},
+	}
+	for _, tt := range tests {
+		if got := s.text(tt.from, tt.to); got != tt.want {
+			t.Errorf("%s: 得到 %q，期望 %q", tt.name, got, tt.want)
+		}
+	}
+}
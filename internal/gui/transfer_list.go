@@ -0,0 +1,289 @@
+package gui
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// transferListRefresh 传输列表刷新间隔，避免每次进度回调都重绘
+const transferListRefresh = 300 * time.Millisecond
+
+// TransferList 显示传输队列中所有任务的列表，可停靠在主窗口底部或弹出为独立窗口
+type TransferList struct {
+	queue    *transfer.TransferQueue
+	list     *widget.List
+	summary  *widget.Label
+	content  fyne.CanvasObject // 列表及其工具栏
+	dock     *fyne.Container   // 主窗口中的停靠位置
+	floating fyne.Window       // 弹出的独立窗口，停靠时为nil
+
+	mu       sync.Mutex
+	jobs     []transfer.Job
+	targets  map[int]*FilePanel // 任务ID -> 目标面板
+	selected int                // 选中的任务ID，0表示未选中
+	selIndex int                // 选中任务在列表中的位置
+	dirty    atomic.Bool
+}
+
+// NewTransferList 创建传输列表和传输队列
+func NewTransferList() *TransferList {
+	t := &TransferList{
+		summary: widget.NewLabel(""),
+		targets: make(map[int]*FilePanel),
+	}
+	t.queue = transfer.NewTransferQueue(func() {
+		t.dirty.Store(true)
+	})
+
+	t.list = widget.NewList(
+		func() int {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			return len(t.jobs)
+		},
+		func() fyne.CanvasObject {
+			labels := make([]fyne.CanvasObject, 7)
+			for i := range labels {
+				label := widget.NewLabel("")
+				label.Truncation = fyne.TextTruncateEllipsis
+				labels[i] = label
+			}
+			return container.NewGridWithColumns(len(labels), labels...)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			t.mu.Lock()
+			if id >= len(t.jobs) {
+				t.mu.Unlock()
+				return
+			}
+			job := t.jobs[id]
+			t.mu.Unlock()
+
+			status := job.Status.String()
+			if job.Err != nil && job.Status == transfer.JobFailed {
+				status += ": " + job.Err.Error()
+			}
+			texts := []string{
+				job.Source,
+				job.Target,
+				formatJobSize(job),
+				formatSpeed(job.Speed),
+				formatETA(job.ETA()),
+				status,
+				fmt.Sprintf("#%d", job.ID),
+			}
+			for i, object := range item.(*fyne.Container).Objects {
+				object.(*widget.Label).SetText(texts[i])
+			}
+		},
+	)
+	t.list.OnSelected = func(id widget.ListItemID) {
+		t.mu.Lock()
+		if id < len(t.jobs) {
+			t.selected = t.jobs[id].ID
+			t.selIndex = id
+		}
+		t.mu.Unlock()
+	}
+	t.list.OnUnselected = func(widget.ListItemID) {
+		t.mu.Lock()
+		t.selected = 0
+		t.mu.Unlock()
+	}
+
+	header := container.NewGridWithColumns(7,
+		widget.NewLabel("源"), widget.NewLabel("目标"), widget.NewLabel("大小"),
+		widget.NewLabel("速度"), widget.NewLabel("剩余时间"), widget.NewLabel("状态"), widget.NewLabel("编号"))
+	for _, object := range header.Objects {
+		object.(*widget.Label).TextStyle = fyne.TextStyle{Bold: true}
+	}
+
+	toolbar := widget.NewToolbar(
+		widget.NewToolbarAction(theme.MoveUpIcon(), func() { t.withSelected(func(id int) { t.queue.Move(id, -1) }) }),
+		widget.NewToolbarAction(theme.MoveDownIcon(), func() { t.withSelected(func(id int) { t.queue.Move(id, 1) }) }),
+		widget.NewToolbarSeparator(),
+		widget.NewToolbarAction(theme.MediaPauseIcon(), func() { t.withSelected(t.queue.Pause) }),
+		widget.NewToolbarAction(theme.MediaPlayIcon(), func() { t.withSelected(t.queue.Resume) }),
+		widget.NewToolbarAction(theme.CancelIcon(), func() { t.withSelected(t.queue.Cancel) }),
+		widget.NewToolbarAction(theme.MediaReplayIcon(), func() { t.withSelected(t.queue.Retry) }),
+		widget.NewToolbarAction(theme.FolderOpenIcon(), func() { t.withSelected(t.openTarget) }),
+		widget.NewToolbarSeparator(),
+		widget.NewToolbarAction(theme.ContentClearIcon(), t.queue.ClearFinished),
+		widget.NewToolbarSpacer(),
+		widget.NewToolbarAction(theme.ViewFullScreenIcon(), t.toggleFloating),
+	)
+
+	t.content = container.NewBorder(
+		container.NewVBox(container.NewBorder(nil, nil, nil, t.summary, toolbar), header),
+		nil, nil, nil,
+		t.list,
+	)
+	t.dock = container.NewStack(t.content)
+
+	go t.refreshLoop()
+	return t
+}
+
+// GetContainer 返回停靠在主窗口中的容器
+func (t *TransferList) GetContainer() fyne.CanvasObject {
+	return t.dock
+}
+
+// Add 添加传输任务并返回任务ID，targetPanel 为目标所在面板，用于“打开目标目录”
+func (t *TransferList) Add(source, target string, size int64, targetPanel *FilePanel,
+	run func(stop <-chan struct{}, progress func(current, total int64)) error, done func(error)) int {
+	id := t.queue.Add(source, target, size, run, done)
+	t.mu.Lock()
+	t.targets[id] = targetPanel
+	t.mu.Unlock()
+	return id
+}
+
+// SetSize 设置任务的总大小，目录任务在后台统计完成后调用
+func (t *TransferList) SetSize(id int, size int64) {
+	t.queue.SetSize(id, size)
+}
+
+// refreshLoop 定期将队列的变化刷新到列表
+func (t *TransferList) refreshLoop() {
+	ticker := time.NewTicker(transferListRefresh)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !t.dirty.Swap(false) {
+			continue
+		}
+		jobs := t.queue.Jobs()
+		active, finished := 0, 0
+		for _, job := range jobs {
+			if job.Status.Finished() {
+				finished++
+			} else {
+				active++
+			}
+		}
+		t.mu.Lock()
+		t.jobs = jobs
+		// 调整顺序或清除后让选中项跟随任务
+		index := -1
+		for i, job := range jobs {
+			if job.ID == t.selected {
+				index = i
+			}
+		}
+		moved := t.selected != 0 && index != t.selIndex
+		t.mu.Unlock()
+
+		t.summary.SetText(fmt.Sprintf("进行中 %d，已结束 %d", active, finished))
+		t.list.Refresh()
+		if moved {
+			if index >= 0 {
+				t.list.Select(index)
+			} else {
+				t.list.UnselectAll()
+			}
+		}
+	}
+}
+
+// withSelected 对选中的任务执行操作
+func (t *TransferList) withSelected(action func(id int)) {
+	t.mu.Lock()
+	id := t.selected
+	t.mu.Unlock()
+	if id != 0 {
+		action(id)
+	}
+}
+
+// openTarget 在目标面板中打开任务的目标目录
+func (t *TransferList) openTarget(id int) {
+	t.mu.Lock()
+	panel := t.targets[id]
+	var target string
+	for _, job := range t.jobs {
+		if job.ID == id {
+			target = job.Target
+		}
+	}
+	t.mu.Unlock()
+	if panel != nil && target != "" {
+		if panel.tabs != nil {
+			panel.tabs.selectPanel(panel)
+		}
+		panel.SetPath(panel.paths().Dir(target))
+	}
+}
+
+// activeFor 返回以面板为目标且尚未结束的任务数量
+func (t *TransferList) activeFor(panel *FilePanel) int {
+	if t == nil {
+		return 0
+	}
+	count := 0
+	for _, job := range t.queue.Jobs() {
+		t.mu.Lock()
+		target := t.targets[job.ID]
+		t.mu.Unlock()
+		if target == panel && !job.Status.Finished() {
+			count++
+		}
+	}
+	return count
+}
+
+// toggleFloating 在主窗口停靠和独立窗口之间切换
+func (t *TransferList) toggleFloating() {
+	if t.floating != nil {
+		t.floating.Close()
+		return
+	}
+
+	t.floating = fyne.CurrentApp().NewWindow("传输列表")
+	t.floating.SetOnClosed(func() {
+		t.floating = nil
+		t.dock.Objects = []fyne.CanvasObject{t.content}
+		t.dock.Refresh()
+	})
+	t.dock.Objects = []fyne.CanvasObject{container.NewBorder(nil, nil, nil,
+		widget.NewButtonWithIcon("停靠", theme.ViewRestoreIcon(), t.toggleFloating),
+		widget.NewLabel("传输列表已在独立窗口中打开"))}
+	t.dock.Refresh()
+	t.floating.SetContent(t.content)
+	t.floating.Resize(fyne.NewSize(900, 400))
+	t.floating.Show()
+}
+
+// formatJobSize 格式化任务的已传输大小和总大小
+func formatJobSize(job transfer.Job) string {
+	if job.Size < 0 {
+		return transfer.FormatSize(job.Transferred)
+	}
+	if job.Status == transfer.JobQueued || job.Status == transfer.JobDone {
+		return transfer.FormatSize(job.Size)
+	}
+	return fmt.Sprintf("%s / %s", transfer.FormatSize(job.Transferred), transfer.FormatSize(job.Size))
+}
+
+// formatSpeed 格式化传输速度
+func formatSpeed(speed float64) string {
+	if speed <= 0 {
+		return "-"
+	}
+	return transfer.FormatSize(int64(speed)) + "/s"
+}
+
+// formatETA 格式化剩余时间
+func formatETA(eta time.Duration) string {
+	if eta < 0 {
+		return "-"
+	}
+	return eta.Round(time.Second).String()
+}
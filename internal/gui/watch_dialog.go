@@ -0,0 +1,166 @@
+package gui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"xftp798/internal/transfer"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// watchLogLimit 活动日志保留的最大条数
+const watchLogLimit = 1000
+
+// WatchDialog 表示监视目录并自动上传的对话框
+type WatchDialog struct {
+	window     fyne.Window
+	remoteFS   transfer.RemoteFS
+	localPath  string
+	remotePath string
+	onStart    func(*transfer.Watcher)
+}
+
+// NewWatchDialog 创建新的自动上传对话框
+func NewWatchDialog(window fyne.Window, remoteFS transfer.RemoteFS, localPath, remotePath string, onStart func(*transfer.Watcher)) *WatchDialog {
+	return &WatchDialog{
+		window:     window,
+		remoteFS:   remoteFS,
+		localPath:  localPath,
+		remotePath: remotePath,
+		onStart:    onStart,
+	}
+}
+
+// Show 显示监视设置对话框
+func (d *WatchDialog) Show() {
+	localEntry := widget.NewEntry()
+	localEntry.SetText(d.localPath)
+
+	remoteEntry := widget.NewEntry()
+	remoteEntry.SetText(d.remotePath)
+
+	ignoreEntry := widget.NewEntry()
+	ignoreEntry.SetText(strings.Join(transfer.DefaultWatchIgnore, ", "))
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("本地目录", localEntry),
+		widget.NewFormItem("远程目录", remoteEntry),
+		widget.NewFormItem("忽略模式", ignoreEntry),
+	}
+
+	formDialog := dialog.NewForm(
+		"监视目录并自动上传",
+		"开始",
+		"取消",
+		items,
+		func(confirm bool) {
+			if !confirm {
+				return
+			}
+			if localEntry.Text == "" || remoteEntry.Text == "" {
+				dialog.ShowError(fmt.Errorf("本地目录和远程目录都必须填写"), d.window)
+				return
+			}
+			d.start(localEntry.Text, remoteEntry.Text, splitPatterns(ignoreEntry.Text))
+		},
+		d.window,
+	)
+	formDialog.Resize(fyne.NewSize(500, 300))
+	formDialog.Show()
+}
+
+// start 开始监视，并打开活动日志窗口
+func (d *WatchDialog) start(localPath, remotePath string, ignore []string) {
+	var logs []string
+	var logsMu sync.Mutex
+	logList := widget.NewList(
+		func() int {
+			logsMu.Lock()
+			defer logsMu.Unlock()
+			return len(logs)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			logsMu.Lock()
+			defer logsMu.Unlock()
+			if id < len(logs) {
+				item.(*widget.Label).SetText(logs[id])
+			}
+		},
+	)
+
+	// 在独立的通道上上传，不与面板的目录列表、延迟检测等操作共用；监视停止时关闭
+	worker, err := d.remoteFS.Share()
+	if err != nil {
+		dialog.ShowError(err, d.window)
+		return
+	}
+	watcher := transfer.NewWatcher(worker, localPath, remotePath, ignore, func(event transfer.WatchEvent) {
+		line := fmt.Sprintf("%s  %s  %s", event.Time.Format("15:04:05"), event.Action, event.LocalPath)
+		if event.Err != nil {
+			line += fmt.Sprintf("  失败: %v", event.Err)
+		}
+		logsMu.Lock()
+		logs = append(logs, line)
+		if len(logs) > watchLogLimit {
+			logs = logs[len(logs)-watchLogLimit:]
+		}
+		logsMu.Unlock()
+		logList.Refresh()
+		logList.ScrollToBottom()
+	})
+	if err := watcher.Start(); err != nil {
+		watcher.Close()
+		dialog.ShowError(err, d.window)
+		return
+	}
+
+	logWindow := fyne.CurrentApp().NewWindow(fmt.Sprintf("自动上传：%s → %s", localPath, remotePath))
+
+	var pauseButton *widget.Button
+	pauseButton = widget.NewButton("暂停", func() {
+		paused := !watcher.Paused()
+		watcher.SetPaused(paused)
+		if paused {
+			pauseButton.SetText("继续")
+		} else {
+			pauseButton.SetText("暂停")
+		}
+	})
+	stopButton := widget.NewButton("停止", func() {
+		logWindow.Close()
+	})
+
+	logWindow.SetOnClosed(func() {
+		watcher.Close()
+	})
+	logWindow.SetContent(container.NewBorder(
+		nil,
+		container.NewHBox(pauseButton, stopButton),
+		nil, nil,
+		logList,
+	))
+	logWindow.Resize(fyne.NewSize(700, 400))
+	logWindow.Show()
+
+	if d.onStart != nil {
+		d.onStart(watcher)
+	}
+}
+
+// splitPatterns 按逗号拆分模式列表
+func splitPatterns(text string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(text, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
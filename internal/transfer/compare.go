@@ -0,0 +1,158 @@
+package transfer
+
+import (
+	"path"
+	"sort"
+	"time"
+)
+
+// CompareStatus 目录比较结果（以左侧为基准）
+type CompareStatus int
+
+const (
+	CompareIdentical      CompareStatus = iota // 相同
+	CompareOnlyLeft                            // 仅左侧存在
+	CompareOnlyRight                           // 仅右侧存在
+	CompareNewer                               // 左侧较新
+	CompareOlder                               // 左侧较旧
+	CompareSizeDiffers                         // 修改时间相同但大小不同
+	CompareContentDiffers                      // 目录内容不同（递归比较）
+	CompareNotCompared                         // 两侧都是目录，非递归比较时未比较内容
+)
+
+// compareTimeTolerance 修改时间比较的容差
+const compareTimeTolerance = 2 * time.Second
+
+// Mirror 返回从右侧视角看到的比较结果
+func (s CompareStatus) Mirror() CompareStatus {
+	switch s {
+	case CompareOnlyLeft:
+		return CompareOnlyRight
+	case CompareOnlyRight:
+		return CompareOnlyLeft
+	case CompareNewer:
+		return CompareOlder
+	case CompareOlder:
+		return CompareNewer
+	default:
+		return s
+	}
+}
+
+// String 返回比较结果名称（以左侧为基准）
+func (s CompareStatus) String() string {
+	switch s {
+	case CompareIdentical:
+		return "相同"
+	case CompareOnlyLeft:
+		return "仅此侧"
+	case CompareOnlyRight:
+		return "仅对侧"
+	case CompareNewer:
+		return "较新"
+	case CompareOlder:
+		return "较旧"
+	case CompareSizeDiffers:
+		return "大小不同"
+	case CompareContentDiffers:
+		return "内容不同"
+	case CompareNotCompared:
+		return "未比较"
+	default:
+		return ""
+	}
+}
+
+// CompareEntry 单个条目的比较结果
+type CompareEntry struct {
+	RelPath string    // 相对于比较根目录的路径（使用/分隔）
+	Left    *FileInfo // 左侧文件信息，不存在时为nil
+	Right   *FileInfo // 右侧文件信息，不存在时为nil
+	Status  CompareStatus
+}
+
+// CompareDirectories 比较两个目录，recursive 为 true 时递归比较子目录
+func CompareDirectories(left, right *FileSystem, leftRoot, rightRoot string, recursive bool) (map[string]CompareEntry, error) {
+	result := make(map[string]CompareEntry)
+	if _, err := compareDir(left, right, leftRoot, rightRoot, "", recursive, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// compareDir 比较一层目录，返回该目录下是否存在差异
+func compareDir(left, right *FileSystem, leftDir, rightDir, rel string, recursive bool, result map[string]CompareEntry) (bool, error) {
+	leftFiles, err := left.ListFiles(leftDir)
+	if err != nil {
+		return false, err
+	}
+	rightFiles, err := right.ListFiles(rightDir)
+	if err != nil {
+		return false, err
+	}
+
+	byName := make(map[string]*CompareEntry)
+	var names []string
+	for i := range leftFiles {
+		f := &leftFiles[i]
+		byName[f.Name] = &CompareEntry{RelPath: path.Join(rel, f.Name), Left: f}
+		names = append(names, f.Name)
+	}
+	for i := range rightFiles {
+		f := &rightFiles[i]
+		if entry, ok := byName[f.Name]; ok {
+			entry.Right = f
+			continue
+		}
+		byName[f.Name] = &CompareEntry{RelPath: path.Join(rel, f.Name), Right: f}
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+
+	differs := false
+	for _, name := range names {
+		entry := byName[name]
+		switch {
+		case entry.Right == nil:
+			entry.Status = CompareOnlyLeft
+		case entry.Left == nil:
+			entry.Status = CompareOnlyRight
+		case entry.Left.IsDir && entry.Right.IsDir && !recursive:
+			entry.Status = CompareNotCompared
+		case entry.Left.IsDir && entry.Right.IsDir:
+			entry.Status = CompareIdentical
+			sub, err := compareDir(left, right, entry.Left.Path, entry.Right.Path, entry.RelPath, recursive, result)
+			if err != nil {
+				return false, err
+			}
+			if sub {
+				entry.Status = CompareContentDiffers
+			}
+		case entry.Left.IsDir != entry.Right.IsDir:
+			entry.Status = CompareContentDiffers
+		default:
+			entry.Status = compareFileInfo(entry.Left, entry.Right)
+		}
+
+		if entry.Status != CompareIdentical {
+			differs = true
+		}
+		result[entry.RelPath] = *entry
+	}
+	return differs, nil
+}
+
+// compareFileInfo 比较两个文件
+func compareFileInfo(left, right *FileInfo) CompareStatus {
+	d := left.ModTime.Sub(right.ModTime)
+	switch {
+	case d > compareTimeTolerance:
+		return CompareNewer
+	case d < -compareTimeTolerance:
+		return CompareOlder
+	case left.Size != right.Size:
+		return CompareSizeDiffers
+	default:
+		return CompareIdentical
+	}
+}
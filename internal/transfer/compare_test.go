@@ -0,0 +1,70 @@
+package transfer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompareDirectories(t *testing.T) {
+	left, right := t.TempDir(), t.TempDir()
+	modTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	write := func(root, rel, content string) {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(left, "same.txt", "a")
+	write(right, "same.txt", "a")
+	write(left, "only.txt", "a")
+	write(left, "d/x.txt", "a")
+	write(right, "d/x.txt", "ab")
+	write(left, "e/y.txt", "a")
+	write(right, "e/y.txt", "a")
+
+	tests := []struct {
+		name      string
+		recursive bool
+		want      map[string]CompareStatus
+	}{
+		{
+			name: "不递归时目录未比较",
+			want: map[string]CompareStatus{
+				"same.txt": CompareIdentical, "only.txt": CompareOnlyLeft,
+				"d": CompareNotCompared, "e": CompareNotCompared,
+			},
+		},
+		{
+			name:      "递归比较",
+			recursive: true,
+			want: map[string]CompareStatus{
+				"same.txt": CompareIdentical, "only.txt": CompareOnlyLeft,
+				"d": CompareContentDiffers, "d/x.txt": CompareSizeDiffers,
+				"e": CompareIdentical, "e/y.txt": CompareIdentical,
+			},
+		},
+	}
+	for _, tt := range tests {
+		entries, err := CompareDirectories(NewFileSystem(left), NewFileSystem(right), left, right, tt.recursive)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		got := make(map[string]CompareStatus)
+		for rel, entry := range entries {
+			got[rel] = entry.Status
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: 得到 %v，期望 %v", tt.name, got, tt.want)
+		}
+	}
+}
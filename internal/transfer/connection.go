@@ -0,0 +1,165 @@
+package transfer
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// Connections 全局的 SSH 连接管理器，同一服务器和用户的标签页、传输任务共用一个连接
+var Connections = NewConnectionManager()
+
+// ConnectionManager 按服务器、端口和用户名共享 SSH 连接并进行引用计数，
+// 最后一个使用者释放后断开连接
+type ConnectionManager struct {
+	mu    sync.Mutex
+	conns map[string]*SharedConn
+}
+
+// SharedConn 共享的 SSH 连接，每个使用者在自己的 SFTP 通道上操作
+type SharedConn struct {
+	manager *ConnectionManager
+	key     string
+	client  *ssh.Client
+	cipher  string        // 协商的加密算法
+	refs    int           // 使用者数量，由 manager.mu 保护
+	closed  bool          // 连接已断开，不再复用，由 manager.mu 保护
+	ready   chan struct{} // 连接建立或失败后关闭
+	err     error         // 建立连接的错误
+}
+
+// NewConnectionManager 创建连接管理器
+func NewConnectionManager() *ConnectionManager {
+	return &ConnectionManager{conns: make(map[string]*SharedConn)}
+}
+
+// connectionKey 返回连接的标识，同一标识的配置共用连接；不含密码，一次性密码等每次不同的凭据也能复用已认证的连接
+func connectionKey(config *SFTPConfig) string {
+	return config.Username + "@" + net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+}
+
+// Acquire 获取到服务器的连接：已有同一标识、尚未断开的连接时直接复用，不再重新认证；
+// 否则建立新连接，同时发起的请求只建立一次。使用完后调用 Release
+func (m *ConnectionManager) Acquire(config *SFTPConfig) (*SharedConn, error) {
+	key := connectionKey(config)
+	m.mu.Lock()
+	conn, ok := m.conns[key]
+	if ok && !conn.closed {
+		conn.refs++
+		m.mu.Unlock()
+		<-conn.ready
+		if conn.err != nil {
+			m.release(conn)
+			return nil, conn.err
+		}
+		return conn, nil
+	}
+	// 已断开的连接由持有者继续使用到释放，新的请求使用新连接
+	conn = &SharedConn{manager: m, key: key, refs: 1, ready: make(chan struct{})}
+	m.conns[key] = conn
+	m.mu.Unlock()
+
+	conn.client, conn.cipher, conn.err = dialSSH(config)
+	if conn.err != nil {
+		// 之后的请求重新连接，正在等待的请求返回同样的错误
+		m.forget(conn)
+	}
+	close(conn.ready)
+	if conn.err != nil {
+		m.release(conn)
+		return nil, conn.err
+	}
+	// 连接断开后不再复用，下次获取时重新连接
+	go func() {
+		conn.client.Wait()
+		m.forget(conn)
+	}()
+	return conn, nil
+}
+
+// forget 将连接标记为已断开并从管理器中移除，已持有的使用者不受影响
+func (m *ConnectionManager) forget(conn *SharedConn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	conn.closed = true
+	if m.conns[conn.key] == conn {
+		delete(m.conns, conn.key)
+	}
+}
+
+// release 减少引用计数，最后一个使用者释放时关闭连接
+func (m *ConnectionManager) release(conn *SharedConn) error {
+	m.mu.Lock()
+	conn.refs--
+	last := conn.refs == 0
+	if last && m.conns[conn.key] == conn {
+		delete(m.conns, conn.key)
+	}
+	m.mu.Unlock()
+	if !last || conn.client == nil {
+		return nil
+	}
+	return conn.client.Close()
+}
+
+// dialSSH 连接并认证 SSH 服务器，同时记录服务器的 KEXINIT 以得知协商的加密算法
+func dialSSH(config *SFTPConfig) (*ssh.Client, string, error) {
+	clientConfig := &ssh.ClientConfig{
+		User: config.Username,
+		Auth: []ssh.AuthMethod{
+			ssh.Password(config.Password),
+		},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         30 * time.Second,
+	}
+	clientConfig.Ciphers = sshCiphers
+
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	conn, err := net.DialTimeout("tcp", addr, clientConfig.Timeout)
+	if err != nil {
+		return nil, "", fmt.Errorf("连接SSH服务器失败: %v", err)
+	}
+	sniffer := &kexSniffer{Conn: conn}
+	sshConn, chans, reqs, err := ssh.NewClientConn(sniffer, addr, clientConfig)
+	if err != nil {
+		conn.Close()
+		return nil, "", fmt.Errorf("连接SSH服务器失败: %v", err)
+	}
+	return ssh.NewClient(sshConn, chans, reqs), sniffer.negotiatedCipher(), nil
+}
+
+// Client 返回 SSH 客户端
+func (c *SharedConn) Client() *ssh.Client {
+	return c.client
+}
+
+// Cipher 返回协商的加密算法，未知时为空
+func (c *SharedConn) Cipher() string {
+	return c.cipher
+}
+
+// Retain 增加一个使用者，对应地调用一次 Release
+func (c *SharedConn) Retain() {
+	c.manager.mu.Lock()
+	c.refs++
+	c.manager.mu.Unlock()
+}
+
+// Release 释放连接，最后一个使用者释放时断开连接
+func (c *SharedConn) Release() error {
+	return c.manager.release(c)
+}
+
+// OpenSFTP 在连接上打开新的 SFTP 子系统通道
+func (c *SharedConn) OpenSFTP() (*sftp.Client, error) {
+	client, err := sftp.NewClient(c.client)
+	if err != nil {
+		return nil, fmt.Errorf("创建SFTP客户端失败: %v", err)
+	}
+	return client, nil
+}
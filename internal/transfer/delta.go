@@ -0,0 +1,445 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/adler32"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// deltaMinSize 小于该大小的文件直接完整上传
+const deltaMinSize = 1 << 20
+
+// 增量数据格式：魔数后跟若干操作
+//
+//	'C' + 起始块号(uint32) + 块数(uint32)  复制旧文件中的连续块
+//	'D' + 长度(uint32) + 数据             写入新数据
+//	'E'                                    结束
+const deltaMagic = "XFTPDELTA1"
+
+// deltaLiteralLimit 单个数据操作的最大长度
+const deltaLiteralLimit = 1 << 20
+
+// deltaCopyLimit 单个复制操作覆盖的最大字节数，辅助脚本按操作整段读入内存
+const deltaCopyLimit = 4 << 20
+
+// deltaHelperScript 远程辅助脚本：sig 计算旧文件的分块校验和，patch 按增量数据重建文件
+const deltaHelperScript = `import sys,os,zlib,hashlib,struct
+def sig(p,bs):
+    w=sys.stdout.write
+    w('XFTPSIG1 %d\n'%os.path.getsize(p))
+    f=open(p,'rb')
+    while True:
+        b=f.read(bs)
+        if not b:
+            break
+        w('%08x %s %d\n'%(zlib.adler32(b)&0xffffffff,hashlib.md5(b).hexdigest(),len(b)))
+    f.close()
+def patch(old,delta,out,bs):
+    h=hashlib.sha256()
+    o=open(old,'rb')
+    d=open(delta,'rb')
+    w=open(out,'wb')
+    if d.read(10)!=b'XFTPDELTA1':
+        raise SystemExit('bad delta')
+    while True:
+        op=d.read(1)
+        if op==b'C':
+            i,n=struct.unpack('>II',d.read(8))
+            o.seek(i*bs)
+            data=o.read(n*bs)
+        elif op==b'D':
+            n,=struct.unpack('>I',d.read(4))
+            data=d.read(n)
+        elif op==b'E':
+            break
+        else:
+            raise SystemExit('bad op')
+        w.write(data)
+        h.update(data)
+    w.close()
+    os.chmod(out,os.stat(old).st_mode&0o7777)
+    sys.stdout.write('XFTPOK %s\n'%h.hexdigest())
+if sys.argv[1]=='sig':
+    sig(sys.argv[2],int(sys.argv[3]))
+else:
+    patch(sys.argv[2],sys.argv[3],sys.argv[4],int(sys.argv[5]))
+`
+
+// errNoDeltaHelper 服务器不支持执行辅助脚本
+var errNoDeltaHelper = errors.New("服务器不支持增量传输")
+
+// blockSignature 旧文件中一个块的校验和
+type blockSignature struct {
+	index  uint32
+	strong [md5.Size]byte
+}
+
+// uploadDelta 使用分块校验和只上传变化的部分，失败时由调用方回退到完整上传
+func (fs *SFTPFileSystem) uploadDelta(localPath, remotePath string, progress func(current, total int64)) error {
+	localInfo, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+	remoteInfo, err := fs.sftpClient.Stat(remotePath)
+	if err != nil || remoteInfo.IsDir() || remoteInfo.Size() < deltaMinSize {
+		return errNoDeltaHelper
+	}
+
+	python, err := fs.findDeltaHelper()
+	if err != nil {
+		return err
+	}
+
+	blockSize := deltaBlockSize(remoteInfo.Size())
+	signatures, err := fs.remoteSignatures(python, remotePath, blockSize)
+	if err != nil {
+		return err
+	}
+
+	// 生成增量数据并通过SFTP写入远程临时文件
+	deltaPath := remotePath + ".xftp-delta"
+	outPath := remotePath + ".xftp-tmp"
+	defer fs.sftpClient.Remove(deltaPath)
+
+	deltaFile, err := fs.sftpClient.Create(deltaPath)
+	if err != nil {
+		return err
+	}
+	localSum, err := writeDelta(localPath, localInfo.Size(), blockSize, signatures, deltaFile, progress, fs.stop)
+	if closeErr := deltaFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	// 在服务器上重建文件并校验
+	output, err := fs.runRemote(fmt.Sprintf("%s -c %s patch %s %s %s %d",
+		python, shellQuote(deltaHelperScript), shellQuote(remotePath), shellQuote(deltaPath), shellQuote(outPath), blockSize))
+	if err != nil {
+		fs.sftpClient.Remove(outPath)
+		return err
+	}
+	remoteSum := strings.TrimPrefix(strings.TrimSpace(string(output)), "XFTPOK ")
+	if remoteSum != localSum {
+		fs.sftpClient.Remove(outPath)
+		return fmt.Errorf("增量传输校验失败")
+	}
+
+	if err := fs.replaceRemote(outPath, remotePath); err != nil {
+		return err
+	}
+	return fs.sftpClient.Chtimes(remotePath, localInfo.ModTime(), localInfo.ModTime())
+}
+
+// findDeltaHelper 查找服务器上可用的 python，结果会被缓存
+func (fs *SFTPFileSystem) findDeltaHelper() (string, error) {
+	if fs.deltaHelper == "" {
+		fs.deltaHelper = "-"
+		for _, candidate := range []string{"python3", "python"} {
+			output, err := fs.runRemote(candidate + ` -c "print('xftp')"`)
+			if err == nil && strings.TrimSpace(string(output)) == "xftp" {
+				fs.deltaHelper = candidate
+				break
+			}
+		}
+	}
+	if fs.deltaHelper == "-" {
+		return "", errNoDeltaHelper
+	}
+	return fs.deltaHelper, nil
+}
+
+// remoteSignatures 在服务器上计算旧文件的分块校验和
+func (fs *SFTPFileSystem) remoteSignatures(python, remotePath string, blockSize int) (map[uint32][]blockSignature, error) {
+	output, err := fs.runRemote(fmt.Sprintf("%s -c %s sig %s %d",
+		python, shellQuote(deltaHelperScript), shellQuote(remotePath), blockSize))
+	if err != nil {
+		return nil, err
+	}
+	return parseSignatures(output, blockSize)
+}
+
+// parseSignatures 解析辅助脚本输出的分块校验和，按弱校验和索引
+func parseSignatures(output []byte, blockSize int) (map[uint32][]blockSignature, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	if !scanner.Scan() || !strings.HasPrefix(scanner.Text(), "XFTPSIG1 ") {
+		return nil, errNoDeltaHelper
+	}
+
+	signatures := make(map[uint32][]blockSignature)
+	var index uint32
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("无法解析校验和: %q", scanner.Text())
+		}
+		weak, err := strconv.ParseUint(fields[0], 16, 32)
+		if err != nil {
+			return nil, err
+		}
+		strong, err := hex.DecodeString(fields[1])
+		if err != nil || len(strong) != md5.Size {
+			return nil, fmt.Errorf("无法解析校验和: %q", scanner.Text())
+		}
+		length, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		// 只匹配完整的块，末尾不足一块的部分作为新数据发送
+		if length == blockSize {
+			sig := blockSignature{index: index}
+			copy(sig.strong[:], strong)
+			signatures[uint32(weak)] = append(signatures[uint32(weak)], sig)
+		}
+		index++
+	}
+	return signatures, scanner.Err()
+}
+
+// runRemote 通过SSH执行远程命令并返回标准输出
+func (fs *SFTPFileSystem) runRemote(command string) ([]byte, error) {
+	session, err := fs.sshClient.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+	return session.Output(command)
+}
+
+// writeDelta 使用滚动校验和扫描本地文件，写出增量数据，返回本地文件的SHA-256；stop 关闭后返回 ErrCanceled
+func writeDelta(localPath string, size int64, blockSize int, signatures map[uint32][]blockSignature, w io.Writer, progress func(current, total int64), stop <-chan struct{}) (string, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	out := bufio.NewWriterSize(w, 1<<16)
+	hash := sha256.New()
+	reader := io.TeeReader(f, hash)
+	encoder := &deltaEncoder{w: out, maxCopy: uint32(max(1, deltaCopyLimit/blockSize))}
+	if _, err := out.WriteString(deltaMagic); err != nil {
+		return "", err
+	}
+
+	// buf[pos:pos+blockSize] 为当前窗口，buf[litStart:pos] 为待发送的新数据
+	buf := make([]byte, 0, 4<<20+blockSize)
+	pos, litStart := 0, 0
+	var offset int64 // buf[0] 在文件中的偏移
+	eof := false
+	var roll rollingChecksum
+	rollValid := false
+
+	fill := func() error {
+		if stopped(stop) {
+			return ErrCanceled
+		}
+		// 先发送待发送的数据，再整理缓冲区
+		if err := encoder.literal(buf[litStart:pos]); err != nil {
+			return err
+		}
+		copy(buf, buf[pos:])
+		buf = buf[:len(buf)-pos]
+		offset += int64(pos)
+		pos, litStart = 0, 0
+		for len(buf) < cap(buf) && !eof {
+			n, err := reader.Read(buf[len(buf):cap(buf)])
+			buf = buf[:len(buf)+n]
+			if err == io.EOF {
+				eof = true
+			} else if err != nil {
+				return err
+			}
+		}
+		if progress != nil {
+			progress(offset, size)
+		}
+		return nil
+	}
+
+	if err := fill(); err != nil {
+		return "", err
+	}
+	for {
+		if pos+blockSize > len(buf) {
+			if eof {
+				break
+			}
+			if err := fill(); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		window := buf[pos : pos+blockSize]
+		if !rollValid {
+			roll = newRollingChecksum(window)
+			rollValid = true
+		}
+
+		if candidates, ok := signatures[roll.sum()]; ok {
+			strong := md5.Sum(window)
+			matched := false
+			for _, sig := range candidates {
+				if sig.strong == strong {
+					if err := encoder.literal(buf[litStart:pos]); err != nil {
+						return "", err
+					}
+					if err := encoder.copyBlock(sig.index); err != nil {
+						return "", err
+					}
+					pos += blockSize
+					litStart = pos
+					rollValid = false
+					matched = true
+					break
+				}
+			}
+			if matched {
+				continue
+			}
+		}
+
+		// 没有匹配：窗口后移一个字节
+		if pos+blockSize < len(buf) {
+			roll.roll(buf[pos], buf[pos+blockSize])
+		} else {
+			rollValid = false
+		}
+		pos++
+		if pos-litStart >= deltaLiteralLimit {
+			if err := encoder.literal(buf[litStart:pos]); err != nil {
+				return "", err
+			}
+			litStart = pos
+		}
+	}
+
+	// 剩余数据全部作为新数据发送
+	if err := encoder.literal(buf[litStart:]); err != nil {
+		return "", err
+	}
+	if err := encoder.finish(); err != nil {
+		return "", err
+	}
+	if err := out.Flush(); err != nil {
+		return "", err
+	}
+	// 最后一次读取可能已经报告了全部进度，不重复报告，否则传输队列会当作下一个文件
+	if progress != nil && offset < size {
+		progress(size, size)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// deltaEncoder 写出增量操作，合并连续的块复制，每个复制操作最多 maxCopy 块
+type deltaEncoder struct {
+	w          io.Writer
+	maxCopy    uint32
+	copyStart  uint32
+	copyCount  uint32
+	hasPending bool
+}
+
+func (e *deltaEncoder) copyBlock(index uint32) error {
+	if e.hasPending && e.copyStart+e.copyCount == index && e.copyCount < e.maxCopy {
+		e.copyCount++
+		return nil
+	}
+	if err := e.flushCopy(); err != nil {
+		return err
+	}
+	e.copyStart, e.copyCount, e.hasPending = index, 1, true
+	return nil
+}
+
+func (e *deltaEncoder) flushCopy() error {
+	if !e.hasPending {
+		return nil
+	}
+	e.hasPending = false
+	var op [9]byte
+	op[0] = 'C'
+	binary.BigEndian.PutUint32(op[1:5], e.copyStart)
+	binary.BigEndian.PutUint32(op[5:9], e.copyCount)
+	_, err := e.w.Write(op[:])
+	return err
+}
+
+func (e *deltaEncoder) literal(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := e.flushCopy(); err != nil {
+		return err
+	}
+	var op [5]byte
+	op[0] = 'D'
+	binary.BigEndian.PutUint32(op[1:5], uint32(len(data)))
+	if _, err := e.w.Write(op[:]); err != nil {
+		return err
+	}
+	_, err := e.w.Write(data)
+	return err
+}
+
+func (e *deltaEncoder) finish() error {
+	if err := e.flushCopy(); err != nil {
+		return err
+	}
+	_, err := e.w.Write([]byte{'E'})
+	return err
+}
+
+// rollingChecksum 可滚动计算的 Adler-32，与 zlib.adler32 结果一致
+type rollingChecksum struct {
+	a, b uint32
+	n    uint32
+}
+
+const adlerMod = 65521
+
+func newRollingChecksum(window []byte) rollingChecksum {
+	sum := adler32.Checksum(window)
+	return rollingChecksum{a: sum & 0xffff, b: sum >> 16, n: uint32(len(window))}
+}
+
+func (r *rollingChecksum) sum() uint32 {
+	return r.b<<16 | r.a
+}
+
+// roll 移出 out 并移入 in
+func (r *rollingChecksum) roll(out, in byte) {
+	r.a = (r.a + adlerMod - uint32(out) + uint32(in)) % adlerMod
+	r.b = (r.b + adlerMod - (r.n*uint32(out))%adlerMod + r.a + adlerMod - 1) % adlerMod
+}
+
+// deltaBlockSize 按文件大小选择块大小（约为大小的平方根）
+func deltaBlockSize(size int64) int {
+	blockSize := int(math.Sqrt(float64(size)))
+	blockSize = blockSize &^ 7
+	if blockSize < 2048 {
+		blockSize = 2048
+	}
+	if blockSize > 128<<10 {
+		blockSize = 128 << 10
+	}
+	return blockSize
+}
+
+// shellQuote 为远程shell命令转义参数
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,257 @@
+package transfer
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/adler32"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRollingChecksum(t *testing.T) {
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(random)
+	tests := []struct {
+		name   string
+		data   []byte
+		window int
+	}{
+		{name: "随机数据", data: random, window: 64},
+		{name: "单字节窗口", data: random[:256], window: 1},
+		{name: "全部为0xff", data: bytes.Repeat([]byte{0xff}, 8192), window: 5552},
+		{name: "全部为0", data: make([]byte, 1024), window: 100},
+		{name: "大窗口", data: random, window: 2048},
+	}
+	for _, tt := range tests {
+		roll := newRollingChecksum(tt.data[:tt.window])
+		for pos := 0; ; pos++ {
+			want := adler32.Checksum(tt.data[pos : pos+tt.window])
+			if got := roll.sum(); got != want {
+				t.Errorf("%s: 位置 %d 的校验和为 %08x，期望 %08x", tt.name, pos, got, want)
+				break
+			}
+			if pos+tt.window >= len(tt.data) {
+				break
+			}
+			roll.roll(tt.data[pos], tt.data[pos+tt.window])
+		}
+	}
+}
+
+func TestDeltaBlockSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		want int
+	}{
+		{size: 0, want: 2048},
+		{size: 1 << 20, want: 2048},
+		{size: 100 << 20, want: 10240},
+		{size: 1 << 40, want: 128 << 10},
+	}
+	for _, tt := range tests {
+		if got := deltaBlockSize(tt.size); got != tt.want {
+			t.Errorf("deltaBlockSize(%d) = %d，期望 %d", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestParseSignatures(t *testing.T) {
+	block := bytes.Repeat([]byte("x"), 16)
+	line := fmt.Sprintf("%08x %x %d\n", adler32.Checksum(block), md5.Sum(block), len(block))
+	tests := []struct {
+		name   string
+		output string
+		count  int
+		err    bool
+	}{
+		{name: "缺少头部", output: line, err: true},
+		{name: "空文件", output: "XFTPSIG1 0\n", count: 0},
+		{name: "完整块", output: "XFTPSIG1 32\n" + line + line, count: 2},
+		{name: "忽略末尾不完整的块", output: "XFTPSIG1 20\n" + line + "00000001 " + hex.EncodeToString(make([]byte, 16)) + " 4\n", count: 1},
+		{name: "字段数错误", output: "XFTPSIG1 16\n00000001 abc\n", err: true},
+		{name: "弱校验和无效", output: "XFTPSIG1 16\nzzzz " + hex.EncodeToString(make([]byte, 16)) + " 16\n", err: true},
+		{name: "强校验和长度错误", output: "XFTPSIG1 16\n00000001 abcd 16\n", err: true},
+	}
+	for _, tt := range tests {
+		signatures, err := parseSignatures([]byte(tt.output), len(block))
+		if (err != nil) != tt.err {
+			t.Errorf("%s: 错误 %v，期望出错 %v", tt.name, err, tt.err)
+			continue
+		}
+		count := 0
+		for _, sigs := range signatures {
+			count += len(sigs)
+		}
+		if count != tt.count {
+			t.Errorf("%s: 解析出 %d 个块，期望 %d", tt.name, count, tt.count)
+		}
+	}
+}
+
+// testSignatures 按辅助脚本的 sig 输出格式计算旧文件的分块校验和
+func testSignatures(t *testing.T, old []byte, blockSize int) map[uint32][]blockSignature {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "XFTPSIG1 %d\n", len(old))
+	for start := 0; start < len(old); start += blockSize {
+		block := old[start:min(start+blockSize, len(old))]
+		fmt.Fprintf(&out, "%08x %x %d\n", adler32.Checksum(block), md5.Sum(block), len(block))
+	}
+	signatures, err := parseSignatures(out.Bytes(), blockSize)
+	if err != nil {
+		t.Fatalf("解析校验和失败: %v", err)
+	}
+	return signatures
+}
+
+// applyDelta 按辅助脚本的 patch 逻辑重建文件，返回重建的内容和复制的块数
+func applyDelta(t *testing.T, old, delta []byte, blockSize int) ([]byte, int) {
+	if !bytes.HasPrefix(delta, []byte(deltaMagic)) {
+		t.Fatalf("缺少魔数")
+	}
+	delta = delta[len(deltaMagic):]
+	var out bytes.Buffer
+	copied := 0
+	for {
+		if len(delta) == 0 {
+			t.Fatalf("缺少结束操作")
+		}
+		op := delta[0]
+		delta = delta[1:]
+		switch op {
+		case 'C':
+			index := int(binary.BigEndian.Uint32(delta))
+			count := int(binary.BigEndian.Uint32(delta[4:]))
+			delta = delta[8:]
+			out.Write(old[index*blockSize : (index+count)*blockSize])
+			copied += count
+		case 'D':
+			n := int(binary.BigEndian.Uint32(delta))
+			out.Write(delta[4 : 4+n])
+			delta = delta[4+n:]
+		case 'E':
+			if len(delta) != 0 {
+				t.Fatalf("结束操作后还有 %d 字节", len(delta))
+			}
+			return out.Bytes(), copied
+		default:
+			t.Fatalf("未知操作 %q", op)
+		}
+	}
+}
+
+func TestWriteDelta(t *testing.T) {
+	const blockSize = 2048
+	random := func(seed int64, n int) []byte {
+		data := make([]byte, n)
+		rand.New(rand.NewSource(seed)).Read(data)
+		return data
+	}
+	old := random(1, 64*blockSize+100)
+	modified := append([]byte(nil), old...)
+	copy(modified[10*blockSize+5:], "changed")
+
+	tests := []struct {
+		name      string
+		old, new  []byte
+		minCopied int // 至少复用的块数
+	}{
+		{name: "内容相同", old: old, new: old, minCopied: 64},
+		{name: "末尾追加", old: old, new: append(append([]byte(nil), old...), random(2, 5000)...), minCopied: 64},
+		{name: "开头插入", old: old, new: append(random(3, 777), old...), minCopied: 64},
+		{name: "中间修改", old: old, new: modified, minCopied: 63},
+		{name: "删除一块", old: old, new: append(append([]byte(nil), old[:5*blockSize]...), old[6*blockSize:]...), minCopied: 63},
+		{name: "完全不同", old: old, new: random(4, 3*blockSize), minCopied: 0},
+		{name: "新文件为空", old: old, new: nil, minCopied: 0},
+		{name: "旧文件小于一块", old: old[:100], new: old[:300], minCopied: 0},
+		{name: "超过单个数据操作的上限", old: old[:blockSize], new: random(5, deltaLiteralLimit*2+10), minCopied: 0},
+	}
+	dir := t.TempDir()
+	for i, tt := range tests {
+		localPath := filepath.Join(dir, fmt.Sprintf("new%d", i))
+		if err := os.WriteFile(localPath, tt.new, 0644); err != nil {
+			t.Fatal(err)
+		}
+		var delta bytes.Buffer
+		var lastProgress int64 = -1
+		sum, err := writeDelta(localPath, int64(len(tt.new)), blockSize, testSignatures(t, tt.old, blockSize), &delta,
+			func(current, total int64) { lastProgress = current }, nil)
+		if err != nil {
+			t.Errorf("%s: writeDelta 失败: %v", tt.name, err)
+			continue
+		}
+		rebuilt, copied := applyDelta(t, tt.old, delta.Bytes(), blockSize)
+		if !bytes.Equal(rebuilt, tt.new) {
+			t.Errorf("%s: 重建的内容与新文件不一致（%d / %d 字节）", tt.name, len(rebuilt), len(tt.new))
+		}
+		if copied < tt.minCopied {
+			t.Errorf("%s: 只复用了 %d 块，期望至少 %d 块", tt.name, copied, tt.minCopied)
+		}
+		want := sha256.Sum256(tt.new)
+		if sum != hex.EncodeToString(want[:]) {
+			t.Errorf("%s: 返回的校验和不正确", tt.name)
+		}
+		if lastProgress != int64(len(tt.new)) {
+			t.Errorf("%s: 最后的进度为 %d，期望 %d", tt.name, lastProgress, len(tt.new))
+		}
+	}
+}
+
+func TestWriteDeltaMergesCopies(t *testing.T) {
+	const blockSize = 2048
+	limit := deltaCopyLimit / blockSize
+	copyOp := func(start, count int) []byte {
+		return []byte{'C', 0, 0, byte(start >> 8), byte(start), 0, 0, byte(count >> 8), byte(count)}
+	}
+	tests := []struct {
+		name   string
+		blocks int
+		ops    [][]byte
+	}{
+		{name: "合并为一个复制操作", blocks: 8, ops: [][]byte{copyOp(0, 8)}},
+		{name: "正好达到上限", blocks: limit, ops: [][]byte{copyOp(0, limit)}},
+		{name: "超过上限时拆分", blocks: limit + 2, ops: [][]byte{copyOp(0, limit), copyOp(limit, 2)}},
+	}
+	dir := t.TempDir()
+	for i, tt := range tests {
+		old := make([]byte, tt.blocks*blockSize)
+		rand.New(rand.NewSource(int64(i))).Read(old)
+		localPath := filepath.Join(dir, fmt.Sprintf("same%d", i))
+		if err := os.WriteFile(localPath, old, 0644); err != nil {
+			t.Fatal(err)
+		}
+		var delta bytes.Buffer
+		if _, err := writeDelta(localPath, int64(len(old)), blockSize, testSignatures(t, old, blockSize), &delta, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		// 魔数 + 复制操作 + 结束
+		want := []byte(deltaMagic)
+		for _, op := range tt.ops {
+			want = append(want, op...)
+		}
+		want = append(want, 'E')
+		if !bytes.Equal(delta.Bytes(), want) {
+			t.Errorf("%s: 增量数据为 %q，期望 %q", tt.name, delta.Bytes(), want)
+		}
+	}
+}
+
+func TestWriteDeltaCanceled(t *testing.T) {
+	localPath := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(localPath, make([]byte, 10000), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	close(stop)
+	var delta bytes.Buffer
+	_, err := writeDelta(localPath, 10000, 2048, nil, &delta, nil, stop)
+	if !errors.Is(err, ErrCanceled) {
+		t.Errorf("取消后应返回 ErrCanceled，得到 %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package transfer
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// localDiskSpace 获取本地路径所在文件系统的空间
+func localDiskSpace(path string) (DiskSpace, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return DiskSpace{}, fmt.Errorf("获取磁盘空间失败: %v", err)
+	}
+	return DiskSpace{
+		Total: int64(uint64(stat.Blocks) * uint64(stat.Bsize)),
+		Free:  int64(uint64(stat.Bavail) * uint64(stat.Bsize)),
+	}, nil
+}
@@ -0,0 +1,20 @@
+package transfer
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// localDiskSpace 获取本地路径所在磁盘的空间
+func localDiskSpace(path string) (DiskSpace, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return DiskSpace{}, err
+	}
+	var free, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(name, &free, &total, &totalFree); err != nil {
+		return DiskSpace{}, fmt.Errorf("获取磁盘空间失败: %v", err)
+	}
+	return DiskSpace{Total: int64(total), Free: int64(free)}, nil
+}
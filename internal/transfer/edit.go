@@ -0,0 +1,118 @@
+package transfer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Stat 获取远程文件信息
+func (fs *SFTPFileSystem) Stat(path string) (FileInfo, error) {
+	if err := fs.checkOpen(); err != nil {
+		return FileInfo{}, err
+	}
+	info, err := fs.sftpClient.Stat(path)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return FileInfo{
+		Name:    info.Name(),
+		Path:    path,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		IsDir:   info.IsDir(),
+		Mode:    info.Mode(),
+		Owner:   remoteOwner(info),
+	}, nil
+}
+
+// WriteFile 原子地写入远程文件：先写入同目录下的临时文件，再重命名覆盖原文件，
+// 写入过程中断不会留下不完整的文件。原文件存在时保留其权限
+func (fs *SFTPFileSystem) WriteFile(path string, data []byte) error {
+	if err := fs.checkOpen(); err != nil {
+		return err
+	}
+	tmpPath := path + ".xftp-tmp"
+	file, err := fs.sftpClient.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fs.sftpClient.Remove(tmpPath)
+		return fmt.Errorf("写入临时文件失败: %v", err)
+	}
+
+	if info, err := fs.sftpClient.Stat(path); err == nil {
+		fs.sftpClient.Chmod(tmpPath, info.Mode().Perm())
+	}
+	return fs.replaceRemote(tmpPath, path)
+}
+
+// replaceRemote 用临时文件替换远程文件
+func (fs *SFTPFileSystem) replaceRemote(tmpPath, path string) error {
+	if err := fs.sftpClient.PosixRename(tmpPath, path); err == nil {
+		return nil
+	}
+	// 服务器不支持 posix-rename 扩展时先删除再重命名
+	if err := fs.sftpClient.Remove(path); err != nil && !os.IsNotExist(err) {
+		fs.sftpClient.Remove(tmpPath)
+		return err
+	}
+	return fs.sftpClient.Rename(tmpPath, path)
+}
+
+// Stat 获取文件信息
+func (fs *FileSystem) Stat(path string) (FileInfo, error) {
+	if fs.remote != nil {
+		return fs.remote.Stat(path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return FileInfo{
+		Name:    info.Name(),
+		Path:    path,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		IsDir:   info.IsDir(),
+		Mode:    info.Mode(),
+		Owner:   localOwner(info),
+	}, nil
+}
+
+// WriteFile 原子地写入文件，远程文件通过SFTP写入
+func (fs *FileSystem) WriteFile(path string, data []byte) error {
+	if fs.remote != nil {
+		return fs.remote.WriteFile(path, data)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".xftp-tmp*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	tmpPath := file.Name()
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("写入临时文件失败: %v", err)
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		os.Chmod(tmpPath, info.Mode().Perm())
+	} else {
+		os.Chmod(tmpPath, 0644)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package transfer
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ErrEditConflict 远程文件在下载后已被修改
+var ErrEditConflict = errors.New("远程文件在打开后已被修改")
+
+// EditEvent 外部编辑的文件保存后产生的上传事件
+type EditEvent struct {
+	Time       time.Time
+	LocalPath  string
+	RemotePath string
+	Err        error // 为 ErrEditConflict 时未上传，需调用 Upload 强制覆盖
+}
+
+// editFile 一个正在外部编辑的文件
+type editFile struct {
+	remotePath string
+	baseline   FileInfo // 下载或上次上传后远程文件的信息，用于检测冲突
+	uploaded   [32]byte // 上次下载或上传内容的校验和，内容未变时不重复上传
+}
+
+// EditSession 管理一个连接上用外部编辑器打开的远程文件：
+// 文件下载到会话专用的临时目录，保存后自动上传，关闭会话时删除临时目录
+type EditSession struct {
+	remote  RemoteFS
+	tempDir string
+	onEvent func(EditEvent)
+
+	watcher *fsnotify.Watcher
+	mu      sync.Mutex
+	files   map[string]*editFile // 本地临时路径 -> 文件
+	opened  int                  // 已创建的子目录数
+	pending map[string]bool
+	timer   *time.Timer
+	done    chan struct{}
+}
+
+// NewEditSession 创建外部编辑会话；remote 应为 Share 打开的独立通道，归会话所有，Close 时一并关闭
+func NewEditSession(remote RemoteFS, onEvent func(EditEvent)) (*EditSession, error) {
+	tempDir, err := os.MkdirTemp("", "xftp798-edit-")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return nil, fmt.Errorf("创建文件监视失败: %v", err)
+	}
+	s := &EditSession{
+		remote:  remote,
+		tempDir: tempDir,
+		onEvent: onEvent,
+		watcher: watcher,
+		files:   make(map[string]*editFile),
+		pending: make(map[string]bool),
+		done:    make(chan struct{}),
+	}
+	go s.loop()
+	return s, nil
+}
+
+// Open 下载远程文件到临时目录并开始监视，返回本地路径；同一文件重复打开时返回已有的副本
+func (s *EditSession) Open(remotePath string) (string, error) {
+	s.mu.Lock()
+	for localPath, file := range s.files {
+		if file.remotePath == remotePath {
+			s.mu.Unlock()
+			return localPath, nil
+		}
+	}
+	// 每个文件使用单独的子目录，保留原文件名，便于编辑器识别文件类型
+	s.opened++
+	dir := filepath.Join(s.tempDir, strconv.Itoa(s.opened))
+	s.mu.Unlock()
+
+	baseline, err := s.remote.Stat(remotePath)
+	if err != nil {
+		return "", fmt.Errorf("获取文件信息失败: %v", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	localPath := filepath.Join(dir, RemotePaths.Base(remotePath))
+	if err := s.remote.DownloadFile(remotePath, localPath, nil); err != nil {
+		return "", fmt.Errorf("下载文件失败: %v", err)
+	}
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return "", err
+	}
+	// 监视所在目录而不是文件本身，编辑器常以“写临时文件再重命名”的方式保存
+	if err := s.watcher.Add(dir); err != nil {
+		return "", fmt.Errorf("监视文件失败: %v", err)
+	}
+
+	s.mu.Lock()
+	s.files[localPath] = &editFile{remotePath: remotePath, baseline: baseline, uploaded: sha256.Sum256(data)}
+	s.mu.Unlock()
+	return localPath, nil
+}
+
+// Upload 立即上传本地副本；force 为 true 时忽略冲突直接覆盖远程文件
+func (s *EditSession) Upload(localPath string, force bool) error {
+	s.mu.Lock()
+	file, ok := s.files[localPath]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%s 不在编辑会话中", localPath)
+	}
+
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	s.mu.Lock()
+	unchanged := sum == file.uploaded
+	baseline := file.baseline
+	s.mu.Unlock()
+	if !force && unchanged {
+		return nil
+	}
+	if !force {
+		current, err := s.remote.Stat(file.remotePath)
+		if err == nil && (current.Size != baseline.Size || !current.ModTime.Equal(baseline.ModTime)) {
+			return ErrEditConflict
+		}
+	}
+
+	if err := s.remote.WriteFile(file.remotePath, data); err != nil {
+		return fmt.Errorf("上传文件失败: %v", err)
+	}
+	baseline, err = s.remote.Stat(file.remotePath)
+	if err != nil {
+		return fmt.Errorf("获取文件信息失败: %v", err)
+	}
+	s.mu.Lock()
+	file.baseline = baseline
+	file.uploaded = sum
+	s.mu.Unlock()
+	return nil
+}
+
+// Close 停止监视并删除临时目录中的所有副本
+func (s *EditSession) Close() error {
+	select {
+	case <-s.done:
+		return nil
+	default:
+		close(s.done)
+	}
+	s.mu.Lock()
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.mu.Unlock()
+	s.watcher.Close()
+	s.remote.Close()
+	return os.RemoveAll(s.tempDir)
+}
+
+// loop 处理临时目录的文件事件
+func (s *EditSession) loop() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case event, ok := <-s.watcher.Events:
+			if !ok {
+				return
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			}
+			s.mu.Lock()
+			if _, tracked := s.files[event.Name]; tracked {
+				s.pending[event.Name] = true
+				if s.timer != nil {
+					s.timer.Stop()
+				}
+				s.timer = time.AfterFunc(watchDebounce, s.flush)
+			}
+			s.mu.Unlock()
+		case err, ok := <-s.watcher.Errors:
+			if !ok {
+				return
+			}
+			s.emit(EditEvent{LocalPath: s.tempDir, Err: err})
+		}
+	}
+}
+
+// flush 上传所有已保存的文件
+func (s *EditSession) flush() {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[string]bool)
+	s.mu.Unlock()
+
+	for localPath := range pending {
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+		s.mu.Lock()
+		remotePath := s.files[localPath].remotePath
+		uploaded := s.files[localPath].uploaded
+		s.mu.Unlock()
+
+		err := s.Upload(localPath, false)
+		s.mu.Lock()
+		unchanged := s.files[localPath].uploaded == uploaded
+		s.mu.Unlock()
+		if err == nil && unchanged {
+			// 内容没有变化（如只更新了修改时间），无需提示
+			continue
+		}
+		s.emit(EditEvent{LocalPath: localPath, RemotePath: remotePath, Err: err})
+	}
+}
+
+// emit 发送事件
+func (s *EditSession) emit(event EditEvent) {
+	event.Time = time.Now()
+	if s.onEvent != nil {
+		s.onEvent(event)
+	}
+}
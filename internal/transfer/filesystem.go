@@ -1,7 +1,9 @@
 package transfer
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
@@ -14,12 +16,15 @@ type FileInfo struct {
 	Size    int64
 	ModTime time.Time
 	IsDir   bool
+	Mode    os.FileMode // 权限和类型
+	Owner   string      // 所有者，远程为 uid
 }
 
 // FileSystem 文件系统接口
 type FileSystem struct {
 	currentPath string
 	remote      RemoteFS
+	filters     FilterRules
 }
 
 // RemoteFS 远程文件系统接口
@@ -27,8 +32,31 @@ type RemoteFS interface {
 	ListFiles(path string) ([]FileInfo, error)
 	CreateDirectory(path string) error
 	DeleteFile(path string) error
+	Rename(oldPath, newPath string) error
 	UploadFile(localPath, remotePath string, progress func(current, total int64)) error
 	DownloadFile(remotePath, localPath string, progress func(current, total int64)) error
+	RetryFailures(report *TransferReport, progress func(current, total int64)) error
+	SetTransferOptions(options TransferOptions)
+	GetTransferOptions() TransferOptions
+	// SetStop 设置取消信号，stop 关闭后上传和下载在当前读写完成后返回 ErrCanceled
+	SetStop(stop <-chan struct{})
+	LoadFilter(root string) (*Filter, error)
+	PlanSync(localRoot, remoteRoot string, options SyncOptions) (*SyncPlan, error)
+	ExecuteSync(plan *SyncPlan, progress func(current, total int64)) error
+	Open(path string) (io.ReadCloser, error)
+	Stat(path string) (FileInfo, error)
+	WriteFile(path string, data []byte) error
+	DiskSpace(path string) (DiskSpace, error)
+	ConnectionInfo() ConnectionInfo
+	HomeDir() (string, error)
+	Ping() (time.Duration, error)
+	Grep(root string, options GrepOptions, onMatch func(GrepMatch), stop <-chan struct{}) error
+	// TreeSize 统计目录中符合过滤规则的文件总大小
+	TreeSize(root string) (int64, error)
+	// Share 在同一连接上打开新的通道，返回独立关闭的文件系统
+	Share() (RemoteFS, error)
+	// OpenShell 在同一连接上打开交互式 shell，dir 不为空时进入该目录
+	OpenShell(cols, rows int, dir string) (*Shell, error)
 	Close() error // 修改Close方法签名
 }
 
@@ -52,6 +80,41 @@ func (fs *FileSystem) SetRemoteFS(remote RemoteFS) {
 	fs.remote = remote
 }
 
+// Detach 返回独立的文件系统：远程时在同一连接上打开新的通道，面板之后切换连接或关闭不影响它；
+// 用于编辑器等在面板之外继续使用的窗口，用完后调用 Close
+func (fs *FileSystem) Detach() (*FileSystem, error) {
+	detached := &FileSystem{currentPath: fs.currentPath, filters: fs.filters}
+	if fs.remote != nil {
+		remote, err := fs.remote.Share()
+		if err != nil {
+			return nil, err
+		}
+		detached.remote = remote
+	}
+	return detached, nil
+}
+
+// Close 关闭 Detach 打开的远程通道，本地文件系统无需关闭
+func (fs *FileSystem) Close() error {
+	if fs.remote == nil {
+		return nil
+	}
+	return fs.remote.Close()
+}
+
+// SetFilterRules 设置本地递归删除时的过滤规则
+func (fs *FileSystem) SetFilterRules(rules FilterRules) {
+	fs.filters = rules
+}
+
+// LoadFilter 加载以 root 为根目录的过滤器
+func (fs *FileSystem) LoadFilter(root string) (*Filter, error) {
+	if fs.remote != nil {
+		return fs.remote.LoadFilter(root)
+	}
+	return LoadLocalFilter(fs.filters, root)
+}
+
 // GetCurrentPath 获取当前路径
 func (fs *FileSystem) GetCurrentPath() string {
 	return fs.currentPath
@@ -62,6 +125,14 @@ func (fs *FileSystem) SetCurrentPath(path string) {
 	fs.currentPath = path
 }
 
+// Open 打开文件用于流式读取，远程文件通过SFTP读取，不会完整下载
+func (fs *FileSystem) Open(path string) (io.ReadCloser, error) {
+	if fs.remote != nil {
+		return fs.remote.Open(path)
+	}
+	return os.Open(path)
+}
+
 // ListFiles 列出目录下的文件
 func (fs *FileSystem) ListFiles(path string) ([]FileInfo, error) {
 	// 如果有远程文件系统，使用远程文件系统
@@ -87,6 +158,8 @@ func (fs *FileSystem) ListFiles(path string) ([]FileInfo, error) {
 			Size:    info.Size(),
 			ModTime: info.ModTime(),
 			IsDir:   entry.IsDir(),
+			Mode:    info.Mode(),
+			Owner:   localOwner(info),
 		})
 	}
 	return files, nil
@@ -105,5 +178,98 @@ func (fs *FileSystem) DeleteFile(path string) error {
 	if fs.remote != nil {
 		return fs.remote.DeleteFile(path)
 	}
-	return os.Remove(path)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return os.Remove(path)
+	}
+
+	filter, err := LoadLocalFilter(fs.filters, path)
+	if err != nil {
+		return err
+	}
+	if filter == nil {
+		return os.RemoveAll(path)
+	}
+	_, err = deleteLocalTree(path, "", filter)
+	return err
+}
+
+// Rename 重命名文件或目录，目标已存在时失败
+func (fs *FileSystem) Rename(oldPath, newPath string) error {
+	if fs.remote != nil {
+		return fs.remote.Rename(oldPath, newPath)
+	}
+	if _, err := os.Lstat(newPath); err == nil {
+		return fmt.Errorf("%s 已存在", newPath)
+	}
+	return os.Rename(oldPath, newPath)
+}
+
+// deleteLocalTree 按过滤规则递归删除本地目录，返回是否有内容被保留
+func deleteLocalTree(dir, rel string, filter *Filter) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+
+	kept := false
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return kept, err
+		}
+		childRel := joinRel(rel, entry.Name())
+		if !filter.Match(childRel, info.IsDir(), info.Size(), info.ModTime()) {
+			kept = true
+			continue
+		}
+
+		childPath := filepath.Join(dir, entry.Name())
+		if info.IsDir() {
+			childKept, err := deleteLocalTree(childPath, childRel, filter)
+			if err != nil {
+				return kept, err
+			}
+			if childKept {
+				kept = true
+			}
+			continue
+		}
+		if err := os.Remove(childPath); err != nil {
+			return kept, err
+		}
+	}
+
+	if kept {
+		return true, nil
+	}
+	return false, os.Remove(dir)
+}
+
+// LoadLocalFilter 按规则加载过滤器，需要时读取 root 下的 .xftpignore
+func LoadLocalFilter(rules FilterRules, root string) (*Filter, error) {
+	if rules.IsEmpty() {
+		return nil, nil
+	}
+	var data []byte
+	if rules.UseIgnoreFile {
+		var err error
+		data, err = os.ReadFile(filepath.Join(root, IgnoreFileName))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("读取%s失败: %v", IgnoreFileName, err)
+		}
+	}
+	return NewFilter(rules, data)
+}
+
+// joinRel 拼接使用/分隔的相对路径
+func joinRel(rel, name string) string {
+	if rel == "" {
+		return name
+	}
+	return rel + "/" + name
 }
@@ -0,0 +1,354 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IgnoreFileName 忽略规则文件名（gitignore 语法），只读取源目录根下的该文件
+const IgnoreFileName = ".xftpignore"
+
+// FilterRules 递归传输的包含/排除规则
+type FilterRules struct {
+	Include       []string      // 包含的通配符，只作用于文件，为空表示全部包含
+	Exclude       []string      // 排除的通配符，作用于文件和目录
+	IncludeRegex  []string      // 包含的正则表达式（匹配相对路径）
+	ExcludeRegex  []string      // 排除的正则表达式（匹配相对路径）
+	MinSize       int64         // 最小文件大小，0表示不限
+	MaxSize       int64         // 最大文件大小，0表示不限
+	MinAge        time.Duration // 最小文件年龄（距修改时间），0表示不限
+	MaxAge        time.Duration // 最大文件年龄，0表示不限
+	UseIgnoreFile bool          // 是否读取源目录下的 .xftpignore
+}
+
+// IsEmpty 是否未设置任何规则
+func (r FilterRules) IsEmpty() bool {
+	return len(r.Include) == 0 && len(r.Exclude) == 0 &&
+		len(r.IncludeRegex) == 0 && len(r.ExcludeRegex) == 0 &&
+		r.MinSize == 0 && r.MaxSize == 0 && r.MinAge == 0 && r.MaxAge == 0 &&
+		!r.UseIgnoreFile
+}
+
+// Filter 编译后的过滤器，nil 表示不过滤
+type Filter struct {
+	rules        FilterRules
+	includeRegex []*regexp.Regexp
+	excludeRegex []*regexp.Regexp
+	ignore       []ignorePattern
+	ignoreLines  []string
+	now          time.Time
+}
+
+// ignorePattern 一条 gitignore 规则
+type ignorePattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// NewFilter 编译过滤规则，ignoreData 为 .xftpignore 的内容（可为nil）
+func NewFilter(rules FilterRules, ignoreData []byte) (*Filter, error) {
+	if rules.IsEmpty() {
+		return nil, nil
+	}
+
+	f := &Filter{rules: rules, now: time.Now()}
+	for _, pattern := range rules.Include {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("无效的包含规则 %q: %v", pattern, err)
+		}
+	}
+	for _, pattern := range rules.Exclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("无效的排除规则 %q: %v", pattern, err)
+		}
+	}
+	for _, expr := range rules.IncludeRegex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("无效的包含正则 %q: %v", expr, err)
+		}
+		f.includeRegex = append(f.includeRegex, re)
+	}
+	for _, expr := range rules.ExcludeRegex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("无效的排除正则 %q: %v", expr, err)
+		}
+		f.excludeRegex = append(f.excludeRegex, re)
+	}
+
+	if rules.UseIgnoreFile && ignoreData != nil {
+		scanner := bufio.NewScanner(bytes.NewReader(ignoreData))
+		for scanner.Scan() {
+			line := scanner.Text()
+			pattern, ok := parseIgnoreLine(line)
+			if !ok {
+				continue
+			}
+			f.ignore = append(f.ignore, pattern)
+			f.ignoreLines = append(f.ignoreLines, strings.TrimSpace(line))
+		}
+	}
+	return f, nil
+}
+
+// Match 判断相对路径（使用/分隔）是否应被处理；目录不匹配时应跳过其全部内容
+func (f *Filter) Match(rel string, isDir bool, size int64, modTime time.Time) bool {
+	if f == nil || rel == "" || rel == "." {
+		return true
+	}
+	name := path.Base(rel)
+
+	// 忽略规则文件本身不参与传输
+	if name == IgnoreFileName && !strings.Contains(rel, "/") && f.rules.UseIgnoreFile {
+		return false
+	}
+
+	for _, pattern := range f.rules.Exclude {
+		if globMatch(pattern, rel, name) {
+			return false
+		}
+	}
+	for _, re := range f.excludeRegex {
+		if re.MatchString(rel) {
+			return false
+		}
+	}
+	if f.ignored(rel, isDir) {
+		return false
+	}
+
+	// 包含、大小、时间规则只作用于文件
+	if isDir {
+		return true
+	}
+	if len(f.rules.Include) > 0 || len(f.includeRegex) > 0 {
+		included := false
+		for _, pattern := range f.rules.Include {
+			if globMatch(pattern, rel, name) {
+				included = true
+				break
+			}
+		}
+		for _, re := range f.includeRegex {
+			if !included && re.MatchString(rel) {
+				included = true
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	if f.rules.MinSize > 0 && size < f.rules.MinSize {
+		return false
+	}
+	if f.rules.MaxSize > 0 && size > f.rules.MaxSize {
+		return false
+	}
+	age := f.now.Sub(modTime)
+	if f.rules.MinAge > 0 && age < f.rules.MinAge {
+		return false
+	}
+	if f.rules.MaxAge > 0 && age > f.rules.MaxAge {
+		return false
+	}
+	return true
+}
+
+// ignored 按 gitignore 语义判断，后面的规则覆盖前面的规则
+func (f *Filter) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, pattern := range f.ignore {
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+		if pattern.re.MatchString(rel) {
+			ignored = !pattern.negate
+		}
+	}
+	return ignored
+}
+
+// Describe 返回生效规则的文字说明
+func (f *Filter) Describe() string {
+	if f == nil {
+		return "未设置过滤规则"
+	}
+
+	var lines []string
+	add := func(label string, values []string) {
+		if len(values) > 0 {
+			lines = append(lines, label+"："+strings.Join(values, ", "))
+		}
+	}
+	add("包含", f.rules.Include)
+	add("排除", f.rules.Exclude)
+	add("包含正则", f.rules.IncludeRegex)
+	add("排除正则", f.rules.ExcludeRegex)
+	if f.rules.MinSize > 0 {
+		lines = append(lines, "最小大小："+FormatSize(f.rules.MinSize))
+	}
+	if f.rules.MaxSize > 0 {
+		lines = append(lines, "最大大小："+FormatSize(f.rules.MaxSize))
+	}
+	if f.rules.MinAge > 0 {
+		lines = append(lines, "修改时间早于："+f.rules.MinAge.String()+" 前")
+	}
+	if f.rules.MaxAge > 0 {
+		lines = append(lines, "修改时间晚于："+f.rules.MaxAge.String()+" 前")
+	}
+	if f.rules.UseIgnoreFile {
+		if len(f.ignoreLines) == 0 {
+			lines = append(lines, IgnoreFileName+"：无规则")
+		} else {
+			add(IgnoreFileName, f.ignoreLines)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// globMatch 不含/的模式匹配文件名，含/的模式匹配相对路径
+func globMatch(pattern, rel, name string) bool {
+	if strings.Contains(pattern, "/") {
+		ok, _ := path.Match(strings.TrimPrefix(pattern, "/"), rel)
+		return ok
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// parseIgnoreLine 解析一行 gitignore 规则
+func parseIgnoreLine(line string) (ignorePattern, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+
+	var p ignorePattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignorePattern{}, false
+	}
+
+	// 含有/的规则相对于根目录，否则匹配任意层级
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case strings.HasPrefix(line[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "/**") && i+3 == len(line):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(line[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(line[i:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := line[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		case c == '\\' && i+1 < len(line):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(line[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return ignorePattern{}, false
+	}
+	p.re = re
+	return p, true
+}
+
+// ParseSize 解析文件大小，支持 K、M、G、T 后缀，空字符串表示0
+func ParseSize(text string) (int64, error) {
+	text = strings.ToUpper(strings.TrimSpace(text))
+	text = strings.TrimSuffix(text, "B")
+	if text == "" {
+		return 0, nil
+	}
+	multiplier := int64(1)
+	if i := strings.IndexAny(text, "KMGT"); i >= 0 && i == len(text)-1 {
+		multiplier = int64(1) << (10 * (strings.IndexByte("KMGT", text[i]) + 1))
+		text = text[:i]
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("无效的大小: %s", text)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// ParseAge 解析时间长度，支持 d 表示天，空字符串表示0
+func ParseAge(text string) (time.Duration, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, nil
+	}
+	if strings.HasSuffix(text, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(text, "d"), 64)
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("无效的时间: %s", text)
+		}
+		return time.Duration(days * float64(24*time.Hour)), nil
+	}
+	age, err := time.ParseDuration(text)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("无效的时间: %s", text)
+	}
+	return age, nil
+}
+
+// FormatSize 格式化文件大小显示
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,184 @@
+package transfer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseIgnoreLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		ok      bool
+		negate  bool
+		dirOnly bool
+		match   []string
+		noMatch []string
+	}{
+		{line: "", ok: false},
+		{line: "   ", ok: false},
+		{line: "# 注释", ok: false},
+		{line: "/", ok: false},
+		{line: "*.log", ok: true, match: []string{"a.log", "dir/a.log", "a/b/c.log"}, noMatch: []string{"a.log.txt", "log"}},
+		{line: "build/", ok: true, dirOnly: true, match: []string{"build", "src/build"}, noMatch: []string{"builds"}},
+		{line: "!keep.log", ok: true, negate: true, match: []string{"keep.log", "dir/keep.log"}},
+		{line: `\!important`, ok: true, match: []string{"!important"}},
+		{line: `\#hash`, ok: true, match: []string{"#hash"}},
+		{line: "/root.txt", ok: true, match: []string{"root.txt"}, noMatch: []string{"dir/root.txt"}},
+		{line: "doc/*.md", ok: true, match: []string{"doc/a.md"}, noMatch: []string{"x/doc/a.md", "doc/sub/a.md"}},
+		{line: "**/cache", ok: true, match: []string{"cache", "a/cache", "a/b/cache"}},
+		{line: "logs/**", ok: true, match: []string{"logs/a", "logs/a/b"}, noMatch: []string{"logs"}},
+		{line: "a/**/z", ok: true, match: []string{"a/z", "a/b/z", "a/b/c/z"}, noMatch: []string{"b/a/z"}},
+		{line: "file?.txt", ok: true, match: []string{"file1.txt"}, noMatch: []string{"file10.txt", "file/.txt"}},
+		{line: "[ab].txt", ok: true, match: []string{"a.txt", "b.txt"}, noMatch: []string{"c.txt"}},
+		{line: "[!ab].txt", ok: true, match: []string{"c.txt"}, noMatch: []string{"a.txt"}},
+		{line: "trailing.txt  \r", ok: true, match: []string{"trailing.txt"}},
+		{line: "a.b", ok: true, match: []string{"a.b"}, noMatch: []string{"axb"}},
+	}
+	for _, tt := range tests {
+		p, ok := parseIgnoreLine(tt.line)
+		if ok != tt.ok {
+			t.Errorf("parseIgnoreLine(%q) ok = %v，期望 %v", tt.line, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if p.negate != tt.negate || p.dirOnly != tt.dirOnly {
+			t.Errorf("parseIgnoreLine(%q) negate=%v dirOnly=%v，期望 negate=%v dirOnly=%v",
+				tt.line, p.negate, p.dirOnly, tt.negate, tt.dirOnly)
+		}
+		for _, rel := range tt.match {
+			if !p.re.MatchString(rel) {
+				t.Errorf("规则 %q 应匹配 %q（%s）", tt.line, rel, p.re)
+			}
+		}
+		for _, rel := range tt.noMatch {
+			if p.re.MatchString(rel) {
+				t.Errorf("规则 %q 不应匹配 %q（%s）", tt.line, rel, p.re)
+			}
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	now := time.Now()
+	ignore := []byte("# 忽略日志，保留 keep.log\n*.log\n!keep.log\nbuild/\n/tmp\n")
+
+	tests := []struct {
+		name    string
+		rules   FilterRules
+		ignore  []byte
+		rel     string
+		isDir   bool
+		size    int64
+		modTime time.Time
+		want    bool
+	}{
+		{name: "无规则", rules: FilterRules{}, rel: "a.txt", want: true},
+		{name: "根目录", rules: FilterRules{Exclude: []string{"*"}}, rel: ".", isDir: true, want: true},
+		{name: "排除文件名", rules: FilterRules{Exclude: []string{"*.tmp"}}, rel: "dir/a.tmp", want: false},
+		{name: "排除目录", rules: FilterRules{Exclude: []string{"node_modules"}}, rel: "src/node_modules", isDir: true, want: false},
+		{name: "排除路径", rules: FilterRules{Exclude: []string{"/src/gen"}}, rel: "src/gen", isDir: true, want: false},
+		{name: "排除路径不匹配其他层级", rules: FilterRules{Exclude: []string{"src/gen"}}, rel: "a/src/gen", isDir: true, want: true},
+		{name: "包含不作用于目录", rules: FilterRules{Include: []string{"*.go"}}, rel: "internal", isDir: true, want: true},
+		{name: "包含匹配", rules: FilterRules{Include: []string{"*.go"}}, rel: "internal/a.go", want: true},
+		{name: "包含不匹配", rules: FilterRules{Include: []string{"*.go"}}, rel: "README.md", want: false},
+		{name: "排除优先于包含", rules: FilterRules{Include: []string{"*.go"}, Exclude: []string{"*_test.go"}}, rel: "a_test.go", want: false},
+		{name: "包含正则", rules: FilterRules{IncludeRegex: []string{`^docs/.*\.md$`}}, rel: "docs/a.md", want: true},
+		{name: "包含正则不匹配", rules: FilterRules{IncludeRegex: []string{`^docs/.*\.md$`}}, rel: "a.md", want: false},
+		{name: "通配符或正则任一包含", rules: FilterRules{Include: []string{"*.txt"}, IncludeRegex: []string{`\.md$`}}, rel: "a.md", want: true},
+		{name: "排除正则", rules: FilterRules{ExcludeRegex: []string{`(^|/)\.git(/|$)`}}, rel: ".git", isDir: true, want: false},
+		{name: "最小大小", rules: FilterRules{MinSize: 100}, rel: "a", size: 99, want: false},
+		{name: "最小大小边界", rules: FilterRules{MinSize: 100}, rel: "a", size: 100, want: true},
+		{name: "最大大小", rules: FilterRules{MaxSize: 100}, rel: "a", size: 101, want: false},
+		{name: "大小不作用于目录", rules: FilterRules{MaxSize: 1}, rel: "a", isDir: true, size: 4096, want: true},
+		{name: "最小年龄", rules: FilterRules{MinAge: time.Hour}, rel: "a", modTime: now.Add(-time.Minute), want: false},
+		{name: "最大年龄", rules: FilterRules{MaxAge: time.Hour}, rel: "a", modTime: now.Add(-2 * time.Hour), want: false},
+		{name: "年龄范围内", rules: FilterRules{MinAge: time.Minute, MaxAge: time.Hour}, rel: "a", modTime: now.Add(-10 * time.Minute), want: true},
+		{name: "忽略文件规则", rules: FilterRules{UseIgnoreFile: true}, ignore: ignore, rel: "logs/a.log", want: false},
+		{name: "忽略文件取反", rules: FilterRules{UseIgnoreFile: true}, ignore: ignore, rel: "logs/keep.log", want: true},
+		{name: "忽略文件目录规则", rules: FilterRules{UseIgnoreFile: true}, ignore: ignore, rel: "a/build", isDir: true, want: false},
+		{name: "目录规则不匹配文件", rules: FilterRules{UseIgnoreFile: true}, ignore: ignore, rel: "a/build", want: true},
+		{name: "锚定规则", rules: FilterRules{UseIgnoreFile: true}, ignore: ignore, rel: "tmp", isDir: true, want: false},
+		{name: "锚定规则不匹配子目录", rules: FilterRules{UseIgnoreFile: true}, ignore: ignore, rel: "a/tmp", isDir: true, want: true},
+		{name: "根下的忽略文件本身", rules: FilterRules{UseIgnoreFile: true}, rel: IgnoreFileName, want: false},
+		{name: "子目录中的同名文件", rules: FilterRules{UseIgnoreFile: true}, rel: "a/" + IgnoreFileName, want: true},
+		{name: "未启用忽略文件", rules: FilterRules{Exclude: []string{"*.bak"}}, ignore: ignore, rel: "a.log", want: true},
+	}
+	for _, tt := range tests {
+		f, err := NewFilter(tt.rules, tt.ignore)
+		if err != nil {
+			t.Errorf("%s: NewFilter 失败: %v", tt.name, err)
+			continue
+		}
+		modTime := tt.modTime
+		if modTime.IsZero() {
+			modTime = now
+		}
+		if got := f.Match(tt.rel, tt.isDir, tt.size, modTime); got != tt.want {
+			t.Errorf("%s: Match(%q, dir=%v) = %v，期望 %v", tt.name, tt.rel, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestNewFilterInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules FilterRules
+	}{
+		{name: "包含通配符", rules: FilterRules{Include: []string{"[a"}}},
+		{name: "排除通配符", rules: FilterRules{Exclude: []string{"[a"}}},
+		{name: "包含正则", rules: FilterRules{IncludeRegex: []string{"("}}},
+		{name: "排除正则", rules: FilterRules{ExcludeRegex: []string{"("}}},
+	}
+	for _, tt := range tests {
+		if _, err := NewFilter(tt.rules, nil); err == nil {
+			t.Errorf("%s: 无效规则应返回错误", tt.name)
+		}
+	}
+	if f, err := NewFilter(FilterRules{}, nil); f != nil || err != nil {
+		t.Errorf("空规则应返回 nil 过滤器，得到 %v, %v", f, err)
+	}
+}
+
+func TestParseSizeAndAge(t *testing.T) {
+	sizes := []struct {
+		text string
+		want int64
+		err  bool
+	}{
+		{text: "", want: 0},
+		{text: "512", want: 512},
+		{text: "1K", want: 1024},
+		{text: "1.5k", want: 1536},
+		{text: "2MB", want: 2 << 20},
+		{text: "1G", want: 1 << 30},
+		{text: "-1", err: true},
+		{text: "abc", err: true},
+	}
+	for _, tt := range sizes {
+		got, err := ParseSize(tt.text)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v，期望 %d（出错 %v）", tt.text, got, err, tt.want, tt.err)
+		}
+	}
+
+	ages := []struct {
+		text string
+		want time.Duration
+		err  bool
+	}{
+		{text: "", want: 0},
+		{text: "7d", want: 7 * 24 * time.Hour},
+		{text: "0.5d", want: 12 * time.Hour},
+		{text: "90m", want: 90 * time.Minute},
+		{text: "-1h", err: true},
+		{text: "xd", err: true},
+	}
+	for _, tt := range ages {
+		got, err := ParseAge(tt.text)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("ParseAge(%q) = %v, %v，期望 %v（出错 %v）", tt.text, got, err, tt.want, tt.err)
+		}
+	}
+}
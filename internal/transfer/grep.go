@@ -0,0 +1,264 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// grepPreviewLimit 匹配行预览的最大长度
+const grepPreviewLimit = 200
+
+// grepBinaryProbe 判断二进制文件时检查的字节数
+const grepBinaryProbe = 8000
+
+// GrepOptions 内容搜索条件
+type GrepOptions struct {
+	Pattern     string // 要搜索的内容
+	Regex       bool   // Pattern 是否为正则表达式（扩展正则）
+	IgnoreCase  bool   // 是否忽略大小写
+	Include     string // 只搜索文件名匹配该通配符的文件，空表示全部
+	MaxFileSize int64  // 回退到SFTP时跳过超过该大小的文件，0表示不限
+}
+
+// DefaultGrepMaxFileSize 回退到SFTP逐个读取文件时默认的文件大小上限
+const DefaultGrepMaxFileSize = 10 << 20
+
+// GrepMatch 一处匹配
+type GrepMatch struct {
+	Path string // 文件路径
+	Line int    // 行号，从1开始
+	Text string // 匹配行的内容（过长时截断）
+}
+
+// Grep 在远程目录下递归搜索文件内容，每找到一处调用 onMatch；
+// 服务器允许执行命令时使用 grep，否则通过SFTP逐个读取文件搜索。stop 关闭时停止搜索
+func (fs *SFTPFileSystem) Grep(root string, options GrepOptions, onMatch func(GrepMatch), stop <-chan struct{}) error {
+	if err := fs.checkOpen(); err != nil {
+		return err
+	}
+	if options.Pattern == "" {
+		return fmt.Errorf("搜索内容不能为空")
+	}
+	matcher, err := grepMatcher(options)
+	if err != nil {
+		return err
+	}
+	if options.Include != "" {
+		if _, err := path.Match(options.Include, ""); err != nil {
+			return fmt.Errorf("无效的文件名通配符 %q: %v", options.Include, err)
+		}
+	}
+
+	if fs.grepAvailable() {
+		err := fs.grepRemote(root, options, onMatch, stop)
+		if !errors.Is(err, errGrepUnavailable) {
+			return err
+		}
+	}
+	return fs.grepSFTP(root, options, matcher, onMatch, stop)
+}
+
+// errGrepUnavailable 无法在服务器上执行 grep
+var errGrepUnavailable = errors.New("服务器不支持执行 grep")
+
+// grepMatcher 将搜索条件编译为正则表达式
+func grepMatcher(options GrepOptions) (*regexp.Regexp, error) {
+	expr := options.Pattern
+	if !options.Regex {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if options.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("无效的正则表达式 %q: %v", options.Pattern, err)
+	}
+	return re, nil
+}
+
+// grepAvailable 检查服务器上的 grep 是否支持所需参数，结果会被缓存
+func (fs *SFTPFileSystem) grepAvailable() bool {
+	if fs.grepMode == "" {
+		fs.grepMode = "-"
+		if output, err := fs.runRemote("printf 'x\\n' | grep -nI --null -e x"); err == nil && len(output) > 0 {
+			fs.grepMode = "grep"
+		}
+	}
+	return fs.grepMode == "grep"
+}
+
+// grepRemote 通过SSH在服务器上执行 grep，逐行解析输出
+func (fs *SFTPFileSystem) grepRemote(root string, options GrepOptions, onMatch func(GrepMatch), stop <-chan struct{}) error {
+	session, err := fs.sshClient.NewSession()
+	if err != nil {
+		return errGrepUnavailable
+	}
+	defer session.Close()
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+
+	args := []string{"grep", "-rnI", "--null"}
+	if options.Regex {
+		args = append(args, "-E")
+	} else {
+		args = append(args, "-F")
+	}
+	if options.IgnoreCase {
+		args = append(args, "-i")
+	}
+	if options.Include != "" {
+		args = append(args, "--include="+shellQuote(options.Include))
+	}
+	args = append(args, "-e", shellQuote(options.Pattern), "--", shellQuote(root))
+	if err := session.Start(strings.Join(args, " ")); err != nil {
+		return errGrepUnavailable
+	}
+
+	// 停止时关闭会话，使读取立即结束
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			session.Close()
+		case <-done:
+		}
+	}()
+
+	found := 0
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		if match, ok := parseGrepLine(scanner.Text()); ok {
+			found++
+			onMatch(match)
+		}
+	}
+	// 超长行会使扫描提前结束，读完剩余输出避免远程进程阻塞
+	io.Copy(io.Discard, stdout)
+
+	err = session.Wait()
+	select {
+	case <-stop:
+		return nil
+	default:
+	}
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		switch {
+		case exitErr.ExitStatus() == 1:
+			// 没有匹配
+			return nil
+		case found > 0:
+			// 部分文件无法读取，已有的结果仍然有效
+			return nil
+		}
+		return fmt.Errorf("远程 grep 执行失败: %s", strings.TrimSpace(stderr.String()))
+	}
+	return err
+}
+
+// parseGrepLine 解析 grep --null 的输出："路径\0行号:内容"
+func parseGrepLine(line string) (GrepMatch, bool) {
+	file, rest, ok := strings.Cut(line, "\x00")
+	if !ok {
+		return GrepMatch{}, false
+	}
+	number, text, ok := strings.Cut(rest, ":")
+	if !ok {
+		return GrepMatch{}, false
+	}
+	lineNumber, err := strconv.Atoi(number)
+	if err != nil {
+		return GrepMatch{}, false
+	}
+	return GrepMatch{Path: file, Line: lineNumber, Text: grepPreview(text)}, true
+}
+
+// grepSFTP 通过SFTP逐个读取文件搜索，跳过二进制文件和超过大小上限的文件
+func (fs *SFTPFileSystem) grepSFTP(root string, options GrepOptions, matcher *regexp.Regexp, onMatch func(GrepMatch), stop <-chan struct{}) error {
+	maxSize := options.MaxFileSize
+	if maxSize == 0 {
+		maxSize = DefaultGrepMaxFileSize
+	}
+
+	walker := fs.sftpClient.Walk(root)
+	for walker.Step() {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		if err := walker.Err(); err != nil {
+			if walker.Path() == root {
+				return err
+			}
+			continue
+		}
+		info := walker.Stat()
+		if !info.Mode().IsRegular() || info.Size() > maxSize {
+			continue
+		}
+		if options.Include != "" {
+			if ok, _ := path.Match(options.Include, info.Name()); !ok {
+				continue
+			}
+		}
+		// 单个文件读取失败时跳过，继续搜索其余文件
+		fs.grepFile(walker.Path(), matcher, onMatch)
+	}
+	return nil
+}
+
+// grepFile 在单个远程文件中搜索
+func (fs *SFTPFileSystem) grepFile(remotePath string, matcher *regexp.Regexp, onMatch func(GrepMatch)) error {
+	file, err := fs.sftpClient.Open(remotePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	if head, _ := reader.Peek(grepBinaryProbe); bytes.IndexByte(head, 0) >= 0 {
+		return nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if matcher.MatchString(line) {
+			onMatch(GrepMatch{Path: remotePath, Line: lineNumber, Text: grepPreview(line)})
+		}
+	}
+	return scanner.Err()
+}
+
+// grepPreview 截断过长的匹配行
+func grepPreview(text string) string {
+	text = strings.TrimRight(text, "\r")
+	if len(text) <= grepPreviewLimit {
+		return text
+	}
+	cut := grepPreviewLimit
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "…"
+}
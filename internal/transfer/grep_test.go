@@ -0,0 +1,66 @@
+package transfer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseGrepLine(t *testing.T) {
+	long := strings.Repeat("中", 100)
+	tests := []struct {
+		name string
+		line string
+		want GrepMatch
+		ok   bool
+	}{
+		{name: "普通匹配", line: "/srv/a.go\x0012:func main() {", want: GrepMatch{Path: "/srv/a.go", Line: 12, Text: "func main() {"}, ok: true},
+		{name: "路径包含冒号", line: "/srv/a:b.txt\x003:x", want: GrepMatch{Path: "/srv/a:b.txt", Line: 3, Text: "x"}, ok: true},
+		{name: "内容包含冒号", line: "/a\x001:key: value", want: GrepMatch{Path: "/a", Line: 1, Text: "key: value"}, ok: true},
+		{name: "空行", line: "/a\x005:", want: GrepMatch{Path: "/a", Line: 5, Text: ""}, ok: true},
+		{name: "去掉行尾的CR", line: "/a\x002:text\r", want: GrepMatch{Path: "/a", Line: 2, Text: "text"}, ok: true},
+		{name: "截断过长的行", line: "/a\x001:" + long, want: GrepMatch{Path: "/a", Line: 1, Text: long[:198] + "…"}, ok: true},
+		{name: "缺少分隔符", line: "/a:1:text", ok: false},
+		{name: "缺少行号", line: "/a\x00text", ok: false},
+		{name: "行号无效", line: "/a\x00x:text", ok: false},
+		{name: "错误信息", line: "grep: /root: Permission denied", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := parseGrepLine(tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("%s: 得到 %+v, %v，期望 %+v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGrepMatcher(t *testing.T) {
+	tests := []struct {
+		name    string
+		options GrepOptions
+		match   []string
+		noMatch []string
+		err     bool
+	}{
+		{name: "普通文本按字面匹配", options: GrepOptions{Pattern: "a.b"}, match: []string{"xa.by"}, noMatch: []string{"axb"}},
+		{name: "区分大小写", options: GrepOptions{Pattern: "Todo"}, match: []string{"Todo"}, noMatch: []string{"TODO"}},
+		{name: "忽略大小写", options: GrepOptions{Pattern: "todo", IgnoreCase: true}, match: []string{"TODO", "ToDo"}},
+		{name: "正则表达式", options: GrepOptions{Pattern: "^func [A-Z]", Regex: true}, match: []string{"func Main"}, noMatch: []string{"func main", " func Main"}},
+		{name: "无效的正则表达式", options: GrepOptions{Pattern: "(", Regex: true}, err: true},
+	}
+	for _, tt := range tests {
+		re, err := grepMatcher(tt.options)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: 错误 %v，期望出错 %v", tt.name, err, tt.err)
+			continue
+		}
+		for _, s := range tt.match {
+			if !re.MatchString(s) {
+				t.Errorf("%s: 应匹配 %q", tt.name, s)
+			}
+		}
+		for _, s := range tt.noMatch {
+			if re.MatchString(s) {
+				t.Errorf("%s: 不应匹配 %q", tt.name, s)
+			}
+		}
+	}
+}
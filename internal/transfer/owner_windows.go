@@ -0,0 +1,10 @@
+//go:build windows
+
+package transfer
+
+import "os"
+
+// localOwner Windows 上不显示文件所有者
+func localOwner(info os.FileInfo) string {
+	return ""
+}
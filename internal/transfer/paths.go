@@ -0,0 +1,144 @@
+package transfer
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Paths 文件系统的路径规则
+type Paths interface {
+	Join(elem ...string) string
+	Dir(p string) string
+	Base(p string) string
+	Clean(p string) string
+	IsAbs(p string) bool
+	// Rel 返回 target 相对 base 的路径，使用 / 分隔
+	Rel(base, target string) (string, error)
+	// Separators 可识别的路径分隔符，第一个为规范分隔符
+	Separators() string
+}
+
+var (
+	// LocalPaths 本地路径，遵循操作系统的规则
+	LocalPaths Paths = localPaths{}
+	// RemotePaths SFTP 路径，始终使用 /；Windows 服务器上的 "/C:/dir" 或 "C:/dir" 以盘符为根目录
+	RemotePaths Paths = remotePaths{}
+)
+
+// Paths 返回当前文件系统的路径规则
+func (fs *FileSystem) Paths() Paths {
+	if fs.remote != nil {
+		return RemotePaths
+	}
+	return LocalPaths
+}
+
+// localPaths 本地路径规则
+type localPaths struct{}
+
+func (localPaths) Join(elem ...string) string { return filepath.Join(elem...) }
+func (localPaths) Dir(p string) string        { return filepath.Dir(p) }
+func (localPaths) Base(p string) string       { return filepath.Base(p) }
+func (localPaths) Clean(p string) string      { return filepath.Clean(p) }
+func (localPaths) IsAbs(p string) bool        { return filepath.IsAbs(p) }
+
+func (localPaths) Rel(base, target string) (string, error) {
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func (localPaths) Separators() string {
+	if filepath.Separator == '/' {
+		return "/"
+	}
+	return string(filepath.Separator) + "/"
+}
+
+// remotePaths POSIX 路径规则，兼容 Windows 服务器的盘符
+type remotePaths struct{}
+
+// splitVolume 拆分出开头的盘符，如 "/C:/dir" 拆分为 "/C:" 和 "/dir"；
+// 有盘符时其余部分的反斜杠转换为 /
+func splitVolume(p string) (string, string) {
+	q := strings.TrimPrefix(p, "/")
+	if len(q) < 2 || q[1] != ':' || !isDriveLetter(q[0]) || (len(q) > 2 && q[2] != '/' && q[2] != '\\') {
+		return "", p
+	}
+	volume := p[:len(p)-len(q)+2]
+	return volume, "/" + strings.ReplaceAll(q[2:], `\`, "/")
+}
+
+// isDriveLetter 是否为盘符字母
+func isDriveLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func (remotePaths) Clean(p string) string {
+	if volume, rest := splitVolume(p); volume != "" {
+		return volume + path.Clean(rest)
+	}
+	return path.Clean(p)
+}
+
+func (r remotePaths) Join(elem ...string) string {
+	joined := path.Join(elem...)
+	if joined == "" {
+		return ""
+	}
+	return r.Clean(joined)
+}
+
+// Dir 返回上级目录，末尾的 / 不算一级；盘符根目录的上级为 "/"（服务器在此列出所有盘符），没有前导 / 的盘符根目录没有上级
+func (r remotePaths) Dir(p string) string {
+	volume, rest := splitVolume(p)
+	if volume == "" {
+		return path.Dir(path.Clean(p))
+	}
+	rest = path.Clean(rest)
+	if rest == "/" {
+		if strings.HasPrefix(volume, "/") {
+			return "/"
+		}
+		return volume + "/"
+	}
+	return volume + path.Dir(rest)
+}
+
+// Base 返回最后一级名称，盘符根目录返回盘符
+func (remotePaths) Base(p string) string {
+	volume, rest := splitVolume(p)
+	if volume == "" {
+		return path.Base(p)
+	}
+	if rest = path.Clean(rest); rest == "/" {
+		return strings.TrimPrefix(volume, "/")
+	}
+	return path.Base(rest)
+}
+
+func (remotePaths) IsAbs(p string) bool {
+	volume, _ := splitVolume(p)
+	return volume != "" || path.IsAbs(p)
+}
+
+func (r remotePaths) Rel(base, target string) (string, error) {
+	base, target = r.Clean(base), r.Clean(target)
+	if base == target {
+		return ".", nil
+	}
+	prefix := base
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	if !strings.HasPrefix(target, prefix) {
+		return "", fmt.Errorf("%s 不在 %s 下", target, base)
+	}
+	return target[len(prefix):], nil
+}
+
+func (remotePaths) Separators() string { return "/" }
@@ -0,0 +1,123 @@
+package transfer
+
+import "testing"
+
+func TestRemotePathsClean(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"/home//user/../x", "/home/x"},
+		{"relative/./a", "relative/a"},
+		{"", "."},
+		{"/", "/"},
+		{"/C:", "/C:/"},
+		{"/C:/", "/C:/"},
+		{"C:", "C:/"},
+		{"C:/dir/../x", "C:/x"},
+		{`/C:\dir\sub`, "/C:/dir/sub"},
+		{"/d:/Data/", "/d:/Data"},
+		{"/C:dir", "/C:dir"},
+		{"/Cx/dir", "/Cx/dir"},
+		{"/1:/x", "/1:/x"},
+		{`/home/a\b`, `/home/a\b`},
+	}
+	for _, tt := range tests {
+		if got := RemotePaths.Clean(tt.in); got != tt.want {
+			t.Errorf("Clean(%q) = %q，期望 %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRemotePathsJoin(t *testing.T) {
+	tests := []struct {
+		elem []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"", ""}, ""},
+		{[]string{"/", "home"}, "/home"},
+		{[]string{"/home/user", "a/b"}, "/home/user/a/b"},
+		{[]string{"/a", "../b"}, "/b"},
+		{[]string{"/C:", "Users"}, "/C:/Users"},
+		{[]string{"/C:/", "Users", "x.txt"}, "/C:/Users/x.txt"},
+		{[]string{"C:", "Users"}, "C:/Users"},
+		{[]string{"/C:/Users", ".."}, "/C:/"},
+	}
+	for _, tt := range tests {
+		if got := RemotePaths.Join(tt.elem...); got != tt.want {
+			t.Errorf("Join(%q) = %q，期望 %q", tt.elem, got, tt.want)
+		}
+	}
+}
+
+func TestRemotePathsDirAndBase(t *testing.T) {
+	tests := []struct {
+		in, dir, base string
+	}{
+		{"/home/user", "/home", "user"},
+		{"/home/user/", "/home", "user"},
+		{"/", "/", "/"},
+		{"file", ".", "file"},
+		{"/C:/Users", "/C:/", "Users"},
+		{"/C:/Users/x.txt", "/C:/Users", "x.txt"},
+		{"/C:/", "/", "C:"},
+		{"/C:", "/", "C:"},
+		{"C:/", "C:/", "C:"},
+		{"C:/Users/x", "C:/Users", "x"},
+		{`/D:\Data\log`, "/D:/Data", "log"},
+	}
+	for _, tt := range tests {
+		if got := RemotePaths.Dir(tt.in); got != tt.dir {
+			t.Errorf("Dir(%q) = %q，期望 %q", tt.in, got, tt.dir)
+		}
+		if got := RemotePaths.Base(tt.in); got != tt.base {
+			t.Errorf("Base(%q) = %q，期望 %q", tt.in, got, tt.base)
+		}
+	}
+}
+
+func TestRemotePathsIsAbs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"/x", true},
+		{"/", true},
+		{"C:/x", true},
+		{"C:", true},
+		{"/C:/x", true},
+		{"x", false},
+		{"", false},
+		{"C:x", false},
+	}
+	for _, tt := range tests {
+		if got := RemotePaths.IsAbs(tt.in); got != tt.want {
+			t.Errorf("IsAbs(%q) = %v，期望 %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRemotePathsRel(t *testing.T) {
+	tests := []struct {
+		base, target string
+		want         string
+		err          bool
+	}{
+		{"/a", "/a", ".", false},
+		{"/a/", "/a", ".", false},
+		{"/a", "/a/b/c", "b/c", false},
+		{"/a/", "/a/b", "b", false},
+		{"/", "/a", "a", false},
+		{"/C:/", "/C:/x/y", "x/y", false},
+		{"/C:", "/C:/x", "x", false},
+		{"/a", "/ab", "", true},
+		{"/a/b", "/a", "", true},
+		{"/C:/", "/D:/x", "", true},
+	}
+	for _, tt := range tests {
+		got, err := RemotePaths.Rel(tt.base, tt.target)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("Rel(%q, %q) = %q, %v，期望 %q（出错 %v）", tt.base, tt.target, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package transfer
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCanceled 传输已被取消
+var ErrCanceled = errors.New("传输已取消")
+
+// stopped 检查传输是否已被取消，stop 为nil时表示不可取消
+func stopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// JobStatus 传输任务状态
+type JobStatus int
+
+const (
+	JobQueued JobStatus = iota
+	JobRunning
+	JobPaused
+	JobDone
+	JobFailed
+	JobCanceled
+)
+
+// String 返回状态名称
+func (s JobStatus) String() string {
+	switch s {
+	case JobQueued:
+		return "等待中"
+	case JobRunning:
+		return "传输中"
+	case JobPaused:
+		return "已暂停"
+	case JobDone:
+		return "已完成"
+	case JobFailed:
+		return "失败"
+	case JobCanceled:
+		return "已取消"
+	}
+	return "未知"
+}
+
+// Finished 任务是否已结束
+func (s JobStatus) Finished() bool {
+	return s == JobDone || s == JobFailed || s == JobCanceled
+}
+
+// speedInterval 计算传输速度的采样间隔
+const speedInterval = time.Second
+
+// Job 传输队列中的一个任务
+type Job struct {
+	ID          int
+	Source      string
+	Target      string
+	Size        int64 // 总大小，小于0表示未知（如目录）
+	Transferred int64
+	Speed       float64 // 字节/秒
+	Status      JobStatus
+	Err         error
+
+	run    func(stop <-chan struct{}, progress func(current, total int64)) error
+	done   func(error)
+	paused bool          // 运行中被暂停，进度回调会阻塞直到恢复
+	cancel bool          // 运行中被取消，stop 已关闭
+	stop   chan struct{} // 取消时关闭，传输在读写之间检查并返回 ErrCanceled
+
+	fileCurrent int64 // 当前文件已传输的字节数
+	fileTotal   int64 // 当前文件的大小，小于0表示还没有开始传输文件
+	speedAt     time.Time
+	speedBytes  int64
+}
+
+// ETA 预计剩余时间，无法估计时返回 -1
+func (j Job) ETA() time.Duration {
+	if j.Size < 0 || j.Speed <= 0 || j.Status != JobRunning {
+		return -1
+	}
+	remaining := j.Size - j.Transferred
+	if remaining < 0 {
+		remaining = 0
+	}
+	return time.Duration(float64(remaining) / j.Speed * float64(time.Second))
+}
+
+// TransferQueue 按顺序逐个执行传输任务的队列；运行中的任务暂停后，后面的任务可以先执行
+type TransferQueue struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	jobs     []*Job
+	nextID   int
+	active   int // 正在传输（未暂停）的任务数
+	onChange func()
+}
+
+// NewTransferQueue 创建传输队列并启动后台执行；任务状态或进度变化时调用 onChange
+func NewTransferQueue(onChange func()) *TransferQueue {
+	q := &TransferQueue{onChange: onChange}
+	q.cond = sync.NewCond(&q.mu)
+	go q.worker()
+	return q
+}
+
+// Add 添加任务。run 执行实际传输并通过 progress 报告当前文件的进度，stop 关闭后应尽快清理并返回 ErrCanceled；
+// done 在任务结束（完成、失败或取消）时调用，可以为nil
+func (q *TransferQueue) Add(source, target string, size int64, run func(stop <-chan struct{}, progress func(current, total int64)) error, done func(error)) int {
+	q.mu.Lock()
+	q.nextID++
+	job := &Job{
+		ID:     q.nextID,
+		Source: source,
+		Target: target,
+		Size:   size,
+		Status: JobQueued,
+		run:    run,
+		done:   done,
+	}
+	q.jobs = append(q.jobs, job)
+	q.cond.Broadcast()
+	q.mu.Unlock()
+	q.changed()
+	return job.ID
+}
+
+// SetSize 设置任务的总大小，用于目录任务在后台统计完成后显示大小和剩余时间；已结束的任务不再修改
+func (q *TransferQueue) SetSize(id int, size int64) {
+	q.update(id, func(job *Job) {
+		if !job.Status.Finished() {
+			job.Size = size
+		}
+	})
+}
+
+// Jobs 返回所有任务的快照
+func (q *TransferQueue) Jobs() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := make([]Job, len(q.jobs))
+	for i, job := range q.jobs {
+		jobs[i] = *job
+	}
+	return jobs
+}
+
+// Move 将任务在队列中前移（delta<0）或后移，只影响尚未开始的任务的执行顺序
+func (q *TransferQueue) Move(id, delta int) {
+	q.mu.Lock()
+	index := q.index(id)
+	target := index + delta
+	if index < 0 || target < 0 || target >= len(q.jobs) {
+		q.mu.Unlock()
+		return
+	}
+	q.jobs[index], q.jobs[target] = q.jobs[target], q.jobs[index]
+	q.mu.Unlock()
+	q.changed()
+}
+
+// Pause 暂停任务：等待中的任务不会被执行，运行中的任务在下一次报告进度时暂停，队列继续执行后面的任务
+func (q *TransferQueue) Pause(id int) {
+	q.update(id, func(job *Job) {
+		switch job.Status {
+		case JobQueued:
+			job.Status = JobPaused
+		case JobRunning:
+			job.paused = true
+			job.Status = JobPaused
+			job.Speed = 0
+			q.active--
+		}
+	})
+}
+
+// Resume 恢复已暂停的任务；运行中暂停的任务立即继续，可能与暂停期间开始的任务同时传输
+func (q *TransferQueue) Resume(id int) {
+	q.update(id, func(job *Job) {
+		if job.Status != JobPaused {
+			return
+		}
+		if job.paused {
+			job.paused = false
+			job.Status = JobRunning
+			q.active++
+			job.speedAt = time.Now()
+			job.speedBytes = job.Transferred
+		} else {
+			job.Status = JobQueued
+		}
+	})
+}
+
+// Cancel 取消任务，运行中的任务在当前读写完成后中止
+func (q *TransferQueue) Cancel(id int) {
+	var done func(error)
+	q.update(id, func(job *Job) {
+		switch {
+		case job.Status.Finished():
+		case job.Status == JobQueued || (job.Status == JobPaused && !job.paused):
+			job.Status = JobCanceled
+			job.Err = ErrCanceled
+			done = job.done
+		case !job.cancel:
+			job.cancel = true
+			close(job.stop)
+		}
+	})
+	if done != nil {
+		done(ErrCanceled)
+	}
+}
+
+// Retry 将失败或已取消的任务重新加入队列
+func (q *TransferQueue) Retry(id int) {
+	q.update(id, func(job *Job) {
+		if job.Status != JobFailed && job.Status != JobCanceled {
+			return
+		}
+		job.Status = JobQueued
+		job.Err = nil
+		job.Transferred = 0
+		job.Speed = 0
+		job.cancel = false
+		job.paused = false
+	})
+}
+
+// ClearFinished 移除所有已完成的任务，失败和已取消的任务保留以便重试
+func (q *TransferQueue) ClearFinished() {
+	q.mu.Lock()
+	jobs := q.jobs[:0]
+	for _, job := range q.jobs {
+		if job.Status != JobDone {
+			jobs = append(jobs, job)
+		}
+	}
+	for i := len(jobs); i < len(q.jobs); i++ {
+		q.jobs[i] = nil
+	}
+	q.jobs = jobs
+	q.mu.Unlock()
+	q.changed()
+}
+
+// index 返回任务在队列中的位置，调用时需持有锁
+func (q *TransferQueue) index(id int) int {
+	for i, job := range q.jobs {
+		if job.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// update 修改任务状态并唤醒等待中的执行和进度回调
+func (q *TransferQueue) update(id int, fn func(job *Job)) {
+	q.mu.Lock()
+	if index := q.index(id); index >= 0 {
+		fn(q.jobs[index])
+		q.cond.Broadcast()
+	}
+	q.mu.Unlock()
+	q.changed()
+}
+
+// changed 通知任务变化
+func (q *TransferQueue) changed() {
+	if q.onChange != nil {
+		q.onChange()
+	}
+}
+
+// worker 没有正在传输的任务时启动下一个等待中的任务
+func (q *TransferQueue) worker() {
+	for {
+		q.mu.Lock()
+		var job *Job
+		for job == nil {
+			if q.active == 0 {
+				for _, candidate := range q.jobs {
+					if candidate.Status == JobQueued {
+						job = candidate
+						break
+					}
+				}
+			}
+			if job == nil {
+				q.cond.Wait()
+			}
+		}
+		job.Status = JobRunning
+		job.stop = make(chan struct{})
+		job.fileCurrent = 0
+		job.fileTotal = -1
+		job.speedAt = time.Now()
+		job.speedBytes = 0
+		q.active++
+		q.mu.Unlock()
+		q.changed()
+
+		go q.execute(job)
+	}
+}
+
+// execute 执行任务并记录结果
+func (q *TransferQueue) execute(job *Job) {
+	err := q.runJob(job)
+
+	q.mu.Lock()
+	if !job.paused {
+		q.active--
+		q.cond.Broadcast()
+	}
+	switch {
+	case job.cancel:
+		job.Status = JobCanceled
+		err = ErrCanceled
+	case err != nil:
+		job.Status = JobFailed
+	default:
+		job.Status = JobDone
+		if job.Size >= 0 {
+			job.Transferred = job.Size
+		}
+	}
+	job.Err = err
+	job.Speed = 0
+	job.paused = false
+	done := job.done
+	q.mu.Unlock()
+	q.changed()
+	if done != nil {
+		done(err)
+	}
+}
+
+// runJob 执行任务，进度回调在暂停时阻塞；取消时关闭 stop，由传输自行清理后返回
+func (q *TransferQueue) runJob(job *Job) error {
+	return job.run(job.stop, func(current, total int64) {
+		q.mu.Lock()
+		// 大小改变、进度回退或上一个文件已传完时，开始传输下一个文件
+		if total != job.fileTotal || current < job.fileCurrent || job.fileCurrent == job.fileTotal {
+			job.fileCurrent = 0
+			job.fileTotal = total
+		}
+		job.Transferred += current - job.fileCurrent
+		job.fileCurrent = current
+		if now := time.Now(); now.Sub(job.speedAt) >= speedInterval {
+			job.Speed = float64(job.Transferred-job.speedBytes) / now.Sub(job.speedAt).Seconds()
+			job.speedAt = now
+			job.speedBytes = job.Transferred
+		}
+		for job.paused && !job.cancel {
+			q.cond.Wait()
+		}
+		q.mu.Unlock()
+		q.changed()
+	})
+}
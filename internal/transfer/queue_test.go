@@ -0,0 +1,262 @@
+package transfer
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitJob 等待任务进入指定状态
+func waitJob(t *testing.T, q *TransferQueue, id int, status JobStatus) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		for _, job := range q.Jobs() {
+			if job.ID == id && job.Status == status {
+				return job
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("任务 %d 没有进入状态 %s，当前为 %+v", id, status, q.Jobs())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTransferQueueControls(t *testing.T) {
+	tests := []struct {
+		name  string
+		fail  bool // 第一次执行返回错误
+		steps []string
+		want  JobStatus
+		runs  int
+		done  []error
+	}{
+		{
+			name:  "暂停和恢复等待中的任务",
+			steps: []string{"pause", "=已暂停", "release", "sleep", "=已暂停", "resume", "=传输中", "proceed"},
+			want:  JobDone, runs: 1, done: []error{nil},
+		},
+		{
+			name:  "取消等待中的任务",
+			steps: []string{"cancel", "=已取消", "release", "sleep"},
+			want:  JobCanceled, runs: 0, done: []error{ErrCanceled},
+		},
+		{
+			name:  "暂停运行中的任务",
+			steps: []string{"release", "=传输中", "pause", "proceed", "sleep", "=已暂停", "resume"},
+			want:  JobDone, runs: 1, done: []error{nil},
+		},
+		{
+			name:  "取消运行中的任务",
+			steps: []string{"release", "=传输中", "cancel"},
+			want:  JobCanceled, runs: 1, done: []error{ErrCanceled},
+		},
+		{
+			name:  "取消已暂停的运行中任务",
+			steps: []string{"release", "=传输中", "pause", "proceed", "sleep", "cancel"},
+			want:  JobCanceled, runs: 1, done: []error{ErrCanceled},
+		},
+		{
+			name:  "重试失败的任务",
+			fail:  true,
+			steps: []string{"release", "proceed", "=失败", "retry", "=传输中", "proceed"},
+			want:  JobDone, runs: 2, done: []error{errTestFailed, nil},
+		},
+		{
+			name:  "重试已取消的任务",
+			steps: []string{"cancel", "=已取消", "release", "retry", "=传输中", "proceed"},
+			want:  JobDone, runs: 1, done: []error{ErrCanceled, nil},
+		},
+		{
+			name:  "已完成的任务不能重试或取消",
+			steps: []string{"release", "proceed", "=已完成", "retry", "cancel", "sleep"},
+			want:  JobDone, runs: 1, done: []error{nil},
+		},
+	}
+	statuses := map[string]JobStatus{}
+	for s := JobQueued; s <= JobCanceled; s++ {
+		statuses[s.String()] = s
+	}
+
+	for _, tt := range tests {
+		q := NewTransferQueue(nil)
+		// 第一个任务占住队列，直到 release
+		hold := make(chan struct{})
+		q.Add("hold", "hold", 0, func(stop <-chan struct{}, progress func(current, total int64)) error {
+			<-hold
+			return nil
+		}, nil)
+
+		var mu sync.Mutex
+		runs := 0
+		var done []error
+		proceed := make(chan struct{}, 10)
+		id := q.Add("src", "dst", -1, func(stop <-chan struct{}, progress func(current, total int64)) error {
+			mu.Lock()
+			runs++
+			run := runs
+			mu.Unlock()
+			select {
+			case <-proceed:
+			case <-stop:
+				return ErrCanceled
+			}
+			progress(1, 2)
+			if stopped(stop) {
+				return ErrCanceled
+			}
+			progress(2, 2)
+			if tt.fail && run == 1 {
+				return errTestFailed
+			}
+			return nil
+		}, func(err error) {
+			mu.Lock()
+			done = append(done, err)
+			mu.Unlock()
+		})
+
+		for _, step := range tt.steps {
+			switch step {
+			case "pause":
+				q.Pause(id)
+			case "resume":
+				q.Resume(id)
+			case "cancel":
+				q.Cancel(id)
+			case "retry":
+				q.Retry(id)
+			case "release":
+				close(hold)
+			case "proceed":
+				proceed <- struct{}{}
+			case "sleep":
+				time.Sleep(20 * time.Millisecond)
+			default:
+				waitJob(t, q, id, statuses[step[1:]])
+			}
+		}
+		waitJob(t, q, id, tt.want)
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		if runs != tt.runs || !reflect.DeepEqual(done, tt.done) {
+			t.Errorf("%s: 执行 %d 次，结束回调 %v，期望执行 %d 次，结束回调 %v", tt.name, runs, done, tt.runs, tt.done)
+		}
+		mu.Unlock()
+	}
+}
+
+// errTestFailed 测试中任务返回的错误
+var errTestFailed = errors.New("测试失败")
+
+func TestTransferQueueProgress(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress [][2]int64 // 依次报告的 current, total
+		want     int64
+	}{
+		{name: "单个文件", progress: [][2]int64{{10, 100}, {60, 100}, {100, 100}}, want: 100},
+		{name: "多个文件", progress: [][2]int64{{50, 50}, {10, 20}, {20, 20}}, want: 70},
+		{name: "空文件", progress: [][2]int64{{5, 5}, {0, 0}, {3, 3}}, want: 8},
+		{name: "小文件之后的大文件", progress: [][2]int64{{100, 100}, {32768, 40000}, {40000, 40000}}, want: 40100},
+		{name: "大小相同的文件", progress: [][2]int64{{10, 10}, {10, 10}, {4, 10}, {10, 10}}, want: 30},
+	}
+	for _, tt := range tests {
+		q := NewTransferQueue(nil)
+		id := q.Add("src", "dst", -1, func(stop <-chan struct{}, progress func(current, total int64)) error {
+			for _, p := range tt.progress {
+				progress(p[0], p[1])
+			}
+			return nil
+		}, nil)
+		if job := waitJob(t, q, id, JobDone); job.Transferred != tt.want {
+			t.Errorf("%s: 已传输 %d，期望 %d", tt.name, job.Transferred, tt.want)
+		}
+	}
+}
+
+func TestTransferQueuePausedJobDoesNotBlock(t *testing.T) {
+	q := NewTransferQueue(nil)
+	started := make(chan struct{})
+	proceed := make(chan struct{})
+	first := q.Add("first", "first", 2, func(stop <-chan struct{}, progress func(current, total int64)) error {
+		close(started)
+		<-proceed
+		progress(1, 2)
+		progress(2, 2)
+		return nil
+	}, nil)
+	second := q.Add("second", "second", 0, func(stop <-chan struct{}, progress func(current, total int64)) error {
+		return nil
+	}, nil)
+
+	// 第一个任务开始后暂停，第二个任务应当照常执行
+	<-started
+	q.Pause(first)
+	close(proceed)
+	waitJob(t, q, second, JobDone)
+	if job := waitJob(t, q, first, JobPaused); job.Transferred != 1 {
+		t.Errorf("暂停的任务已传输 %d，期望 1", job.Transferred)
+	}
+	q.Resume(first)
+	waitJob(t, q, first, JobDone)
+}
+
+func TestTransferQueueSetSize(t *testing.T) {
+	q := NewTransferQueue(nil)
+	hold := make(chan struct{})
+	id := q.Add("dir", "dir", -1, func(stop <-chan struct{}, progress func(current, total int64)) error {
+		<-hold
+		return nil
+	}, nil)
+	q.SetSize(id, 300)
+	close(hold)
+	if job := waitJob(t, q, id, JobDone); job.Size != 300 || job.Transferred != 300 {
+		t.Errorf("大小为 %d，已传输 %d，期望都为 300", job.Size, job.Transferred)
+	}
+	q.SetSize(id, 10)
+	if job := waitJob(t, q, id, JobDone); job.Size != 300 {
+		t.Errorf("已结束的任务大小被修改为 %d", job.Size)
+	}
+}
+
+func TestTransferQueueMoveAndClear(t *testing.T) {
+	q := NewTransferQueue(nil)
+	hold := make(chan struct{})
+	q.Add("hold", "hold", 0, func(stop <-chan struct{}, progress func(current, total int64)) error {
+		<-hold
+		return nil
+	}, nil)
+	var mu sync.Mutex
+	var order []string
+	add := func(name string) int {
+		return q.Add(name, name, 0, func(stop <-chan struct{}, progress func(current, total int64)) error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil
+		}, nil)
+	}
+	add("a")
+	add("b")
+	c := add("c")
+	q.Move(c, -1)
+	q.Move(c, -1)
+	close(hold)
+	waitJob(t, q, c, JobDone)
+	for _, job := range q.Jobs() {
+		waitJob(t, q, job.ID, JobDone)
+	}
+	mu.Lock()
+	if want := []string{"c", "a", "b"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("执行顺序为 %v，期望 %v", order, want)
+	}
+	mu.Unlock()
+	q.ClearFinished()
+	if jobs := q.Jobs(); len(jobs) != 0 {
+		t.Errorf("清除后还剩 %d 个任务", len(jobs))
+	}
+}
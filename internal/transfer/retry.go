@@ -0,0 +1,174 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// RetryPolicy 重试策略（指数退避 + 随机抖动）
+type RetryPolicy struct {
+	MaxAttempts int           // 最大尝试次数（包含第一次），<=1 表示不重试
+	BaseDelay   time.Duration // 第一次重试前的等待时间
+	MaxDelay    time.Duration // 单次等待的上限
+	Jitter      float64       // 抖动比例，0~1
+}
+
+// DefaultRetryPolicy 返回默认重试策略
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+		Jitter:      0.2,
+	}
+}
+
+// Delay 计算第 attempt 次重试（从1开始）前的等待时间
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	if attempt < 1 || p.BaseDelay <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			delay = p.MaxDelay
+			break
+		}
+	}
+	if p.Jitter > 0 {
+		// 在 [-jitter, +jitter] 范围内随机浮动
+		delta := (rand.Float64()*2 - 1) * p.Jitter * float64(delay)
+		delay += time.Duration(delta)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return delay
+}
+
+// Do 按策略执行 fn，仅对可重试的错误进行重试
+func (p RetryPolicy) Do(fn func() error) error {
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if !IsRetryable(err) || attempt == attempts {
+			break
+		}
+		time.Sleep(p.Delay(attempt))
+	}
+	return err
+}
+
+// IsRetryable 判断错误是否为可重试的临时错误
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// 明确不可重试的错误：文件不存在、权限不足、已取消、重新连接失败等
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrPermission) ||
+		errors.Is(err, ErrCanceled) || errors.Is(err, ErrClosed) ||
+		errors.Is(err, sftp.ErrSSHFxNoSuchFile) || errors.Is(err, sftp.ErrSSHFxPermissionDenied) ||
+		errors.Is(err, sftp.ErrSSHFxOpUnsupported) {
+		return false
+	}
+
+	// 连接中断、超时等网络错误；SSH_FX_FAILURE 是通用的失败码（如磁盘已满、目录非空），不重试
+	if errors.Is(err, sftp.ErrSSHFxConnectionLost) || errors.Is(err, sftp.ErrSSHFxNoConnection) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ETIMEDOUT) {
+		return true
+	}
+	// 其他网络错误（如域名解析失败、连接被拒绝）重试也不会成功，只重试超时
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	// 部分连接错误只以文本形式出现
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "connection reset") ||
+		strings.Contains(msg, "broken pipe") ||
+		strings.Contains(msg, "connection lost")
+}
+
+// TransferDirection 传输方向
+type TransferDirection int
+
+const (
+	Upload TransferDirection = iota
+	Download
+)
+
+// TransferOptions 目录传输选项
+type TransferOptions struct {
+	Retry           RetryPolicy // 单个文件的重试策略
+	ContinueOnError bool        // 出错时是否继续传输剩余文件
+	Filters         FilterRules // 递归传输、删除、同步时的过滤规则
+	Delta           bool        // 上传时对已存在的文件使用增量传输
+	TarStream       bool        // 目录传输时通过SSH执行 tar 流式打包，适合大量小文件
+}
+
+// DefaultTransferOptions 返回默认传输选项
+func DefaultTransferOptions() TransferOptions {
+	return TransferOptions{
+		Retry: DefaultRetryPolicy(),
+	}
+}
+
+// TransferFailure 单个失败的传输项
+type TransferFailure struct {
+	Source    string            // 源路径
+	Target    string            // 目标路径
+	Direction TransferDirection // 传输方向
+	Err       error             // 失败原因
+}
+
+// TransferReport 目录传输报告
+type TransferReport struct {
+	Succeeded int               // 成功的文件数
+	Failures  []TransferFailure // 失败的文件列表
+}
+
+// HasFailures 是否存在失败项
+func (r *TransferReport) HasFailures() bool {
+	return r != nil && len(r.Failures) > 0
+}
+
+// addFailure 记录失败项
+func (r *TransferReport) addFailure(source, target string, direction TransferDirection, err error) {
+	r.Failures = append(r.Failures, TransferFailure{
+		Source:    source,
+		Target:    target,
+		Direction: direction,
+		Err:       err,
+	})
+}
+
+// Error 实现error接口，便于作为错误返回
+func (r *TransferReport) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d 个文件传输失败（成功 %d 个）", len(r.Failures), r.Succeeded)
+	for _, f := range r.Failures {
+		fmt.Fprintf(&b, "\n%s: %v", f.Source, f.Err)
+	}
+	return b.String()
+}
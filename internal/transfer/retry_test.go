@@ -0,0 +1,48 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// timeoutError 模拟 net.Error，可以指定是否为超时
+type timeoutError bool
+
+func (e timeoutError) Error() string   { return "网络错误" }
+func (e timeoutError) Timeout() bool   { return bool(e) }
+func (e timeoutError) Temporary() bool { return bool(e) }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"无错误", nil, false},
+		{"文件不存在", os.ErrNotExist, false},
+		{"权限不足", fmt.Errorf("打开文件失败: %w", sftp.ErrSSHFxPermissionDenied), false},
+		{"已取消", ErrCanceled, false},
+		{"重新连接失败", fmt.Errorf("%w: %v", ErrClosed, sftp.ErrSSHFxConnectionLost), false},
+		{"连接中断", sftp.ErrSSHFxConnectionLost, true},
+		{"意外EOF", fmt.Errorf("读取失败: %w", io.ErrUnexpectedEOF), true},
+		{"连接被重置", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, true},
+		{"网络超时", timeoutError(true), true},
+		{"连接被拒绝", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, false},
+		{"域名解析失败", &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}, false},
+		{"非超时的网络错误", timeoutError(false), false},
+		{"只有文本的连接错误", errors.New("write: broken pipe"), true},
+		{"普通失败", sftp.ErrSSHFxFailure, false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("%s: 得到 %v，期望 %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package transfer
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SearchType 搜索的条目类型
+type SearchType int
+
+const (
+	SearchAnyType SearchType = iota
+	SearchFileType
+	SearchDirType
+)
+
+// SearchOptions 递归搜索条件，零值表示不限
+type SearchOptions struct {
+	Pattern string     // 名称通配符或正则表达式，空表示全部
+	Regex   bool       // Pattern 是否为正则表达式
+	Type    SearchType // 条目类型
+	MinSize int64      // 最小文件大小
+	MaxSize int64      // 最大文件大小
+	After   time.Time  // 修改时间不早于
+	Before  time.Time  // 修改时间不晚于
+}
+
+// searchMatcher 编译后的搜索条件
+type searchMatcher struct {
+	options SearchOptions
+	glob    string
+	re      *regexp.Regexp
+}
+
+// newSearchMatcher 编译搜索条件，通配符不区分大小写
+func newSearchMatcher(options SearchOptions) (*searchMatcher, error) {
+	m := &searchMatcher{options: options}
+	if options.Regex {
+		re, err := regexp.Compile(options.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("无效的正则表达式 %q: %v", options.Pattern, err)
+		}
+		m.re = re
+	} else if options.Pattern != "" {
+		m.glob = strings.ToLower(options.Pattern)
+		// 不含通配符时按包含匹配
+		if !strings.ContainsAny(m.glob, "*?[") {
+			m.glob = "*" + m.glob + "*"
+		}
+		if _, err := path.Match(m.glob, ""); err != nil {
+			return nil, fmt.Errorf("无效的通配符 %q: %v", options.Pattern, err)
+		}
+	}
+	return m, nil
+}
+
+// match 判断文件是否满足搜索条件
+func (m *searchMatcher) match(file FileInfo) bool {
+	o := m.options
+	switch o.Type {
+	case SearchFileType:
+		if file.IsDir {
+			return false
+		}
+	case SearchDirType:
+		if !file.IsDir {
+			return false
+		}
+	}
+	if m.re != nil && !m.re.MatchString(file.Name) {
+		return false
+	}
+	if m.glob != "" {
+		if ok, _ := path.Match(m.glob, strings.ToLower(file.Name)); !ok {
+			return false
+		}
+	}
+	// 设置了大小条件时只匹配文件
+	if (o.MinSize > 0 || o.MaxSize > 0) && file.IsDir {
+		return false
+	}
+	if o.MinSize > 0 && file.Size < o.MinSize {
+		return false
+	}
+	if o.MaxSize > 0 && file.Size > o.MaxSize {
+		return false
+	}
+	if !o.After.IsZero() && file.ModTime.Before(o.After) {
+		return false
+	}
+	if !o.Before.IsZero() && file.ModTime.After(o.Before) {
+		return false
+	}
+	return true
+}
+
+// SearchFiles 递归搜索 root 下满足条件的条目，每找到一个调用 onMatch；
+// stop 关闭时停止搜索。无法读取的子目录会被跳过并计入返回的跳过数
+func SearchFiles(fs *FileSystem, root string, options SearchOptions, onMatch func(FileInfo), stop <-chan struct{}) (int, error) {
+	matcher, err := newSearchMatcher(options)
+	if err != nil {
+		return 0, err
+	}
+
+	skipped := 0
+	pending := []string{root}
+	for len(pending) > 0 {
+		select {
+		case <-stop:
+			return skipped, nil
+		default:
+		}
+
+		dir := pending[0]
+		pending = pending[1:]
+		files, err := fs.ListFiles(dir)
+		if err != nil {
+			if dir == root {
+				return 0, err
+			}
+			skipped++
+			continue
+		}
+		for _, file := range files {
+			if matcher.match(file) {
+				onMatch(file)
+			}
+			if file.IsDir {
+				pending = append(pending, file.Path)
+			}
+		}
+	}
+	return skipped, nil
+}
@@ -0,0 +1,200 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sshCiphers 客户端提供的加密算法，顺序与 x/crypto/ssh 的默认偏好一致；
+// 显式指定是为了能按同样的规则推算出协商结果
+var sshCiphers = []string{
+	"aes128-gcm@openssh.com", "aes256-gcm@openssh.com",
+	"chacha20-poly1305@openssh.com",
+	"aes128-ctr", "aes192-ctr", "aes256-ctr",
+}
+
+// kexInitLimit 查找服务器 KEXINIT 时最多检查的字节数
+const kexInitLimit = 64 << 10
+
+// ConnectionInfo 远程连接的标识
+type ConnectionInfo struct {
+	User   string
+	Host   string
+	Port   int
+	Cipher string // 协商的加密算法，未知时为空
+}
+
+// String 返回 user@host:port 形式的连接标识
+func (c ConnectionInfo) String() string {
+	return fmt.Sprintf("%s@%s:%d", c.User, c.Host, c.Port)
+}
+
+// DiskSpace 文件系统的空间信息
+type DiskSpace struct {
+	Total int64 // 总空间
+	Free  int64 // 当前用户可用的空间
+}
+
+// ConnectionInfo 返回连接标识和协商的加密算法
+func (fs *SFTPFileSystem) ConnectionInfo() ConnectionInfo {
+	return ConnectionInfo{
+		User:   fs.config.Username,
+		Host:   fs.config.Host,
+		Port:   fs.config.Port,
+		Cipher: fs.cipher,
+	}
+}
+
+// Ping 发送一次 keepalive 请求，返回往返时间
+func (fs *SFTPFileSystem) Ping() (time.Duration, error) {
+	if err := fs.checkOpen(); err != nil {
+		return 0, err
+	}
+	started := time.Now()
+	// 服务器不认识该请求时也会回复失败，同样可以测量往返时间
+	if _, _, err := fs.sshClient.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+		return 0, err
+	}
+	return time.Since(started), nil
+}
+
+// HomeDir 返回登录用户的主目录，即 SFTP 会话的初始工作目录
+func (fs *SFTPFileSystem) HomeDir() (string, error) {
+	if err := fs.checkOpen(); err != nil {
+		return "", err
+	}
+	dir, err := fs.sftpClient.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("获取远程主目录失败: %v", err)
+	}
+	return dir, nil
+}
+
+// HomeDir 返回当前用户的主目录
+func (fs *FileSystem) HomeDir() (string, error) {
+	if fs.remote != nil {
+		return fs.remote.HomeDir()
+	}
+	return os.UserHomeDir()
+}
+
+// DiskSpace 通过 statvfs@openssh.com 扩展获取远程文件系统的空间
+func (fs *SFTPFileSystem) DiskSpace(path string) (DiskSpace, error) {
+	if err := fs.checkOpen(); err != nil {
+		return DiskSpace{}, err
+	}
+	stat, err := fs.sftpClient.StatVFS(path)
+	if err != nil {
+		return DiskSpace{}, fmt.Errorf("获取磁盘空间失败: %v", err)
+	}
+	return DiskSpace{
+		Total: int64(stat.Blocks * stat.Frsize),
+		Free:  int64(stat.Bavail * stat.Frsize),
+	}, nil
+}
+
+// DiskSpace 获取路径所在文件系统的空间
+func (fs *FileSystem) DiskSpace(path string) (DiskSpace, error) {
+	if fs.remote != nil {
+		return fs.remote.DiskSpace(path)
+	}
+	return localDiskSpace(path)
+}
+
+// kexSniffer 记录服务器发来的前几个数据包，用于解析明文的 KEXINIT 消息
+type kexSniffer struct {
+	net.Conn
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	ciphers []string // 服务器支持的客户端到服务器方向的加密算法
+	done    bool
+}
+
+// Read 读取数据并在找到 KEXINIT 前保留一份副本
+func (s *kexSniffer) Read(p []byte) (int, error) {
+	n, err := s.Conn.Read(p)
+	s.mu.Lock()
+	if !s.done && n > 0 {
+		s.buf.Write(p[:n])
+		if ciphers, ok := parseServerKexInit(s.buf.Bytes()); ok || s.buf.Len() > kexInitLimit {
+			s.ciphers = ciphers
+			s.done = true
+			s.buf = bytes.Buffer{}
+		}
+	}
+	s.mu.Unlock()
+	return n, err
+}
+
+// negotiatedCipher 按客户端偏好找出双方都支持的第一个加密算法
+func (s *kexSniffer) negotiatedCipher() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, client := range sshCiphers {
+		for _, server := range s.ciphers {
+			if client == server {
+				return client
+			}
+		}
+	}
+	return ""
+}
+
+// parseServerKexInit 从服务器输出的开头解析 KEXINIT，返回客户端到服务器方向的加密算法列表；
+// 数据不完整时返回false
+func parseServerKexInit(data []byte) ([]string, bool) {
+	// 跳过版本行及其之前的欢迎信息
+	for {
+		end := bytes.Index(data, []byte("\r\n"))
+		if end < 0 {
+			return nil, false
+		}
+		line := data[:end]
+		data = data[end+2:]
+		if bytes.HasPrefix(line, []byte("SSH-")) {
+			break
+		}
+	}
+
+	// 二进制包：长度(4) 填充长度(1) 消息类型(1)=20 cookie(16) 然后是 name-list
+	if len(data) < 5 {
+		return nil, false
+	}
+	length := binary.BigEndian.Uint32(data)
+	if length < 17 {
+		return nil, true
+	}
+	if uint64(len(data)) < 4+uint64(length) {
+		return nil, false
+	}
+	// 去掉末尾的填充
+	padding := uint64(data[4])
+	if padding > uint64(length)-1 {
+		return nil, true
+	}
+	payload := data[5 : 4+uint64(length)-padding]
+	if len(payload) < 17 || payload[0] != 20 {
+		return nil, true
+	}
+	payload = payload[17:]
+	// 依次为密钥交换、主机密钥、客户端到服务器的加密算法
+	var lists []string
+	for i := 0; i < 3; i++ {
+		if len(payload) < 4 {
+			return nil, true
+		}
+		size := uint64(binary.BigEndian.Uint32(payload))
+		if uint64(len(payload)) < 4+size {
+			return nil, true
+		}
+		lists = append(lists, string(payload[4:4+size]))
+		payload = payload[4+size:]
+	}
+	return strings.Split(lists[2], ","), true
+}
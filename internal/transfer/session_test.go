@@ -0,0 +1,81 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// nameList 编码 SSH 的 name-list
+func nameList(names string) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(names)))
+	return append(b, names...)
+}
+
+// kexInitPacket 构造服务器发送的版本行和 KEXINIT 二进制包，length 为0时按实际长度填写
+func kexInitPacket(msgType byte, length uint32, lists ...[]byte) []byte {
+	payload := append([]byte{msgType}, make([]byte, 16)...)
+	for _, list := range lists {
+		payload = append(payload, list...)
+	}
+	padding := make([]byte, 4)
+	body := append(append([]byte{byte(len(padding))}, payload...), padding...)
+	if length == 0 {
+		length = uint32(len(body))
+	}
+	packet := []byte("SSH-2.0-OpenSSH_9.6\r\n")
+	packet = binary.BigEndian.AppendUint32(packet, length)
+	return append(packet, body...)
+}
+
+func TestParseServerKexInit(t *testing.T) {
+	kex := nameList("curve25519-sha256")
+	hostKey := nameList("ssh-ed25519")
+	ciphers := nameList("aes128-ctr,aes256-gcm@openssh.com")
+	valid := kexInitPacket(20, 0, kex, hostKey, ciphers, ciphers)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		ciphers []string
+		done    bool
+	}{
+		{name: "完整的 KEXINIT", data: valid, ciphers: []string{"aes128-ctr", "aes256-gcm@openssh.com"}, done: true},
+		{name: "版本行之前有欢迎信息", data: append([]byte("welcome\r\n"), valid...), ciphers: []string{"aes128-ctr", "aes256-gcm@openssh.com"}, done: true},
+		{name: "没有版本行", data: []byte("SSH-2.0-OpenSSH"), done: false},
+		{name: "长度不完整", data: []byte("SSH-2.0-x\r\n\x00\x00"), done: false},
+		{name: "数据包不完整", data: valid[:len(valid)-3], done: false},
+		{name: "长度为0", data: []byte("SSH-2.0-x\r\n\x00\x00\x00\x00\x04"), done: true},
+		{name: "长度小于 KEXINIT 头部", data: append([]byte("SSH-2.0-x\r\n\x00\x00\x00\x05\x04"), make([]byte, 8)...), done: true},
+		{name: "长度为最大值", data: []byte("SSH-2.0-x\r\n\xff\xff\xff\xff\x04\x14"), done: false},
+		{name: "不是 KEXINIT", data: kexInitPacket(21, 0, kex, hostKey, ciphers), done: true},
+		{name: "缺少加密算法列表", data: kexInitPacket(20, 0, kex, hostKey), done: true},
+		{name: "列表长度超出数据包", data: kexInitPacket(20, 0, kex, []byte{0, 0, 1, 0, 'x'}), done: true},
+		{name: "name-list 长度为最大值", data: kexInitPacket(20, 0, []byte{0xff, 0xff, 0xff, 0xff}), done: true},
+		{name: "列表长度不完整", data: kexInitPacket(20, 0, kex, hostKey, []byte{0, 0}), done: true},
+	}
+	for _, tt := range tests {
+		got, done := parseServerKexInit(tt.data)
+		if done != tt.done || !reflect.DeepEqual(got, tt.ciphers) {
+			t.Errorf("%s: 得到 %q, %v，期望 %q, %v", tt.name, got, done, tt.ciphers, tt.done)
+		}
+	}
+}
+
+func TestParseServerKexInitTruncated(t *testing.T) {
+	// 任意截断的数据都不应导致越界
+	valid := kexInitPacket(20, 0, nameList("kex"), nameList("key"), nameList("aes128-ctr"))
+	for i := 0; i <= len(valid); i++ {
+		ciphers, done := parseServerKexInit(valid[:i])
+		if i < len(valid) && (done || ciphers != nil) {
+			t.Errorf("截断到 %d 字节时得到 %q, %v，期望继续等待", i, ciphers, done)
+		}
+	}
+	// 长度字段声明的长度大于实际的列表
+	for n := uint32(1); n < 64; n++ {
+		data := kexInitPacket(20, 0, nameList("kex"), []byte{0, 0, 0, byte(n)})
+		data = append(data, bytes.Repeat([]byte{'x'}, int(n%7))...)
+		parseServerKexInit(data)
+	}
+}
@@ -1,10 +1,12 @@
 package transfer
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
@@ -12,73 +14,155 @@ import (
 
 // SFTPConfig SFTP配置
 type SFTPConfig struct {
-	Host     string
-	Port     int
-	Username string
-	Password string
+	Name       string // 连接名称，为空时使用 user@host
+	Host       string
+	Port       int
+	Username   string
+	Password   string
+	InitialDir string // 连接后打开的目录，可以是相对主目录的路径，为空时打开主目录
 }
 
 // SFTPFileSystem SFTP文件系统实现
 type SFTPFileSystem struct {
-	config     *SFTPConfig
-	sshClient  *ssh.Client
-	sftpClient *sftp.Client
+	config      *SFTPConfig
+	conn        *SharedConn // 共享的 SSH 连接
+	sshClient   *ssh.Client
+	sftpClient  *sftp.Client
+	options     TransferOptions
+	deltaHelper string          // 增量传输使用的远程 python，"-" 表示不可用
+	tarMode     string          // 远程 tar 是否可用，"-" 表示不可用
+	grepMode    string          // 远程 grep 是否可用，"-" 表示不可用
+	cipher      string          // 协商的加密算法
+	stop        <-chan struct{} // 取消信号，为nil时不可取消
+	shared      bool            // 由 Share 打开，只被一个任务使用，连接中断时可以就地重新连接
+}
+
+// DisplayName 返回连接名称，未命名时为 user@host
+func (c *SFTPConfig) DisplayName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Username + "@" + c.Host
 }
 
 // NewSFTPFileSystem 创建新的SFTP文件系统
 func NewSFTPFileSystem(config *SFTPConfig) *SFTPFileSystem {
 	return &SFTPFileSystem{
-		config: config,
+		config:  config,
+		options: DefaultTransferOptions(),
 	}
 }
 
-// Connect 连接到SFTP服务器
-func (fs *SFTPFileSystem) Connect() error {
-	// 创建SSH配置
-	config := &ssh.ClientConfig{
-		User: fs.config.Username,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(fs.config.Password),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-	}
+// SetTransferOptions 设置传输选项
+func (fs *SFTPFileSystem) SetTransferOptions(options TransferOptions) {
+	fs.options = options
+}
+
+// GetTransferOptions 获取传输选项
+func (fs *SFTPFileSystem) GetTransferOptions() TransferOptions {
+	return fs.options
+}
 
-	// 连接到SSH服务器
-	client, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", fs.config.Host, fs.config.Port), config)
+// SetStop 设置取消信号，用于传输队列中的任务
+func (fs *SFTPFileSystem) SetStop(stop <-chan struct{}) {
+	fs.stop = stop
+}
+
+// Connect 连接到SFTP服务器：已有到同一服务器和用户的连接时复用该连接，只打开新的 SFTP 通道
+func (fs *SFTPFileSystem) Connect() error {
+	conn, err := Connections.Acquire(fs.config)
 	if err != nil {
-		return fmt.Errorf("连接SSH服务器失败: %v", err)
+		return err
 	}
-
-	// 创建SFTP客户端
-	sftpClient, err := sftp.NewClient(client)
+	sftpClient, err := conn.OpenSFTP()
 	if err != nil {
-		client.Close()
-		return fmt.Errorf("创建SFTP客户端失败: %v", err)
+		// 复用的连接可能已经断开但尚未被移除，标记后重新连接一次
+		Connections.forget(conn)
+		conn.Release()
+		if conn, err = Connections.Acquire(fs.config); err != nil {
+			return err
+		}
+		if sftpClient, err = conn.OpenSFTP(); err != nil {
+			conn.Release()
+			return err
+		}
 	}
 
-	fs.sshClient = client
+	fs.conn = conn
+	fs.sshClient = conn.Client()
 	fs.sftpClient = sftpClient
+	fs.cipher = conn.Cipher()
+	return nil
+}
+
+// ErrClosed 文件系统已关闭，或连接中断后重新连接失败
+var ErrClosed = errors.New("连接已关闭")
+
+// checkOpen 文件系统已关闭时返回 ErrClosed，避免使用已释放的 SFTP 客户端
+func (fs *SFTPFileSystem) checkOpen() error {
+	if fs.sftpClient == nil {
+		return ErrClosed
+	}
 	return nil
 }
 
-// Close 关闭连接
+// Share 在同一 SSH 连接上打开新的 SFTP 通道，返回的文件系统持有连接的一个引用，用完后调用 Close；
+// 用于传输任务等与面板并行的操作，关闭面板不会中断已开始的任务
+func (fs *SFTPFileSystem) Share() (RemoteFS, error) {
+	if fs.conn == nil {
+		return nil, ErrClosed
+	}
+	fs.conn.Retain()
+	sftpClient, err := fs.conn.OpenSFTP()
+	if err != nil {
+		fs.conn.Release()
+		return nil, err
+	}
+	shared := *fs
+	shared.sftpClient = sftpClient
+	shared.shared = true
+	return &shared, nil
+}
+
+// Close 关闭本文件系统的 SFTP 通道并释放连接，最后一个使用者释放时断开连接
 func (fs *SFTPFileSystem) Close() error {
 	var err error
 	if fs.sftpClient != nil {
 		if e := fs.sftpClient.Close(); e != nil {
 			err = e
 		}
+		fs.sftpClient = nil
 	}
-	if fs.sshClient != nil {
-		if e := fs.sshClient.Close(); e != nil && err == nil {
+	if fs.conn != nil {
+		if e := fs.conn.Release(); e != nil && err == nil {
 			err = e
 		}
+		fs.conn = nil
 	}
 	return err
 }
 
+// Open 打开远程文件用于流式读取
+func (fs *SFTPFileSystem) Open(path string) (io.ReadCloser, error) {
+	if err := fs.checkOpen(); err != nil {
+		return nil, err
+	}
+	return fs.sftpClient.Open(path)
+}
+
+// remoteOwner 返回远程文件所有者的 uid
+func remoteOwner(info os.FileInfo) string {
+	if stat, ok := info.Sys().(*sftp.FileStat); ok {
+		return strconv.FormatUint(uint64(stat.UID), 10)
+	}
+	return ""
+}
+
 // ListFiles 列出目录下的文件
 func (fs *SFTPFileSystem) ListFiles(path string) ([]FileInfo, error) {
+	if err := fs.checkOpen(); err != nil {
+		return nil, err
+	}
 	files, err := fs.sftpClient.ReadDir(path)
 	if err != nil {
 		return nil, err
@@ -88,10 +172,12 @@ func (fs *SFTPFileSystem) ListFiles(path string) ([]FileInfo, error) {
 	for _, file := range files {
 		fileInfos = append(fileInfos, FileInfo{
 			Name:    file.Name(),
-			Path:    filepath.Join(path, file.Name()),
+			Path:    RemotePaths.Join(path, file.Name()),
 			Size:    file.Size(),
 			ModTime: file.ModTime(),
 			IsDir:   file.IsDir(),
+			Mode:    file.Mode(),
+			Owner:   remoteOwner(file),
 		})
 	}
 	return fileInfos, nil
@@ -99,6 +185,9 @@ func (fs *SFTPFileSystem) ListFiles(path string) ([]FileInfo, error) {
 
 // UploadFile 上传文件或目录
 func (fs *SFTPFileSystem) UploadFile(localPath, remotePath string, progress func(current, total int64)) error {
+	if err := fs.checkOpen(); err != nil {
+		return err
+	}
 	// 获取本地文件信息
 	info, err := os.Stat(localPath)
 	if err != nil {
@@ -110,8 +199,28 @@ func (fs *SFTPFileSystem) UploadFile(localPath, remotePath string, progress func
 		return fs.uploadDirectory(localPath, remotePath, progress)
 	}
 
+	return fs.retry(func() error {
+		return fs.uploadFile(localPath, remotePath, progress)
+	})
+}
+
+// uploadFile 上传单个文件
+func (fs *SFTPFileSystem) uploadFile(localPath, remotePath string, progress func(current, total int64)) error {
+	// 获取本地文件信息
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+
+	// 远程文件已存在时优先尝试增量传输，失败则完整上传
+	if fs.options.Delta {
+		if err := fs.uploadDelta(localPath, remotePath, progress); err == nil || errors.Is(err, ErrCanceled) {
+			return err
+		}
+	}
+
 	// 创建远程目录
-	if err := fs.createRemoteDirectory(filepath.Dir(remotePath)); err != nil {
+	if err := fs.createRemoteDirectory(RemotePaths.Dir(remotePath)); err != nil {
 		return err
 	}
 
@@ -136,6 +245,7 @@ func (fs *SFTPFileSystem) UploadFile(localPath, remotePath string, progress func
 	// 创建带进度的读取器
 	reader := &progressReader{
 		reader: localFile,
+		stop:   fs.stop,
 		progress: func(n int64) {
 			uploaded += n
 			if progress != nil {
@@ -152,46 +262,128 @@ func (fs *SFTPFileSystem) UploadFile(localPath, remotePath string, progress func
 // uploadDirectory 递归上传目录
 func (fs *SFTPFileSystem) uploadDirectory(localPath, remotePath string, progress func(current, total int64)) error {
 	// 创建远程目录
-	if err := fs.createRemoteDirectory(remotePath); err != nil {
+	if err := fs.retry(func() error { return fs.createRemoteDirectory(remotePath) }); err != nil {
 		return err
 	}
 
-	// 遍历本地目录
-	return filepath.Walk(localPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
+	filter, err := LoadLocalFilter(fs.options.Filters, localPath)
+	if err != nil {
+		return err
+	}
+
+	// 优先使用 tar 流传输，服务器不支持时回退到逐个文件上传
+	if fs.options.TarStream {
+		if err := fs.uploadTar(localPath, remotePath, filter, progress); err != errTarUnavailable {
 			return err
 		}
+	}
+	report := &TransferReport{}
 
+	// 遍历本地目录
+	err = filepath.Walk(localPath, func(path string, info os.FileInfo, err error) error {
 		// 计算相对路径
-		relPath, err := filepath.Rel(localPath, path)
-		if err != nil {
-			return err
+		relPath, relErr := filepath.Rel(localPath, path)
+		if relErr != nil {
+			return relErr
 		}
 
-		// 构建远程路径
-		remoteFilePath := filepath.Join(remotePath, relPath)
+		// 跳过不符合过滤规则的文件和目录
+		if err == nil && !filter.Match(filepath.ToSlash(relPath), info.IsDir(), info.Size(), info.ModTime()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 
-		// 如果是目录，创建远程目录
-		if info.IsDir() {
-			return fs.createRemoteDirectory(remoteFilePath)
+		// 构建远程路径
+		remoteFilePath := RemotePaths.Join(remotePath, filepath.ToSlash(relPath))
+
+		if err == nil {
+			if info.IsDir() {
+				// 如果是目录，创建远程目录
+				err = fs.retry(func() error { return fs.createRemoteDirectory(remoteFilePath) })
+			} else {
+				// 上传文件
+				err = fs.retry(func() error { return fs.uploadFile(path, remoteFilePath, progress) })
+				if err == nil {
+					report.Succeeded++
+				}
+			}
+		}
+		if err == nil {
+			return nil
 		}
 
-		// 上传文件
-		return fs.UploadFile(path, remoteFilePath, progress)
+		if !fs.options.ContinueOnError || stopsTransfer(err) {
+			return err
+		}
+		report.addFailure(path, remoteFilePath, Upload, err)
+		// 目录失败时跳过其内容，继续处理其余部分
+		if info != nil && info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
 	})
+	if err != nil {
+		return err
+	}
+	if report.HasFailures() {
+		return report
+	}
+	return nil
 }
 
 // DownloadFile 下载文件或目录
 func (fs *SFTPFileSystem) DownloadFile(remotePath, localPath string, progress func(current, total int64)) error {
+	if err := fs.checkOpen(); err != nil {
+		return err
+	}
 	// 获取远程文件信息
-	info, err := fs.sftpClient.Stat(remotePath)
+	var info os.FileInfo
+	err := fs.retry(func() error {
+		var statErr error
+		info, statErr = fs.sftpClient.Stat(remotePath)
+		return statErr
+	})
 	if err != nil {
 		return err
 	}
 
 	// 如果是目录，递归下载
 	if info.IsDir() {
-		return fs.downloadDirectory(remotePath, localPath, progress)
+		filter, err := fs.LoadFilter(remotePath)
+		if err != nil {
+			return err
+		}
+		job := &directoryJob{report: &TransferReport{}, filter: filter}
+		// 优先使用 tar 流传输，服务器不支持时回退到逐个文件下载
+		err = errTarUnavailable
+		if fs.options.TarStream {
+			err = fs.downloadTar(remotePath, localPath, progress, job)
+		}
+		if err == errTarUnavailable {
+			err = fs.downloadDirectory(remotePath, localPath, "", progress, job)
+		}
+		if err != nil {
+			return err
+		}
+		if job.report.HasFailures() {
+			return job.report
+		}
+		return nil
+	}
+
+	return fs.retry(func() error {
+		return fs.downloadFile(remotePath, localPath, progress)
+	})
+}
+
+// downloadFile 下载单个文件
+func (fs *SFTPFileSystem) downloadFile(remotePath, localPath string, progress func(current, total int64)) error {
+	// 获取远程文件信息
+	info, err := fs.sftpClient.Stat(remotePath)
+	if err != nil {
+		return err
 	}
 
 	// 创建本地目录
@@ -220,6 +412,7 @@ func (fs *SFTPFileSystem) DownloadFile(remotePath, localPath string, progress fu
 	// 创建带进度的读取器
 	reader := &progressReader{
 		reader: remoteFile,
+		stop:   fs.stop,
 		progress: func(n int64) {
 			downloaded += n
 			if progress != nil {
@@ -233,57 +426,155 @@ func (fs *SFTPFileSystem) DownloadFile(remotePath, localPath string, progress fu
 	return err
 }
 
-// downloadDirectory 递归下载目录
-func (fs *SFTPFileSystem) downloadDirectory(remotePath, localPath string, progress func(current, total int64)) error {
-	// 创建本地目录
-	if err := os.MkdirAll(localPath, 0755); err != nil {
-		return err
-	}
+// directoryJob 一次目录传输的共享状态
+type directoryJob struct {
+	report *TransferReport
+	filter *Filter
+}
 
-	// 列出远程目录内容
-	files, err := fs.sftpClient.ReadDir(remotePath)
+// downloadDirectory 递归下载目录，rel 为相对于下载根目录的路径，失败项记录到 job.report
+func (fs *SFTPFileSystem) downloadDirectory(remotePath, localPath, rel string, progress func(current, total int64), job *directoryJob) error {
+	// 创建本地目录，并列出远程目录内容
+	var files []os.FileInfo
+	err := fs.retry(func() error {
+		if err := os.MkdirAll(localPath, 0755); err != nil {
+			return err
+		}
+		var readErr error
+		files, readErr = fs.sftpClient.ReadDir(remotePath)
+		return readErr
+	})
 	if err != nil {
-		return err
+		if !fs.options.ContinueOnError || stopsTransfer(err) {
+			return err
+		}
+		job.report.addFailure(remotePath, localPath, Download, err)
+		return nil
 	}
 
 	// 遍历并下载每个文件/目录
 	for _, file := range files {
-		remoteFilePath := filepath.Join(remotePath, file.Name())
+		remoteFilePath := RemotePaths.Join(remotePath, file.Name())
 		localFilePath := filepath.Join(localPath, file.Name())
+		fileRel := joinRel(rel, file.Name())
+
+		// 跳过不符合过滤规则的文件和目录
+		if !job.filter.Match(fileRel, file.IsDir(), file.Size(), file.ModTime()) {
+			continue
+		}
 
 		if file.IsDir() {
 			// 递归下载子目录
-			if err := fs.downloadDirectory(remoteFilePath, localFilePath, progress); err != nil {
-				return err
-			}
-		} else {
-			// 下载文件
-			if err := fs.DownloadFile(remoteFilePath, localFilePath, progress); err != nil {
+			if err := fs.downloadDirectory(remoteFilePath, localFilePath, fileRel, progress, job); err != nil {
 				return err
 			}
+			continue
 		}
+
+		// 下载文件
+		err := fs.retry(func() error {
+			return fs.downloadFile(remoteFilePath, localFilePath, progress)
+		})
+		if err == nil {
+			job.report.Succeeded++
+			continue
+		}
+		if !fs.options.ContinueOnError || stopsTransfer(err) {
+			return err
+		}
+		job.report.addFailure(remoteFilePath, localFilePath, Download, err)
+	}
+
+	return nil
+}
+
+// RetryFailures 仅重试报告中失败的项，返回新的报告（全部成功时返回nil）
+func (fs *SFTPFileSystem) RetryFailures(report *TransferReport, progress func(current, total int64)) error {
+	if err := fs.checkOpen(); err != nil {
+		return err
+	}
+	if !report.HasFailures() {
+		return nil
 	}
 
+	result := &TransferReport{}
+	for _, failure := range report.Failures {
+		var err error
+		switch failure.Direction {
+		case Upload:
+			err = fs.UploadFile(failure.Source, failure.Target, progress)
+		case Download:
+			err = fs.DownloadFile(failure.Source, failure.Target, progress)
+		}
+
+		var sub *TransferReport
+		switch {
+		case stopsTransfer(err):
+			return err
+		case err == nil:
+			result.Succeeded++
+		case errors.As(err, &sub):
+			// 目录仍有部分失败，合并其报告
+			result.Succeeded += sub.Succeeded
+			result.Failures = append(result.Failures, sub.Failures...)
+		default:
+			result.addFailure(failure.Source, failure.Target, failure.Direction, err)
+		}
+	}
+
+	if result.HasFailures() {
+		return result
+	}
 	return nil
 }
 
+// retry 按传输选项重试操作。连接中断时，Share 打开的通道先尝试重新连接，重新连接失败时返回 ErrClosed，不再重试；
+// 面板自己的通道同时被延迟检测、路径补全等后台操作使用，不能在这里替换客户端，直接返回 ErrClosed
+func (fs *SFTPFileSystem) retry(fn func() error) error {
+	return fs.options.Retry.Do(func() error {
+		if err := fs.checkOpen(); err != nil {
+			return err
+		}
+		err := fn()
+		if errors.Is(err, sftp.ErrSSHFxConnectionLost) || errors.Is(err, sftp.ErrSSHFxNoConnection) {
+			if !fs.shared {
+				return fmt.Errorf("%w: %v", ErrClosed, err)
+			}
+			fs.Close()
+			if connErr := fs.Connect(); connErr != nil {
+				return fmt.Errorf("%w: %v（重新连接失败: %v）", ErrClosed, err, connErr)
+			}
+		}
+		return err
+	})
+}
+
+// stopsTransfer 取消或连接已关闭时，即使设置了出错继续也不再传输其余文件
+func stopsTransfer(err error) bool {
+	return errors.Is(err, ErrCanceled) || errors.Is(err, ErrClosed)
+}
+
 // createRemoteDirectory 创建远程目录
 func (fs *SFTPFileSystem) createRemoteDirectory(path string) error {
 	// 尝试创建目录
 	err := fs.sftpClient.MkdirAll(path)
 	if err != nil {
-		return fmt.Errorf("创建远程目录失败: %v", err)
+		return fmt.Errorf("创建远程目录失败: %w", err)
 	}
 	return nil
 }
 
-// progressReader 用于跟踪读取进度的io.Reader
+// progressReader 用于跟踪读取进度的io.Reader，stop 关闭后返回 ErrCanceled
 type progressReader struct {
 	reader   io.Reader
 	progress func(int64)
+	stop     <-chan struct{}
 }
 
 func (r *progressReader) Read(p []byte) (int, error) {
+	if stopped(r.stop) {
+		return 0, ErrCanceled
+	}
 	n, err := r.reader.Read(p)
 	if n > 0 && r.progress != nil {
 		r.progress(int64(n))
@@ -293,35 +584,105 @@ func (r *progressReader) Read(p []byte) (int, error) {
 
 // CreateDirectory 创建目录
 func (fs *SFTPFileSystem) CreateDirectory(path string) error {
+	if err := fs.checkOpen(); err != nil {
+		return err
+	}
 	return fs.createRemoteDirectory(path)
 }
 
+// Rename 重命名远程文件或目录，目标已存在时由服务器拒绝
+func (fs *SFTPFileSystem) Rename(oldPath, newPath string) error {
+	if err := fs.checkOpen(); err != nil {
+		return err
+	}
+	if err := fs.sftpClient.Rename(oldPath, newPath); err != nil {
+		return fmt.Errorf("重命名失败: %v", err)
+	}
+	return nil
+}
+
 // DeleteFile 删除文件或目录
 func (fs *SFTPFileSystem) DeleteFile(path string) error {
+	if err := fs.checkOpen(); err != nil {
+		return err
+	}
 	// 获取文件信息
 	info, err := fs.sftpClient.Stat(path)
 	if err != nil {
 		return err
 	}
 
-	// 如果是目录，先删除所有内容
+	// 如果是目录，按过滤规则删除内容
 	if info.IsDir() {
-		files, err := fs.sftpClient.ReadDir(path)
+		filter, err := fs.LoadFilter(path)
 		if err != nil {
 			return err
 		}
+		_, err = fs.deleteTree(path, "", filter)
+		return err
+	}
 
-		for _, file := range files {
-			filePath := filepath.Join(path, file.Name())
-			if err := fs.DeleteFile(filePath); err != nil {
-				return err
+	// 删除文件
+	return fs.sftpClient.Remove(path)
+}
+
+// deleteTree 递归删除远程目录，返回是否有内容因过滤规则被保留
+func (fs *SFTPFileSystem) deleteTree(path, rel string, filter *Filter) (bool, error) {
+	files, err := fs.sftpClient.ReadDir(path)
+	if err != nil {
+		return false, err
+	}
+
+	kept := false
+	for _, file := range files {
+		fileRel := joinRel(rel, file.Name())
+		if !filter.Match(fileRel, file.IsDir(), file.Size(), file.ModTime()) {
+			kept = true
+			continue
+		}
+
+		filePath := RemotePaths.Join(path, file.Name())
+		if file.IsDir() {
+			childKept, err := fs.deleteTree(filePath, fileRel, filter)
+			if err != nil {
+				return kept, err
+			}
+			if childKept {
+				kept = true
 			}
+			continue
 		}
+		if err := fs.sftpClient.Remove(filePath); err != nil {
+			return kept, err
+		}
+	}
 
-		// 删除空目录
-		return fs.sftpClient.RemoveDirectory(path)
+	// 删除空目录
+	if kept {
+		return true, nil
 	}
+	return false, fs.sftpClient.RemoveDirectory(path)
+}
 
-	// 删除文件
-	return fs.sftpClient.Remove(path)
+// LoadFilter 按传输选项加载过滤器，需要时读取远程 root 下的 .xftpignore
+func (fs *SFTPFileSystem) LoadFilter(root string) (*Filter, error) {
+	if err := fs.checkOpen(); err != nil {
+		return nil, err
+	}
+	rules := fs.options.Filters
+	if rules.IsEmpty() {
+		return nil, nil
+	}
+	var data []byte
+	if rules.UseIgnoreFile {
+		f, err := fs.sftpClient.Open(RemotePaths.Join(root, IgnoreFileName))
+		if err == nil {
+			data, err = io.ReadAll(f)
+			f.Close()
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("读取%s失败: %v", IgnoreFileName, err)
+		}
+	}
+	return NewFilter(rules, data)
 }
@@ -0,0 +1,113 @@
+package transfer
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Shell 在面板的 SSH 连接上打开的交互式 shell，持有连接的一个引用
+type Shell struct {
+	conn    *SharedConn
+	session *ssh.Session
+	stdin   io.WriteCloser
+	stdout  io.Reader
+	once    sync.Once
+}
+
+// OpenShell 在同一 SSH 连接上申请 cols×rows 的伪终端并启动登录 shell；
+// dir 不为空时启动后进入该目录
+func (fs *SFTPFileSystem) OpenShell(cols, rows int, dir string) (*Shell, error) {
+	if err := fs.checkOpen(); err != nil {
+		return nil, err
+	}
+	if fs.conn == nil {
+		return nil, fmt.Errorf("连接已关闭")
+	}
+	fs.conn.Retain()
+	shell, err := openShell(fs.conn, cols, rows)
+	if err != nil {
+		fs.conn.Release()
+		return nil, err
+	}
+	if dir != "" {
+		// 以空格开头，多数 shell 不会记入历史
+		if _, err := fmt.Fprintf(shell.stdin, " cd -- %s && clear\n", shellQuote(dir)); err != nil {
+			shell.Close()
+			return nil, fmt.Errorf("进入目录失败: %v", err)
+		}
+	}
+	return shell, nil
+}
+
+// openShell 创建会话、申请伪终端并启动 shell
+func openShell(conn *SharedConn, cols, rows int) (*Shell, error) {
+	session, err := conn.Client().NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("创建SSH会话失败: %v", err)
+	}
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 38400,
+		ssh.TTY_OP_OSPEED: 38400,
+	}
+	if err := session.RequestPty("xterm-256color", rows, cols, modes); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("申请伪终端失败: %v", err)
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	// 伪终端已将标准错误合并到输出，这里只是防止非伪终端的服务器阻塞
+	session.Stderr = io.Discard
+	if err := session.Shell(); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("启动shell失败: %v", err)
+	}
+	return &Shell{conn: conn, session: session, stdin: stdin, stdout: stdout}, nil
+}
+
+// Read 读取终端输出
+func (s *Shell) Read(p []byte) (int, error) {
+	return s.stdout.Read(p)
+}
+
+// Write 向终端输入
+func (s *Shell) Write(p []byte) (int, error) {
+	return s.stdin.Write(p)
+}
+
+// Resize 通知服务器终端大小变化
+func (s *Shell) Resize(cols, rows int) error {
+	return s.session.WindowChange(rows, cols)
+}
+
+// Wait 等待 shell 退出
+func (s *Shell) Wait() error {
+	return s.session.Wait()
+}
+
+// Close 结束 shell 并释放连接
+func (s *Shell) Close() error {
+	var err error
+	s.once.Do(func() {
+		err = s.session.Close()
+		if err == io.EOF {
+			// shell 已经退出
+			err = nil
+		}
+		if e := s.conn.Release(); e != nil && err == nil {
+			err = e
+		}
+	})
+	return err
+}
@@ -0,0 +1,747 @@
+package transfer
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SyncMode 同步模式
+type SyncMode int
+
+const (
+	SyncUpload   SyncMode = iota // 单向镜像：本地 -> 远程
+	SyncDownload                 // 单向镜像：远程 -> 本地
+	SyncTwoWay                   // 双向同步
+)
+
+// SyncActionType 同步动作类型
+type SyncActionType int
+
+const (
+	SyncCopy     SyncActionType = iota // 目标端不存在，复制
+	SyncUpdate                         // 目标端存在但不同，覆盖
+	SyncDelete                         // 删除多余的文件
+	SyncConflict                       // 两端都有修改，需要人工处理
+)
+
+// String 返回动作名称
+func (t SyncActionType) String() string {
+	switch t {
+	case SyncCopy:
+		return "复制"
+	case SyncUpdate:
+		return "更新"
+	case SyncDelete:
+		return "删除"
+	case SyncConflict:
+		return "冲突"
+	default:
+		return "未知"
+	}
+}
+
+// SyncOptions 同步选项
+type SyncOptions struct {
+	Mode            SyncMode
+	DeleteExtras    bool          // 单向镜像时删除目标端多余的文件
+	CompareChecksum bool          // 大小相同时比较校验和
+	TimeTolerance   time.Duration // 修改时间比较的容差
+}
+
+// DefaultSyncOptions 返回默认同步选项
+func DefaultSyncOptions() SyncOptions {
+	return SyncOptions{
+		Mode:          SyncUpload,
+		TimeTolerance: 2 * time.Second,
+	}
+}
+
+// SyncAction 同步计划中的单个动作
+type SyncAction struct {
+	Type SyncActionType
+	// Direction 对复制/更新表示传输方向；对删除表示被删除的一端：
+	// Upload 删除远程，Download 删除本地
+	Direction  TransferDirection
+	RelPath    string // 相对路径（使用/分隔）
+	LocalPath  string
+	RemotePath string
+	IsDir      bool
+	Size       int64
+	Reason     string
+}
+
+// SyncPlan 同步计划（dry-run 结果）
+type SyncPlan struct {
+	LocalRoot  string
+	RemoteRoot string
+	Options    SyncOptions
+	Filter     *Filter // 生效的过滤规则，nil 表示不过滤
+	Actions    []SyncAction
+}
+
+// Count 统计指定类型的动作数量
+func (p *SyncPlan) Count(t SyncActionType) int {
+	n := 0
+	for _, a := range p.Actions {
+		if a.Type == t {
+			n++
+		}
+	}
+	return n
+}
+
+// syncEntry 目录树中的一项
+type syncEntry struct {
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// syncStateEntry 上次同步后两端的状态
+type syncStateEntry struct {
+	Local  syncEntry
+	Remote syncEntry
+}
+
+// PlanSync 比较本地和远程目录树，生成同步计划
+func (fs *SFTPFileSystem) PlanSync(localRoot, remoteRoot string, options SyncOptions) (*SyncPlan, error) {
+	if err := fs.checkOpen(); err != nil {
+		return nil, err
+	}
+	// 过滤规则中的 .xftpignore 从源端根目录读取
+	var filter *Filter
+	var err error
+	if options.Mode == SyncDownload {
+		filter, err = fs.LoadFilter(remoteRoot)
+	} else {
+		filter, err = LoadLocalFilter(fs.options.Filters, localRoot)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	local, err := scanLocalTree(localRoot, filter)
+	if err != nil {
+		return nil, fmt.Errorf("扫描本地目录失败: %v", err)
+	}
+	remote, err := fs.scanRemoteTree(remoteRoot, filter)
+	if err != nil {
+		return nil, fmt.Errorf("扫描远程目录失败: %v", err)
+	}
+
+	var state map[string]syncStateEntry
+	if options.Mode == SyncTwoWay {
+		state, err = fs.loadSyncState(localRoot, remoteRoot)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	actions, err := planSync(localRoot, remoteRoot, local, remote, state, options, fs.compareFiles)
+	if err != nil {
+		return nil, err
+	}
+	return &SyncPlan{
+		LocalRoot:  localRoot,
+		RemoteRoot: remoteRoot,
+		Options:    options,
+		Filter:     filter,
+		Actions:    actions,
+	}, nil
+}
+
+// syncComparer 比较两端文件，返回差异原因，相同时返回空字符串
+type syncComparer func(action SyncAction, src, dst syncEntry, options SyncOptions) (string, error)
+
+// planSync 根据两端的目录树和上次同步的状态生成同步动作
+func planSync(localRoot, remoteRoot string, local, remote map[string]syncEntry, state map[string]syncStateEntry, options SyncOptions, compare syncComparer) ([]SyncAction, error) {
+	// 合并两端的相对路径并排序，保证父目录在子项之前
+	relPaths := make([]string, 0, len(local)+len(remote))
+	for rel := range local {
+		relPaths = append(relPaths, rel)
+	}
+	for rel := range remote {
+		if _, ok := local[rel]; !ok {
+			relPaths = append(relPaths, rel)
+		}
+	}
+	sort.Strings(relPaths)
+
+	var planned []SyncAction
+	var deleted []string        // 单向镜像中整体删除的目录
+	var dirDeletes []SyncAction // 双向同步中删除的目录，在其内容之后决定
+	for _, rel := range relPaths {
+		// 已整体删除的目录，跳过其内容
+		if UnderAny(rel, deleted) {
+			continue
+		}
+
+		l, inLocal := local[rel]
+		r, inRemote := remote[rel]
+		action := SyncAction{
+			RelPath:    rel,
+			LocalPath:  filepath.Join(localRoot, filepath.FromSlash(rel)),
+			RemotePath: RemotePaths.Join(remoteRoot, rel),
+		}
+
+		var actions []SyncAction
+		var err error
+		switch options.Mode {
+		case SyncUpload:
+			actions, err = planMirror(action, l, inLocal, r, inRemote, Upload, options, compare)
+		case SyncDownload:
+			actions, err = planMirror(action, r, inRemote, l, inLocal, Download, options, compare)
+		case SyncTwoWay:
+			rec, known := state[rel]
+			actions, err = planTwoWay(action, l, inLocal, r, inRemote, rec, known, options, compare)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range actions {
+			switch {
+			case options.Mode == SyncTwoWay && a.Type == SyncDelete && a.IsDir:
+				// 目录的内容逐项处理，目录本身在内容之后删除
+				dirDeletes = append(dirDeletes, a)
+				continue
+			case options.Mode == SyncTwoWay && a.Type == SyncCopy && UnderAny(rel, relPathsOf(dirDeletes)):
+				// 另一端删除了所在目录，但这一端在其中新增了内容
+				a.Type = SyncConflict
+				a.Reason = "远程已删除所在目录，本地有新增"
+				if a.Direction == Download {
+					a.Reason = "本地已删除所在目录，远程有新增"
+				}
+			case a.Type == SyncDelete && a.IsDir:
+				deleted = append(deleted, rel)
+			}
+			planned = append(planned, a)
+		}
+	}
+
+	// 由深到浅删除目录，目录中有保留的内容（冲突等）时不删除
+	for i := len(dirDeletes) - 1; i >= 0; i-- {
+		dir := dirDeletes[i]
+		keep := false
+		for _, a := range planned {
+			if a.Type != SyncDelete && UnderAny(a.RelPath, []string{dir.RelPath}) {
+				keep = true
+				break
+			}
+		}
+		if !keep {
+			planned = append(planned, dir)
+		}
+	}
+	return planned, nil
+}
+
+// relPathsOf 返回动作的相对路径
+func relPathsOf(actions []SyncAction) []string {
+	paths := make([]string, len(actions))
+	for i, a := range actions {
+		paths[i] = a.RelPath
+	}
+	return paths
+}
+
+// planMirror 单向镜像：src 为源端，dst 为目标端
+func planMirror(action SyncAction, src syncEntry, inSrc bool, dst syncEntry, inDst bool, direction TransferDirection, options SyncOptions, compare syncComparer) ([]SyncAction, error) {
+	action.Direction = direction
+
+	switch {
+	case inSrc && !inDst:
+		action.Type = SyncCopy
+		action.IsDir = src.IsDir
+		action.Size = src.Size
+		action.Reason = "目标端不存在"
+		return []SyncAction{action}, nil
+
+	case !inSrc && inDst:
+		if !options.DeleteExtras {
+			return nil, nil
+		}
+		action.Type = SyncDelete
+		action.IsDir = dst.IsDir
+		action.Size = dst.Size
+		action.Reason = "源端不存在"
+		return []SyncAction{action}, nil
+
+	case inSrc && inDst:
+		if src.IsDir != dst.IsDir {
+			// 类型不同：先删除目标端，再复制
+			del := action
+			del.Type = SyncDelete
+			del.IsDir = dst.IsDir
+			del.Reason = "类型不同"
+			action.Type = SyncCopy
+			action.IsDir = src.IsDir
+			action.Size = src.Size
+			action.Reason = "类型不同"
+			return []SyncAction{del, action}, nil
+		}
+		if src.IsDir {
+			return nil, nil
+		}
+		reason, err := compare(action, src, dst, options)
+		if err != nil || reason == "" {
+			return nil, err
+		}
+		action.Type = SyncUpdate
+		action.Size = src.Size
+		action.Reason = reason
+		return []SyncAction{action}, nil
+	}
+	return nil, nil
+}
+
+// planTwoWay 双向同步，借助上次同步的状态判断哪一端发生了变化
+func planTwoWay(action SyncAction, l syncEntry, inLocal bool, r syncEntry, inRemote bool, rec syncStateEntry, known bool, options SyncOptions, compare syncComparer) ([]SyncAction, error) {
+	switch {
+	case inLocal && !inRemote:
+		if known && !entryChanged(l, rec.Local, options.TimeTolerance) {
+			// 远程已删除，本地未修改：删除本地
+			action.Type = SyncDelete
+			action.Direction = Download
+			action.IsDir = l.IsDir
+			action.Reason = "远程已删除"
+		} else if known {
+			action.Type = SyncConflict
+			action.Reason = "远程已删除，本地已修改"
+		} else {
+			action.Type = SyncCopy
+			action.Direction = Upload
+			action.IsDir = l.IsDir
+			action.Size = l.Size
+			action.Reason = "本地新增"
+		}
+		return []SyncAction{action}, nil
+
+	case !inLocal && inRemote:
+		if known && !entryChanged(r, rec.Remote, options.TimeTolerance) {
+			action.Type = SyncDelete
+			action.Direction = Upload
+			action.IsDir = r.IsDir
+			action.Reason = "本地已删除"
+		} else if known {
+			action.Type = SyncConflict
+			action.Reason = "本地已删除，远程已修改"
+		} else {
+			action.Type = SyncCopy
+			action.Direction = Download
+			action.IsDir = r.IsDir
+			action.Size = r.Size
+			action.Reason = "远程新增"
+		}
+		return []SyncAction{action}, nil
+
+	case inLocal && inRemote:
+		if l.IsDir && r.IsDir {
+			return nil, nil
+		}
+		if l.IsDir != r.IsDir {
+			action.Type = SyncConflict
+			action.Reason = "类型不同"
+			return []SyncAction{action}, nil
+		}
+
+		localChanged := !known || entryChanged(l, rec.Local, options.TimeTolerance)
+		remoteChanged := !known || entryChanged(r, rec.Remote, options.TimeTolerance)
+		switch {
+		case !localChanged && !remoteChanged:
+			return nil, nil
+		case localChanged && !remoteChanged:
+			action.Type = SyncUpdate
+			action.Direction = Upload
+			action.Size = l.Size
+			action.Reason = "本地已修改"
+		case !localChanged && remoteChanged:
+			action.Type = SyncUpdate
+			action.Direction = Download
+			action.Size = r.Size
+			action.Reason = "远程已修改"
+		default:
+			// 两端都有变化（或没有同步记录），内容相同则无需处理
+			reason, err := compare(action, l, r, options)
+			if err != nil || reason == "" {
+				return nil, err
+			}
+			action.Type = SyncConflict
+			action.Reason = "两端都已修改（" + reason + "）"
+		}
+		return []SyncAction{action}, nil
+	}
+	return nil, nil
+}
+
+// compareFiles 比较两端文件，返回差异原因，相同时返回空字符串
+func (fs *SFTPFileSystem) compareFiles(action SyncAction, src, dst syncEntry, options SyncOptions) (string, error) {
+	if src.Size != dst.Size {
+		return "大小不同", nil
+	}
+	if options.CompareChecksum {
+		localSum, err := localChecksum(action.LocalPath)
+		if err != nil {
+			return "", err
+		}
+		remoteSum, err := fs.remoteChecksum(action.RemotePath)
+		if err != nil {
+			return "", err
+		}
+		if localSum != remoteSum {
+			return "校验和不同", nil
+		}
+		return "", nil
+	}
+	if !sameTime(src.ModTime, dst.ModTime, options.TimeTolerance) {
+		if dst.ModTime.After(src.ModTime) {
+			return "目标端较新", nil
+		}
+		return "源端较新", nil
+	}
+	return "", nil
+}
+
+// ExecuteSync 执行同步计划，冲突项会被跳过
+func (fs *SFTPFileSystem) ExecuteSync(plan *SyncPlan, progress func(current, total int64)) error {
+	if err := fs.checkOpen(); err != nil {
+		return err
+	}
+	report := &TransferReport{}
+	failed := make(map[string]bool)
+	for _, action := range plan.Actions {
+		if action.Type == SyncConflict {
+			continue
+		}
+
+		err := fs.retry(func() error {
+			return fs.executeSyncAction(action, plan.Options.Mode, progress)
+		})
+		if err == nil {
+			report.Succeeded++
+			continue
+		}
+
+		source, target := action.LocalPath, action.RemotePath
+		if action.Direction == Download {
+			source, target = action.RemotePath, action.LocalPath
+		}
+		if !fs.options.ContinueOnError || stopsTransfer(err) {
+			return err
+		}
+		report.addFailure(source, target, action.Direction, err)
+		failed[action.RelPath] = true
+	}
+
+	if plan.Options.Mode == SyncTwoWay {
+		if err := fs.saveSyncState(plan, failed); err != nil {
+			return err
+		}
+	}
+
+	if report.HasFailures() {
+		return report
+	}
+	return nil
+}
+
+// executeSyncAction 执行单个同步动作
+func (fs *SFTPFileSystem) executeSyncAction(action SyncAction, mode SyncMode, progress func(current, total int64)) error {
+	switch action.Type {
+	case SyncDelete:
+		if mode == SyncTwoWay && action.IsDir {
+			// 双向同步时目录内容已逐项删除，只删除空目录，不删除未参与同步的内容
+			if action.Direction == Upload {
+				return fs.sftpClient.RemoveDirectory(action.RemotePath)
+			}
+			return os.Remove(action.LocalPath)
+		}
+		if action.Direction == Upload {
+			return fs.DeleteFile(action.RemotePath)
+		}
+		// 与上传方向一样按过滤规则删除，目录中被排除的内容保留
+		local := NewFileSystem(filepath.Dir(action.LocalPath))
+		local.SetFilterRules(fs.options.Filters)
+		return local.DeleteFile(action.LocalPath)
+
+	case SyncCopy, SyncUpdate:
+		if action.Direction == Upload {
+			if action.IsDir {
+				return fs.createRemoteDirectory(action.RemotePath)
+			}
+			if err := fs.uploadFile(action.LocalPath, action.RemotePath, progress); err != nil {
+				return err
+			}
+			// 保留修改时间，便于下次比较
+			info, err := os.Stat(action.LocalPath)
+			if err != nil {
+				return err
+			}
+			return fs.sftpClient.Chtimes(action.RemotePath, info.ModTime(), info.ModTime())
+		}
+
+		if action.IsDir {
+			return os.MkdirAll(action.LocalPath, 0755)
+		}
+		if err := fs.downloadFile(action.RemotePath, action.LocalPath, progress); err != nil {
+			return err
+		}
+		info, err := fs.sftpClient.Stat(action.RemotePath)
+		if err != nil {
+			return err
+		}
+		return os.Chtimes(action.LocalPath, info.ModTime(), info.ModTime())
+	}
+	return nil
+}
+
+// scanLocalTree 扫描本地目录树，跳过不符合过滤规则的条目，目录不存在时返回空树
+func scanLocalTree(root string, filter *Filter) (map[string]syncEntry, error) {
+	entries := make(map[string]syncEntry)
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if p == root && errors.Is(err, os.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !filter.Match(rel, info.IsDir(), info.Size(), info.ModTime()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		entries[rel] = syncEntry{
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			IsDir:   info.IsDir(),
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// scanRemoteTree 扫描远程目录树，跳过不符合过滤规则的条目，目录不存在时返回空树
+func (fs *SFTPFileSystem) scanRemoteTree(root string, filter *Filter) (map[string]syncEntry, error) {
+	entries := make(map[string]syncEntry)
+	walker := fs.sftpClient.Walk(root)
+	for walker.Step() {
+		p := walker.Path()
+		if err := walker.Err(); err != nil {
+			if p == root && errors.Is(err, os.ErrNotExist) {
+				return entries, nil
+			}
+			return nil, err
+		}
+		if p == root {
+			continue
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+		info := walker.Stat()
+		if !filter.Match(rel, info.IsDir(), info.Size(), info.ModTime()) {
+			if info.IsDir() {
+				walker.SkipDir()
+			}
+			continue
+		}
+		entries[rel] = syncEntry{
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			IsDir:   info.IsDir(),
+		}
+	}
+	return entries, nil
+}
+
+// LocalTreeSize 统计本地目录中符合过滤规则的文件总大小，用于显示目录传输任务的大小和剩余时间
+func LocalTreeSize(root string, rules FilterRules) (int64, error) {
+	filter, err := LoadLocalFilter(rules, root)
+	if err != nil {
+		return 0, err
+	}
+	entries, err := scanLocalTree(root, filter)
+	if err != nil {
+		return 0, err
+	}
+	return treeSize(entries), nil
+}
+
+// TreeSize 统计远程目录中符合过滤规则的文件总大小
+func (fs *SFTPFileSystem) TreeSize(root string) (int64, error) {
+	filter, err := fs.LoadFilter(root)
+	if err != nil {
+		return 0, err
+	}
+	entries, err := fs.scanRemoteTree(root, filter)
+	if err != nil {
+		return 0, err
+	}
+	return treeSize(entries), nil
+}
+
+// treeSize 累加目录树中文件的大小
+func treeSize(entries map[string]syncEntry) int64 {
+	var size int64
+	for _, entry := range entries {
+		if !entry.IsDir {
+			size += entry.Size
+		}
+	}
+	return size
+}
+
+// localChecksum 计算本地文件的SHA-256
+func localChecksum(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return checksum(f)
+}
+
+// remoteChecksum 通过SFTP流式读取远程文件计算SHA-256
+func (fs *SFTPFileSystem) remoteChecksum(p string) (string, error) {
+	f, err := fs.sftpClient.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return checksum(f)
+}
+
+func checksum(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// entryChanged 判断条目相对于记录是否发生变化
+func entryChanged(e, rec syncEntry, tolerance time.Duration) bool {
+	return e.IsDir != rec.IsDir || (!e.IsDir && (e.Size != rec.Size || !sameTime(e.ModTime, rec.ModTime, tolerance)))
+}
+
+// sameTime 在容差范围内比较时间
+func sameTime(a, b time.Time, tolerance time.Duration) bool {
+	d := a.Sub(b)
+	if d < 0 {
+		d = -d
+	}
+	return d <= tolerance
+}
+
+// UnderAny 判断 / 分隔的相对路径 rel 是否位于任一目录之下
+func UnderAny(rel string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(rel, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// syncStatePath 返回同步状态文件路径
+func (fs *SFTPFileSystem) syncStatePath(localRoot, remoteRoot string) (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf("%s@%s:%d|%s|%s", fs.config.Username, fs.config.Host, fs.config.Port, localRoot, remoteRoot)
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(configDir, "xftp798", "sync", hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// loadSyncState 读取上次同步的状态，不存在时返回空状态
+func (fs *SFTPFileSystem) loadSyncState(localRoot, remoteRoot string) (map[string]syncStateEntry, error) {
+	state := make(map[string]syncStateEntry)
+	statePath, err := fs.syncStatePath(localRoot, remoteRoot)
+	if err != nil {
+		return state, nil
+	}
+	data, err := os.ReadFile(statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取同步状态失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("解析同步状态失败: %v", err)
+	}
+	return state, nil
+}
+
+// saveSyncState 重新扫描两端并保存同步状态；冲突项和执行失败的项保留原有记录，
+// 下次同步时仍按原来的变化处理
+func (fs *SFTPFileSystem) saveSyncState(plan *SyncPlan, failed map[string]bool) error {
+	local, err := scanLocalTree(plan.LocalRoot, plan.Filter)
+	if err != nil {
+		return err
+	}
+	remote, err := fs.scanRemoteTree(plan.RemoteRoot, plan.Filter)
+	if err != nil {
+		return err
+	}
+	previous, err := fs.loadSyncState(plan.LocalRoot, plan.RemoteRoot)
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[string]bool)
+	for _, a := range plan.Actions {
+		if a.Type == SyncConflict || failed[a.RelPath] {
+			keep[a.RelPath] = true
+		}
+	}
+
+	state := make(map[string]syncStateEntry)
+	for rel, rec := range previous {
+		if keep[rel] {
+			state[rel] = rec
+		}
+	}
+	for rel, l := range local {
+		if keep[rel] {
+			continue
+		}
+		if r, ok := remote[rel]; ok {
+			state[rel] = syncStateEntry{Local: l, Remote: r}
+		}
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	statePath, err := fs.syncStatePath(plan.LocalRoot, plan.RemoteRoot)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(statePath), 0755); err != nil {
+		return fmt.Errorf("保存同步状态失败: %v", err)
+	}
+	if err := os.WriteFile(statePath, data, 0644); err != nil {
+		return fmt.Errorf("保存同步状态失败: %v", err)
+	}
+	return nil
+}
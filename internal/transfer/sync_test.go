@@ -0,0 +1,435 @@
+package transfer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// syncTestTime 测试中文件的基准修改时间
+var syncTestTime = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// syncFile 返回大小为 size、修改时间比基准晚 minutes 分钟的文件
+func syncFile(size int64, minutes int) syncEntry {
+	return syncEntry{Size: size, ModTime: syncTestTime.Add(time.Duration(minutes) * time.Minute)}
+}
+
+// syncDir 返回目录
+func syncDir() syncEntry {
+	return syncEntry{IsDir: true, ModTime: syncTestTime}
+}
+
+// synced 返回两端都与 entry 相同的同步记录
+func synced(entry syncEntry) syncStateEntry {
+	return syncStateEntry{Local: entry, Remote: entry}
+}
+
+// compareBySize 测试用的比较函数：只比较大小
+func compareBySize(action SyncAction, src, dst syncEntry, options SyncOptions) (string, error) {
+	if src.Size != dst.Size {
+		return "大小不同", nil
+	}
+	return "", nil
+}
+
+// describeActions 将同步动作转换为便于比较的文字，如 "复制 上传 a"、"冲突 a"
+func describeActions(actions []SyncAction) []string {
+	descriptions := []string{}
+	for _, a := range actions {
+		if a.Type == SyncConflict {
+			descriptions = append(descriptions, a.Type.String()+" "+a.RelPath)
+			continue
+		}
+		direction := "上传"
+		if a.Direction == Download {
+			direction = "下载"
+		}
+		descriptions = append(descriptions, a.Type.String()+" "+direction+" "+a.RelPath)
+	}
+	return descriptions
+}
+
+func TestEntryChanged(t *testing.T) {
+	tests := []struct {
+		name   string
+		entry  syncEntry
+		record syncEntry
+		want   bool
+	}{
+		{name: "未修改", entry: syncFile(1, 0), record: syncFile(1, 0), want: false},
+		{name: "大小不同", entry: syncFile(2, 0), record: syncFile(1, 0), want: true},
+		{name: "修改时间不同", entry: syncFile(1, 10), record: syncFile(1, 0), want: true},
+		{name: "修改时间在容差内", entry: syncEntry{Size: 1, ModTime: syncTestTime.Add(time.Second)}, record: syncFile(1, 0), want: false},
+		{name: "修改时间早于记录", entry: syncEntry{Size: 1, ModTime: syncTestTime.Add(-3 * time.Second)}, record: syncFile(1, 0), want: true},
+		{name: "目录不比较大小和时间", entry: syncEntry{IsDir: true, Size: 4096}, record: syncDir(), want: false},
+		{name: "类型不同", entry: syncDir(), record: syncFile(1, 0), want: true},
+	}
+	for _, tt := range tests {
+		if got := entryChanged(tt.entry, tt.record, 2*time.Second); got != tt.want {
+			t.Errorf("%s: 得到 %v，期望 %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScanLocalTree(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "d", "e"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "d", "x.txt"), []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "d", "e", "y.log"), []byte("y"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	exclude, err := NewFilter(FilterRules{Exclude: []string{"e"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		root   string
+		filter *Filter
+		want   map[string]int64 // 目录的大小记为 -1
+	}{
+		{name: "全部条目", root: root, want: map[string]int64{"d": -1, "d/e": -1, "d/e/y.log": 1, "d/x.txt": 3}},
+		{name: "跳过排除的目录", root: root, filter: exclude, want: map[string]int64{"d": -1, "d/x.txt": 3}},
+		{name: "目录不存在", root: filepath.Join(root, "missing"), want: map[string]int64{}},
+	}
+	for _, tt := range tests {
+		entries, err := scanLocalTree(tt.root, tt.filter)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		got := map[string]int64{}
+		for rel, e := range entries {
+			if e.IsDir {
+				got[rel] = -1
+			} else {
+				got[rel] = e.Size
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: 扫描得到 %v，期望 %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLocalTreeSize(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("abcd"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "d", "b.log"), []byte("bb"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		root  string
+		rules FilterRules
+		want  int64
+	}{
+		{name: "全部文件", root: root, want: 6},
+		{name: "排除日志", root: root, rules: FilterRules{Exclude: []string{"*.log"}}, want: 4},
+		{name: "目录不存在", root: filepath.Join(root, "missing"), want: 0},
+	}
+	for _, tt := range tests {
+		size, err := LocalTreeSize(tt.root, tt.rules)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if size != tt.want {
+			t.Errorf("%s: 得到 %d，期望 %d", tt.name, size, tt.want)
+		}
+	}
+}
+
+func TestExecuteSyncDeleteLocalWithFilters(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules FilterRules
+		want  []string // 删除后 extra 目录中剩余的文件
+	}{
+		{name: "没有过滤规则", want: nil},
+		{name: "保留排除的文件", rules: FilterRules{Exclude: []string{"*.keep"}}, want: []string{"a.keep"}},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		extra := filepath.Join(root, "extra")
+		if err := os.MkdirAll(extra, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"a.keep", "b.txt"} {
+			if err := os.WriteFile(filepath.Join(extra, name), []byte(name), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		fs := &SFTPFileSystem{options: TransferOptions{Filters: tt.rules}}
+		action := SyncAction{Type: SyncDelete, Direction: Download, IsDir: true, LocalPath: extra, RelPath: "extra"}
+		if err := fs.executeSyncAction(action, SyncDownload, nil); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var got []string
+		entries, _ := os.ReadDir(extra)
+		for _, entry := range entries {
+			got = append(got, entry.Name())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: 剩余 %q，期望 %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlanSyncMirror(t *testing.T) {
+	upload := SyncOptions{Mode: SyncUpload, TimeTolerance: 2 * time.Second}
+	uploadDelete := upload
+	uploadDelete.DeleteExtras = true
+	download := SyncOptions{Mode: SyncDownload, DeleteExtras: true, TimeTolerance: 2 * time.Second}
+
+	tests := []struct {
+		name    string
+		options SyncOptions
+		local   map[string]syncEntry
+		remote  map[string]syncEntry
+		want    []string
+	}{
+		{
+			name:    "两端为空",
+			options: upload,
+			want:    []string{},
+		},
+		{
+			name:    "目标端不存在",
+			options: upload,
+			local:   map[string]syncEntry{"a": syncFile(1, 0), "d": syncDir(), "d/x": syncFile(1, 0)},
+			want:    []string{"复制 上传 a", "复制 上传 d", "复制 上传 d/x"},
+		},
+		{
+			name:    "未设置删除时保留多余的文件",
+			options: upload,
+			remote:  map[string]syncEntry{"extra": syncFile(1, 0)},
+			want:    []string{},
+		},
+		{
+			name:    "整体删除多余的目录",
+			options: uploadDelete,
+			remote:  map[string]syncEntry{"d": syncDir(), "d/x": syncFile(1, 0), "d/y": syncFile(1, 0)},
+			want:    []string{"删除 上传 d"},
+		},
+		{
+			name:    "相同的文件",
+			options: upload,
+			local:   map[string]syncEntry{"a": syncFile(1, 0)},
+			remote:  map[string]syncEntry{"a": syncFile(1, 0)},
+			want:    []string{},
+		},
+		{
+			name:    "不同的文件",
+			options: upload,
+			local:   map[string]syncEntry{"a": syncFile(2, 0)},
+			remote:  map[string]syncEntry{"a": syncFile(1, 0)},
+			want:    []string{"更新 上传 a"},
+		},
+		{
+			name:    "已有目录中的新文件",
+			options: upload,
+			local:   map[string]syncEntry{"d": syncDir(), "d/x": syncFile(1, 0)},
+			remote:  map[string]syncEntry{"d": syncDir()},
+			want:    []string{"复制 上传 d/x"},
+		},
+		{
+			name:    "类型不同时先删除再复制",
+			options: uploadDelete,
+			local:   map[string]syncEntry{"a": syncFile(1, 0)},
+			remote:  map[string]syncEntry{"a": syncDir(), "a/x": syncFile(1, 0)},
+			want:    []string{"删除 上传 a", "复制 上传 a"},
+		},
+		{
+			name:    "下载镜像",
+			options: download,
+			local:   map[string]syncEntry{"a": syncFile(1, 0), "b": syncFile(1, 0)},
+			remote:  map[string]syncEntry{"a": syncFile(3, 0), "c": syncFile(1, 0)},
+			want:    []string{"更新 下载 a", "删除 下载 b", "复制 下载 c"},
+		},
+	}
+	for _, tt := range tests {
+		actions, err := planSync("/local", "/remote", tt.local, tt.remote, nil, tt.options, compareBySize)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := describeActions(actions); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: 得到 %q，期望 %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlanSyncTwoWay(t *testing.T) {
+	options := SyncOptions{Mode: SyncTwoWay, TimeTolerance: 2 * time.Second}
+
+	tests := []struct {
+		name   string
+		local  map[string]syncEntry
+		remote map[string]syncEntry
+		state  map[string]syncStateEntry
+		want   []string
+	}{
+		{
+			name:   "首次同步内容相同",
+			local:  map[string]syncEntry{"a": syncFile(1, 0)},
+			remote: map[string]syncEntry{"a": syncFile(1, 5)},
+			want:   []string{},
+		},
+		{
+			name:   "首次同步内容不同",
+			local:  map[string]syncEntry{"a": syncFile(1, 0)},
+			remote: map[string]syncEntry{"a": syncFile(2, 0)},
+			want:   []string{"冲突 a"},
+		},
+		{
+			name:   "两端新增",
+			local:  map[string]syncEntry{"l": syncFile(1, 0)},
+			remote: map[string]syncEntry{"r": syncFile(1, 0)},
+			want:   []string{"复制 上传 l", "复制 下载 r"},
+		},
+		{
+			name:  "远程已删除，本地未修改",
+			local: map[string]syncEntry{"a": syncFile(1, 0)},
+			state: map[string]syncStateEntry{"a": synced(syncFile(1, 0))},
+			want:  []string{"删除 下载 a"},
+		},
+		{
+			name:  "远程已删除，本地已修改",
+			local: map[string]syncEntry{"a": syncFile(2, 0)},
+			state: map[string]syncStateEntry{"a": synced(syncFile(1, 0))},
+			want:  []string{"冲突 a"},
+		},
+		{
+			name:   "本地已删除，远程已修改",
+			remote: map[string]syncEntry{"a": syncFile(1, 10)},
+			state:  map[string]syncStateEntry{"a": synced(syncFile(1, 0))},
+			want:   []string{"冲突 a"},
+		},
+		{
+			name:   "修改时间在容差内",
+			local:  map[string]syncEntry{"a": syncFile(1, 0)},
+			remote: map[string]syncEntry{"a": {Size: 1, ModTime: syncTestTime.Add(time.Second)}},
+			state:  map[string]syncStateEntry{"a": synced(syncFile(1, 0))},
+			want:   []string{},
+		},
+		{
+			name:   "只有一端修改",
+			local:  map[string]syncEntry{"a": syncFile(2, 0), "b": syncFile(1, 0)},
+			remote: map[string]syncEntry{"a": syncFile(1, 0), "b": syncFile(1, 10)},
+			state:  map[string]syncStateEntry{"a": synced(syncFile(1, 0)), "b": synced(syncFile(1, 0))},
+			want:   []string{"更新 上传 a", "更新 下载 b"},
+		},
+		{
+			name:   "两端修改为相同内容",
+			local:  map[string]syncEntry{"a": syncFile(2, 10)},
+			remote: map[string]syncEntry{"a": syncFile(2, 20)},
+			state:  map[string]syncStateEntry{"a": synced(syncFile(1, 0))},
+			want:   []string{},
+		},
+		{
+			name:   "类型不同",
+			local:  map[string]syncEntry{"a": syncDir()},
+			remote: map[string]syncEntry{"a": syncFile(1, 0)},
+			want:   []string{"冲突 a"},
+		},
+		{
+			name:  "删除目录时逐项删除，目录最后删除",
+			local: map[string]syncEntry{"d": syncDir(), "d/x": syncFile(1, 0)},
+			state: map[string]syncStateEntry{"d": synced(syncDir()), "d/x": synced(syncFile(1, 0))},
+			want:  []string{"删除 下载 d/x", "删除 下载 d"},
+		},
+		{
+			name:   "嵌套目录由深到浅删除",
+			remote: map[string]syncEntry{"d": syncDir(), "d/e": syncDir(), "d/e/x": syncFile(1, 0)},
+			state:  map[string]syncStateEntry{"d": synced(syncDir()), "d/e": synced(syncDir()), "d/e/x": synced(syncFile(1, 0))},
+			want:   []string{"删除 上传 d/e/x", "删除 上传 d/e", "删除 上传 d"},
+		},
+		{
+			name:  "已删除目录中的新增内容成为冲突，目录保留",
+			local: map[string]syncEntry{"d": syncDir(), "d/new": syncFile(1, 0), "d/x": syncFile(1, 0)},
+			state: map[string]syncStateEntry{"d": synced(syncDir()), "d/x": synced(syncFile(1, 0))},
+			want:  []string{"冲突 d/new", "删除 下载 d/x"},
+		},
+		{
+			name:  "已删除目录中的修改成为冲突，目录保留",
+			local: map[string]syncEntry{"d": syncDir(), "d/e": syncDir(), "d/e/x": syncFile(2, 0)},
+			state: map[string]syncStateEntry{"d": synced(syncDir()), "d/e": synced(syncDir()), "d/e/x": synced(syncFile(1, 0))},
+			want:  []string{"冲突 d/e/x"},
+		},
+	}
+	for _, tt := range tests {
+		actions, err := planSync("/local", "/remote", tt.local, tt.remote, tt.state, options, compareBySize)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := describeActions(actions); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: 得到 %q，期望 %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlanSyncConflictReasons(t *testing.T) {
+	options := SyncOptions{Mode: SyncTwoWay, TimeTolerance: 2 * time.Second}
+	tests := []struct {
+		name   string
+		local  map[string]syncEntry
+		remote map[string]syncEntry
+		want   string
+	}{
+		{
+			name:  "远程删除目录，本地新增",
+			local: map[string]syncEntry{"d": syncDir(), "d/new": syncFile(1, 0)},
+			want:  "远程已删除所在目录，本地有新增",
+		},
+		{
+			name:   "本地删除目录，远程新增",
+			remote: map[string]syncEntry{"d": syncDir(), "d/new": syncFile(1, 0)},
+			want:   "本地已删除所在目录，远程有新增",
+		},
+	}
+	state := map[string]syncStateEntry{"d": synced(syncDir())}
+	for _, tt := range tests {
+		actions, err := planSync("/local", "/remote", tt.local, tt.remote, state, options, compareBySize)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(actions) != 1 || actions[0].Type != SyncConflict || actions[0].Reason != tt.want {
+			t.Errorf("%s: 得到 %+v，期望一个原因为 %q 的冲突", tt.name, actions, tt.want)
+		}
+	}
+}
+
+func TestPlanSyncPaths(t *testing.T) {
+	actions, err := planSync("/local", "/remote/root", map[string]syncEntry{"d/x.txt": syncFile(1, 0)}, nil, nil,
+		SyncOptions{Mode: SyncUpload}, compareBySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 1 {
+		t.Fatalf("得到 %d 个动作，期望 1 个", len(actions))
+	}
+	if got := actions[0].RemotePath; got != "/remote/root/d/x.txt" {
+		t.Errorf("远程路径为 %q", got)
+	}
+	if got := actions[0].LocalPath; got != LocalPaths.Join("/local", "d", "x.txt") {
+		t.Errorf("本地路径为 %q", got)
+	}
+}
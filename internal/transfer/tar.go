@@ -0,0 +1,448 @@
+package transfer
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// errTarUnavailable 服务器不允许执行 tar，需要回退到SFTP
+var errTarUnavailable = errors.New("服务器不支持 tar 流传输")
+
+// tarAvailable 检查服务器上是否可以执行 tar，结果会被缓存
+func (fs *SFTPFileSystem) tarAvailable() bool {
+	if fs.tarMode == "" {
+		fs.tarMode = "-"
+		if _, err := fs.runRemote("tar --version"); err == nil {
+			fs.tarMode = "tar"
+		}
+	}
+	return fs.tarMode == "tar"
+}
+
+// downloadTar 在服务器上运行 tar 打包目录，通过SSH通道流式传输并在本地边接收边解压；
+// 有过滤规则时回退到SFTP逐个下载
+func (fs *SFTPFileSystem) downloadTar(remotePath, localPath string, progress func(current, total int64), job *directoryJob) error {
+	// 过滤规则包含大小、时间和 .xftpignore，无法完整转换为远程 tar 的参数；
+	// 在本地解压时才过滤会把被排除的内容全部传输一遍
+	if job.filter != nil || !fs.tarAvailable() {
+		return errTarUnavailable
+	}
+
+	session, err := fs.sshClient.NewSession()
+	if err != nil {
+		return errTarUnavailable
+	}
+	defer session.Close()
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+	if err := session.Start(fmt.Sprintf("tar -C %s -cf - .", shellQuote(remotePath))); err != nil {
+		return errTarUnavailable
+	}
+
+	if err := os.MkdirAll(localPath, 0755); err != nil {
+		return err
+	}
+
+	extractErr := extractTar(tar.NewReader(stdout), localPath, progress, job, fs.options.ContinueOnError, fs.stop)
+	if errors.Is(extractErr, ErrCanceled) {
+		// 不再读取剩余数据，关闭会话结束远程 tar
+		return extractErr
+	}
+	// 读完剩余数据，避免远程进程阻塞
+	io.Copy(io.Discard, stdout)
+	waitErr := session.Wait()
+	if extractErr != nil {
+		return extractErr
+	}
+	if waitErr != nil {
+		// tar 跳过无法读取的文件后以非零状态退出，其余文件已经解压
+		failures := tarFailures(stderr.String(), waitErr, func(rel string) (string, string) {
+			return RemotePaths.Join(remotePath, rel), filepath.Join(localPath, filepath.FromSlash(rel))
+		}, Download)
+		if !fs.options.ContinueOnError {
+			return failures[0].Err
+		}
+		job.report.Failures = append(job.report.Failures, failures...)
+	}
+	return nil
+}
+
+// tarFailures 从 tar 的错误输出中解析失败的条目，形如 "tar: ./dir/file: Cannot open: Permission denied"；
+// 无法对应到条目时返回一个整体的失败项
+func tarFailures(stderr string, waitErr error, paths func(rel string) (source, target string), direction TransferDirection) []TransferFailure {
+	var failures []TransferFailure
+	for _, line := range strings.Split(stderr, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), ": ", 3)
+		if len(parts) < 3 || parts[0] != "tar" {
+			continue
+		}
+		rel := path.Clean(strings.TrimPrefix(parts[1], "./"))
+		if rel == "." || path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+			continue
+		}
+		source, target := paths(rel)
+		failures = append(failures, TransferFailure{
+			Source:    source,
+			Target:    target,
+			Direction: direction,
+			Err:       fmt.Errorf("远程 tar 执行失败: %s", parts[2]),
+		})
+	}
+	if len(failures) == 0 {
+		source, target := paths(".")
+		failures = append(failures, TransferFailure{
+			Source:    source,
+			Target:    target,
+			Direction: direction,
+			Err:       fmt.Errorf("远程 tar 执行失败: %v %s", waitErr, strings.TrimSpace(stderr)),
+		})
+	}
+	return failures
+}
+
+// extractTar 解压 tar 流到 localRoot，按过滤规则跳过条目，并逐个报告进度；stop 关闭后在条目之间中止
+func extractTar(reader *tar.Reader, localRoot string, progress func(current, total int64), job *directoryJob, continueOnError bool, stop <-chan struct{}) error {
+	type link struct{ target, path string }
+	var links []link
+	var skipped []string
+
+	for {
+		if stopped(stop) {
+			return ErrCanceled
+		}
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("读取 tar 数据失败: %v", err)
+		}
+
+		rel, err := tarEntryPath(header.Name)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			continue
+		}
+		if UnderAny(rel, skipped) {
+			continue
+		}
+		isDir := header.Typeflag == tar.TypeDir
+		if !job.filter.Match(rel, isDir, header.Size, header.ModTime) {
+			if isDir {
+				skipped = append(skipped, rel)
+			}
+			continue
+		}
+
+		target := filepath.Join(localRoot, filepath.FromSlash(rel))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			err := extractTarFile(reader, header, target, progress, stop)
+			if err == nil {
+				job.report.Succeeded++
+				continue
+			}
+			if !continueOnError || errors.Is(err, ErrCanceled) {
+				return err
+			}
+			job.report.addFailure(rel, target, Download, err)
+		case tar.TypeLink:
+			// 硬链接指向流中之前出现的文件
+			err := extractTarLink(localRoot, header.Linkname, target)
+			if err == nil {
+				job.report.Succeeded++
+				continue
+			}
+			if !continueOnError {
+				return err
+			}
+			job.report.addFailure(rel, target, Download, err)
+		case tar.TypeSymlink:
+			// 符号链接最后创建，避免后续条目通过链接写到目标目录之外
+			links = append(links, link{target: header.Linkname, path: target})
+		default:
+			err := fmt.Errorf("不支持的 tar 条目类型: %q", header.Typeflag)
+			if !continueOnError {
+				return err
+			}
+			job.report.addFailure(rel, target, Download, err)
+		}
+	}
+
+	for _, l := range links {
+		// 再次下载时替换已存在的链接或文件
+		if _, err := os.Lstat(l.path); err == nil {
+			if err := os.Remove(l.path); err != nil {
+				if !continueOnError {
+					return err
+				}
+				job.report.addFailure(l.path, l.path, Download, err)
+				continue
+			}
+		}
+		if err := os.Symlink(l.target, l.path); err != nil {
+			if !continueOnError {
+				return err
+			}
+			job.report.addFailure(l.path, l.path, Download, err)
+		}
+	}
+	return nil
+}
+
+// tarEntryPath 规范化 tar 条目的路径，拒绝指向目标目录之外的条目；根目录返回 "."
+func tarEntryPath(name string) (string, error) {
+	rel := path.Clean(strings.TrimPrefix(name, "./"))
+	if path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("tar 条目路径不安全: %s", name)
+	}
+	return rel, nil
+}
+
+// extractTarLink 创建硬链接，文件系统不支持时复制已解压的文件
+func extractTarLink(localRoot, linkname, target string) error {
+	rel, err := tarEntryPath(linkname)
+	if err != nil {
+		return err
+	}
+	source := filepath.Join(localRoot, filepath.FromSlash(rel))
+	info, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("硬链接的目标不存在: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	// 再次下载时替换已存在的文件
+	if _, err := os.Lstat(target); err == nil {
+		if err := os.Remove(target); err != nil {
+			return err
+		}
+	}
+	if os.Link(source, target) == nil {
+		return nil
+	}
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(target, info.ModTime(), info.ModTime())
+}
+
+// extractTarFile 解压单个文件并保留权限和修改时间
+func extractTarFile(reader io.Reader, header *tar.Header, target string, progress func(current, total int64), stop <-chan struct{}) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode)&os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	var written int64
+	_, err = io.Copy(file, &progressReader{
+		reader: reader,
+		stop:   stop,
+		progress: func(n int64) {
+			written += n
+			if progress != nil {
+				progress(written, header.Size)
+			}
+		},
+	})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if header.Size == 0 && progress != nil {
+		progress(0, 0)
+	}
+	return os.Chtimes(target, header.ModTime, header.ModTime)
+}
+
+// uploadTar 在本地打包目录，通过SSH通道流式传输给服务器上的 tar 解压；
+// 设置了出错继续时，无法读取的文件和服务器上解压失败的文件记录到报告中
+func (fs *SFTPFileSystem) uploadTar(localPath, remotePath string, filter *Filter, progress func(current, total int64)) error {
+	if !fs.tarAvailable() {
+		return errTarUnavailable
+	}
+
+	session, err := fs.sshClient.NewSession()
+	if err != nil {
+		return errTarUnavailable
+	}
+	defer session.Close()
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+	quoted := shellQuote(remotePath)
+	if err := session.Start(fmt.Sprintf("mkdir -p %s && tar -C %s -xf -", quoted, quoted)); err != nil {
+		return errTarUnavailable
+	}
+
+	report := &TransferReport{}
+	remoteOf := func(rel string) (string, string) {
+		return filepath.Join(localPath, filepath.FromSlash(rel)), RemotePaths.Join(remotePath, rel)
+	}
+	var written []string // 已写入的普通文件
+	writer := tar.NewWriter(stdin)
+	writeErr := filepath.Walk(localPath, func(p string, info os.FileInfo, err error) error {
+		if stopped(fs.stop) {
+			return ErrCanceled
+		}
+		rel, relErr := filepath.Rel(localPath, p)
+		if relErr != nil {
+			return relErr
+		}
+		if err == nil && rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if err == nil {
+			if !filter.Match(rel, info.IsDir(), info.Size(), info.ModTime()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			err = writeTarEntry(writer, p, rel, info, progress, fs.stop)
+			if err == nil {
+				if info.Mode().IsRegular() {
+					written = append(written, rel)
+				}
+				return nil
+			}
+			if errors.Is(err, errTarStream) || errors.Is(err, ErrCanceled) {
+				// 条目已部分写入，数据流无法继续
+				return err
+			}
+		}
+		if !fs.options.ContinueOnError {
+			return err
+		}
+		_, target := remoteOf(rel)
+		report.addFailure(p, target, Upload, err)
+		if info != nil && info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if writeErr == nil {
+		writeErr = writer.Close()
+	}
+	stdin.Close()
+
+	waitErr := session.Wait()
+	if writeErr != nil {
+		return writeErr
+	}
+	failed := make(map[string]bool)
+	if waitErr != nil {
+		failures := tarFailures(stderr.String(), waitErr, remoteOf, Upload)
+		if !fs.options.ContinueOnError {
+			return failures[0].Err
+		}
+		for _, f := range failures {
+			failed[f.Source] = true
+		}
+		report.Failures = append(report.Failures, failures...)
+	}
+	for _, rel := range written {
+		if source, _ := remoteOf(rel); !failed[source] {
+			report.Succeeded++
+		}
+	}
+	if report.HasFailures() {
+		return report
+	}
+	return nil
+}
+
+// errTarStream tar 条目写入到一半时失败，数据流已损坏
+var errTarStream = errors.New("tar 数据流写入失败")
+
+// writeTarEntry 写入一个 tar 条目，普通文件逐个报告进度
+func writeTarEntry(writer *tar.Writer, p, rel string, info os.FileInfo, progress func(current, total int64), stop <-chan struct{}) error {
+	link := ""
+	if info.Mode()&os.ModeSymlink != 0 {
+		var err error
+		if link, err = os.Readlink(p); err != nil {
+			return err
+		}
+	}
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = rel
+	if info.IsDir() {
+		header.Name += "/"
+	}
+
+	// 先打开文件，无法读取时不写入条目，数据流仍可继续
+	var file *os.File
+	if info.Mode().IsRegular() {
+		if file, err = os.Open(p); err != nil {
+			return err
+		}
+		defer file.Close()
+	}
+	if err := writer.WriteHeader(header); err != nil {
+		return fmt.Errorf("%w: %v", errTarStream, err)
+	}
+	if file == nil {
+		return nil
+	}
+
+	var written int64
+	_, err = io.Copy(writer, &progressReader{
+		reader: file,
+		stop:   stop,
+		progress: func(n int64) {
+			written += n
+			if progress != nil {
+				progress(written, info.Size())
+			}
+		},
+	})
+	if err != nil && !errors.Is(err, ErrCanceled) {
+		return fmt.Errorf("%w: %v", errTarStream, err)
+	}
+	return err
+}
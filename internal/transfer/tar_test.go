@@ -0,0 +1,295 @@
+package transfer
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tarEntry 测试用的 tar 条目
+type tarEntry struct {
+	name     string
+	typeflag byte
+	body     string
+	link     string
+}
+
+// buildTar 按顺序写入条目，返回 tar 数据
+func buildTar(t *testing.T, entries []tarEntry) []byte {
+	var buf bytes.Buffer
+	writer := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Mode:     0644,
+			Size:     int64(len(e.body)),
+			Linkname: e.link,
+			ModTime:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+		if e.typeflag != tar.TypeReg {
+			header.Size = 0
+		}
+		if e.typeflag == tar.TypeDir {
+			header.Mode = 0755
+		}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if e.typeflag == tar.TypeReg {
+			writer.Write([]byte(e.body))
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// listTree 列出目录下的全部条目："路径" 为目录，"路径=内容" 为文件，"路径->目标" 为符号链接
+func listTree(t *testing.T, root string) []string {
+	var entries []string
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil || p == root {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, _ := os.Readlink(p)
+			entries = append(entries, rel+"->"+target)
+		case info.IsDir():
+			entries = append(entries, rel)
+		default:
+			data, _ := os.ReadFile(p)
+			entries = append(entries, rel+"="+string(data))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+func TestExtractTar(t *testing.T) {
+	exclude, err := NewFilter(FilterRules{Exclude: []string{"node_modules", "*.log"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		entries   []tarEntry
+		filter    *Filter
+		want      []string
+		succeeded int
+		err       bool
+	}{
+		{
+			name: "目录和文件",
+			entries: []tarEntry{
+				{name: "./", typeflag: tar.TypeDir},
+				{name: "./a.txt", typeflag: tar.TypeReg, body: "a"},
+				{name: "./d/", typeflag: tar.TypeDir},
+				{name: "./d/b.txt", typeflag: tar.TypeReg, body: "bb"},
+				{name: "./empty", typeflag: tar.TypeReg},
+			},
+			want:      []string{"a.txt=a", "d", "d/b.txt=bb", "empty="},
+			succeeded: 3,
+		},
+		{
+			name:      "缺少目录条目时自动创建上级目录",
+			entries:   []tarEntry{{name: "x/y/z.txt", typeflag: tar.TypeReg, body: "z"}},
+			want:      []string{"x", "x/y", "x/y/z.txt=z"},
+			succeeded: 1,
+		},
+		{
+			name: "按过滤规则跳过目录和文件",
+			entries: []tarEntry{
+				{name: "./node_modules/", typeflag: tar.TypeDir},
+				{name: "./node_modules/m.js", typeflag: tar.TypeReg, body: "m"},
+				{name: "./a.log", typeflag: tar.TypeReg, body: "log"},
+				{name: "./src/", typeflag: tar.TypeDir},
+				{name: "./src/main.go", typeflag: tar.TypeReg, body: "go"},
+			},
+			filter:    exclude,
+			want:      []string{"src", "src/main.go=go"},
+			succeeded: 1,
+		},
+		{
+			name: "符号链接最后创建",
+			entries: []tarEntry{
+				{name: "link", typeflag: tar.TypeSymlink, link: "target.txt"},
+				{name: "target.txt", typeflag: tar.TypeReg, body: "t"},
+			},
+			want:      []string{"link->target.txt", "target.txt=t"},
+			succeeded: 1,
+		},
+		{
+			name: "硬链接",
+			entries: []tarEntry{
+				{name: "./d/", typeflag: tar.TypeDir},
+				{name: "./d/a.txt", typeflag: tar.TypeReg, body: "a"},
+				{name: "./d/hard", typeflag: tar.TypeLink, link: "./d/a.txt"},
+				{name: "./other/hard", typeflag: tar.TypeLink, link: "d/a.txt"},
+			},
+			want:      []string{"d", "d/a.txt=a", "d/hard=a", "other", "other/hard=a"},
+			succeeded: 3,
+		},
+		{
+			name: "硬链接指向目录之外",
+			entries: []tarEntry{
+				{name: "a.txt", typeflag: tar.TypeReg, body: "a"},
+				{name: "hard", typeflag: tar.TypeLink, link: "../a.txt"},
+			},
+			err: true,
+		},
+		{
+			name:    "拒绝上级目录",
+			entries: []tarEntry{{name: "../evil", typeflag: tar.TypeReg, body: "x"}},
+			err:     true,
+		},
+		{
+			name:    "拒绝绝对路径",
+			entries: []tarEntry{{name: "/etc/evil", typeflag: tar.TypeReg, body: "x"}},
+			err:     true,
+		},
+		{
+			name:      "以..开头的文件名",
+			entries:   []tarEntry{{name: "..hidden", typeflag: tar.TypeReg, body: "h"}},
+			want:      []string{"..hidden=h"},
+			succeeded: 1,
+		},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		job := &directoryJob{report: &TransferReport{}, filter: tt.filter}
+		data := buildTar(t, tt.entries)
+		err := extractTar(tar.NewReader(bytes.NewReader(data)), root, nil, job, false, nil)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: 错误 %v，期望出错 %v", tt.name, err, tt.err)
+			continue
+		}
+		if tt.err {
+			continue
+		}
+		if got := listTree(t, root); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: 解压得到 %q，期望 %q", tt.name, got, tt.want)
+		}
+		if job.report.Succeeded != tt.succeeded || job.report.HasFailures() {
+			t.Errorf("%s: 成功 %d 个，失败 %d 个，期望成功 %d 个", tt.name, job.report.Succeeded, len(job.report.Failures), tt.succeeded)
+		}
+	}
+}
+
+func TestExtractTarReplacesSymlink(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Symlink("old", filepath.Join(root, "link")); err != nil {
+		t.Skip("无法创建符号链接:", err)
+	}
+	data := buildTar(t, []tarEntry{{name: "link", typeflag: tar.TypeSymlink, link: "new"}})
+	job := &directoryJob{report: &TransferReport{}}
+	if err := extractTar(tar.NewReader(bytes.NewReader(data)), root, nil, job, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.Readlink(filepath.Join(root, "link")); got != "new" {
+		t.Errorf("链接指向 %q，期望 %q", got, "new")
+	}
+}
+
+func TestExtractTarProgressAndCancel(t *testing.T) {
+	data := buildTar(t, []tarEntry{
+		{name: "a", typeflag: tar.TypeReg, body: strings.Repeat("x", 100)},
+		{name: "b", typeflag: tar.TypeReg},
+	})
+	var progress []int64
+	job := &directoryJob{report: &TransferReport{}}
+	err := extractTar(tar.NewReader(bytes.NewReader(data)), t.TempDir(), func(current, total int64) {
+		progress = append(progress, current, total)
+	}, job, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{100, 100, 0, 0}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("进度为 %v，期望 %v", progress, want)
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	err = extractTar(tar.NewReader(bytes.NewReader(data)), t.TempDir(), nil, &directoryJob{report: &TransferReport{}}, false, stop)
+	if !errors.Is(err, ErrCanceled) {
+		t.Errorf("取消后应返回 ErrCanceled，得到 %v", err)
+	}
+}
+
+func TestExtractTarContinueOnError(t *testing.T) {
+	root := t.TempDir()
+	// 同名目录使文件无法写入
+	if err := os.MkdirAll(filepath.Join(root, "blocked", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := buildTar(t, []tarEntry{
+		{name: "blocked", typeflag: tar.TypeReg, body: "x"},
+		{name: "ok", typeflag: tar.TypeReg, body: "y"},
+		{name: "fifo", typeflag: tar.TypeFifo},
+		{name: "missing-link", typeflag: tar.TypeLink, link: "missing"},
+	})
+
+	job := &directoryJob{report: &TransferReport{}}
+	if err := extractTar(tar.NewReader(bytes.NewReader(data)), root, nil, job, false, nil); err == nil {
+		t.Errorf("未设置出错继续时应返回错误")
+	}
+
+	job = &directoryJob{report: &TransferReport{}}
+	if err := extractTar(tar.NewReader(bytes.NewReader(data)), root, nil, job, true, nil); err != nil {
+		t.Fatalf("设置出错继续时不应返回错误: %v", err)
+	}
+	var failed []string
+	for _, f := range job.report.Failures {
+		failed = append(failed, f.Source)
+	}
+	if want := []string{"blocked", "fifo", "missing-link"}; job.report.Succeeded != 1 || !reflect.DeepEqual(failed, want) {
+		t.Errorf("成功 %d 个，失败 %q，期望成功 1 个，失败 %q", job.report.Succeeded, failed, want)
+	}
+}
+
+func TestTarFailures(t *testing.T) {
+	paths := func(rel string) (string, string) { return "/remote/" + rel, "/local/" + rel }
+	tests := []struct {
+		name    string
+		stderr  string
+		sources []string
+	}{
+		{
+			name:    "逐个文件的错误",
+			stderr:  "tar: ./a/secret: Cannot open: Permission denied\ntar: ./b: Cannot open: Permission denied\ntar: Exiting with failure status due to previous errors\n",
+			sources: []string{"/remote/a/secret", "/remote/b"},
+		},
+		{name: "无法对应到条目", stderr: "bash: tar: command not found\n", sources: []string{"/remote/."}},
+		{name: "忽略目录之外的路径", stderr: "tar: ../x: Cannot open: No such file\n", sources: []string{"/remote/."}},
+		{name: "没有错误输出", stderr: "", sources: []string{"/remote/."}},
+	}
+	for _, tt := range tests {
+		failures := tarFailures(tt.stderr, errors.New("exit status 2"), paths, Download)
+		var sources []string
+		for _, f := range failures {
+			sources = append(sources, f.Source)
+			if f.Direction != Download || f.Err == nil {
+				t.Errorf("%s: 失败项 %+v 不完整", tt.name, f)
+			}
+		}
+		if !reflect.DeepEqual(sources, tt.sources) {
+			t.Errorf("%s: 得到 %q，期望 %q", tt.name, sources, tt.sources)
+		}
+	}
+}
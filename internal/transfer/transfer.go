@@ -29,6 +29,8 @@ type TransferProgress struct {
 // TransferManager 文件传输管理器
 type TransferManager struct {
 	onProgress func(TransferProgress) // 进度回调函数
+	filters    FilterRules            // 复制目录时的过滤规则
+	stop       <-chan struct{}        // 取消信号，为nil时不可取消
 }
 
 // NewTransferManager 创建新的传输管理器
@@ -38,6 +40,16 @@ func NewTransferManager(progressCallback func(TransferProgress)) *TransferManage
 	}
 }
 
+// SetFilterRules 设置复制目录时的过滤规则
+func (tm *TransferManager) SetFilterRules(rules FilterRules) {
+	tm.filters = rules
+}
+
+// SetStop 设置取消信号，stop 关闭后在当前读写完成后返回 ErrCanceled，已取消的移动不会删除源文件
+func (tm *TransferManager) SetStop(stop <-chan struct{}) {
+	tm.stop = stop
+}
+
 // Transfer 传输文件或目录
 func (tm *TransferManager) Transfer(src, dst string, transferType TransferType) error {
 	// 获取源文件信息
@@ -49,8 +61,17 @@ func (tm *TransferManager) Transfer(src, dst string, transferType TransferType)
 	// 创建目标路径
 	dstPath := filepath.Join(dst, filepath.Base(src))
 
-	// 如果是移动操作，先尝试直接重命名
-	if transferType == Move {
+	// 加载过滤规则
+	var filter *Filter
+	if srcInfo.IsDir() {
+		filter, err = LoadLocalFilter(tm.filters, src)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 如果是移动操作，先尝试直接重命名（有过滤规则时需要逐个处理）
+	if transferType == Move && filter == nil {
 		if err := os.Rename(src, dstPath); err == nil {
 			// 重命名成功，直接返回
 			if tm.onProgress != nil {
@@ -67,15 +88,15 @@ func (tm *TransferManager) Transfer(src, dst string, transferType TransferType)
 
 	// 如果是目录，递归复制
 	if srcInfo.IsDir() {
-		return tm.transferDir(src, dstPath, transferType)
+		return tm.transferDir(src, dstPath, "", transferType, filter)
 	}
 
 	// 如果是文件，直接复制
 	return tm.transferFile(src, dstPath, transferType)
 }
 
-// transferDir 递归传输目录
-func (tm *TransferManager) transferDir(src, dst string, transferType TransferType) error {
+// transferDir 递归传输目录，rel 为相对于源根目录的路径
+func (tm *TransferManager) transferDir(src, dst, rel string, transferType TransferType, filter *Filter) error {
 	// 创建目标目录
 	if err := os.MkdirAll(dst, 0755); err != nil {
 		return fmt.Errorf("创建目标目录失败: %v", err)
@@ -91,9 +112,21 @@ func (tm *TransferManager) transferDir(src, dst string, transferType TransferTyp
 	for _, entry := range entries {
 		srcPath := filepath.Join(src, entry.Name())
 		dstPath := filepath.Join(dst, entry.Name())
+		entryRel := joinRel(rel, entry.Name())
+
+		// 跳过不符合过滤规则的文件和目录
+		if filter != nil {
+			info, err := entry.Info()
+			if err != nil {
+				return fmt.Errorf("获取源文件信息失败: %v", err)
+			}
+			if !filter.Match(entryRel, entry.IsDir(), info.Size(), info.ModTime()) {
+				continue
+			}
+		}
 
 		if entry.IsDir() {
-			if err := tm.transferDir(srcPath, dstPath, transferType); err != nil {
+			if err := tm.transferDir(srcPath, dstPath, entryRel, transferType, filter); err != nil {
 				return err
 			}
 		} else {
@@ -105,6 +138,11 @@ func (tm *TransferManager) transferDir(src, dst string, transferType TransferTyp
 
 	// 如果是移动操作，删除源目录
 	if transferType == Move {
+		if filter != nil {
+			// 有过滤规则时只删除已清空的目录，保留被排除的内容
+			os.Remove(src)
+			return nil
+		}
 		if err := os.RemoveAll(src); err != nil {
 			return fmt.Errorf("删除源目录失败: %v", err)
 		}
@@ -139,6 +177,7 @@ func (tm *TransferManager) transferFile(src, dst string, transferType TransferTy
 	progressReader := &ProgressReader{
 		reader: srcFile,
 		size:   srcInfo.Size(),
+		stop:   tm.stop,
 		onProgress: func(transferred int64) {
 			if tm.onProgress != nil {
 				percentage := float64(transferred) / float64(srcInfo.Size()) * 100
@@ -156,7 +195,7 @@ func (tm *TransferManager) transferFile(src, dst string, transferType TransferTy
 
 	// 复制文件内容
 	if _, err := io.Copy(dstFile, progressReader); err != nil {
-		return fmt.Errorf("复制文件内容失败: %v", err)
+		return fmt.Errorf("复制文件内容失败: %w", err)
 	}
 
 	// 如果是移动操作，删除源文件
@@ -175,10 +214,14 @@ type ProgressReader struct {
 	size        int64
 	transferred int64
 	onProgress  func(int64)
+	stop        <-chan struct{}
 }
 
 // Read 实现io.Reader接口
 func (pr *ProgressReader) Read(p []byte) (int, error) {
+	if stopped(pr.stop) {
+		return 0, ErrCanceled
+	}
 	n, err := pr.reader.Read(p)
 	if n > 0 {
 		pr.transferred += int64(n)
@@ -0,0 +1,275 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/sftp"
+)
+
+// DefaultWatchIgnore 默认忽略的文件模式
+var DefaultWatchIgnore = []string{".git", ".DS_Store", "*.swp", "*.tmp", "*~"}
+
+// watchDebounce 事件防抖时间
+const watchDebounce = 500 * time.Millisecond
+
+// WatchEvent 监视目录产生的同步事件
+type WatchEvent struct {
+	Time       time.Time
+	Action     string // 上传、删除、创建目录
+	LocalPath  string
+	RemotePath string
+	Err        error
+}
+
+// Watcher 监视本地目录，并将变化同步到远程目录
+// 重命名按“删除旧路径 + 上传新路径”处理
+type Watcher struct {
+	remote     RemoteFS
+	localRoot  string
+	remoteRoot string
+	ignore     []string
+	onEvent    func(WatchEvent)
+
+	watcher *fsnotify.Watcher
+	mu      sync.Mutex
+	pending map[string]bool // 待处理的本地路径
+	timer   *time.Timer
+	paused  bool
+	flushMu sync.Mutex
+	done    chan struct{}
+}
+
+// NewWatcher 创建新的目录监视器；remote 应为 Share 打开的独立通道，Close 时一并关闭
+func NewWatcher(remote RemoteFS, localRoot, remoteRoot string, ignore []string, onEvent func(WatchEvent)) *Watcher {
+	return &Watcher{
+		remote:     remote,
+		localRoot:  filepath.Clean(localRoot),
+		remoteRoot: remoteRoot,
+		ignore:     ignore,
+		onEvent:    onEvent,
+		pending:    make(map[string]bool),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start 开始监视
+func (w *Watcher) Start() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("创建目录监视失败: %v", err)
+	}
+	w.watcher = watcher
+
+	// fsnotify 不支持递归监视，需要逐个添加子目录
+	if err := w.addTree(w.localRoot); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go w.loop()
+	return nil
+}
+
+// Close 停止监视并关闭远程通道
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	default:
+		close(w.done)
+	}
+	w.remote.Close()
+	if w.watcher == nil {
+		return nil
+	}
+	return w.watcher.Close()
+}
+
+// SetPaused 暂停或恢复同步，暂停期间的变化会在恢复后统一处理
+func (w *Watcher) SetPaused(paused bool) {
+	w.mu.Lock()
+	w.paused = paused
+	w.mu.Unlock()
+	if !paused {
+		w.schedule()
+	}
+}
+
+// Paused 是否已暂停
+func (w *Watcher) Paused() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.paused
+}
+
+// loop 处理文件系统事件
+func (w *Watcher) loop() {
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if w.ignored(event.Name) {
+				continue
+			}
+			// 新建的目录需要加入监视
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					w.addTree(event.Name)
+				}
+			}
+			w.mu.Lock()
+			w.pending[event.Name] = true
+			w.mu.Unlock()
+			w.schedule()
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.emit(WatchEvent{Action: "监视", LocalPath: w.localRoot, Err: err})
+		}
+	}
+}
+
+// schedule 重置防抖定时器
+func (w *Watcher) schedule() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.paused || len(w.pending) == 0 {
+		return
+	}
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.timer = time.AfterFunc(watchDebounce, w.flush)
+}
+
+// flush 同步所有待处理的路径
+func (w *Watcher) flush() {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	if w.paused {
+		w.mu.Unlock()
+		return
+	}
+	pending := w.pending
+	w.pending = make(map[string]bool)
+	w.mu.Unlock()
+
+	for localPath := range pending {
+		select {
+		case <-w.done:
+			return
+		default:
+		}
+		w.syncPath(localPath)
+	}
+}
+
+// syncPath 根据本地路径的当前状态同步到远程
+func (w *Watcher) syncPath(localPath string) {
+	remotePath, err := w.remotePath(localPath)
+	if err != nil {
+		return
+	}
+
+	event := WatchEvent{LocalPath: localPath, RemotePath: remotePath}
+	info, err := os.Stat(localPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		event.Action = "删除"
+		event.Err = w.remote.DeleteFile(remotePath)
+		if errors.Is(event.Err, os.ErrNotExist) || errors.Is(event.Err, sftp.ErrSSHFxNoSuchFile) {
+			// 远程本来就不存在（如临时文件），无需提示
+			return
+		}
+	case err != nil:
+		event.Action = "上传"
+		event.Err = err
+	case info.IsDir():
+		// 目录可能在加入监视前已有内容，整体上传
+		event.Action = "上传目录"
+		event.Err = w.remote.UploadFile(localPath, remotePath, nil)
+	default:
+		event.Action = "上传"
+		event.Err = w.remote.UploadFile(localPath, remotePath, nil)
+	}
+	w.emit(event)
+}
+
+// addTree 将目录及其所有子目录加入监视
+func (w *Watcher) addTree(root string) error {
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if p != w.localRoot && w.ignored(p) {
+			return filepath.SkipDir
+		}
+		if err := w.watcher.Add(p); err != nil {
+			return fmt.Errorf("监视目录失败 %s: %v", p, err)
+		}
+		return nil
+	})
+}
+
+// ignored 判断路径是否匹配忽略模式（匹配文件名或相对路径中的任一段）
+func (w *Watcher) ignored(localPath string) bool {
+	rel, err := filepath.Rel(w.localRoot, localPath)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range w.ignore {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		for _, part := range strings.Split(rel, "/") {
+			if ok, _ := path.Match(pattern, part); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// remotePath 计算本地路径对应的远程路径
+func (w *Watcher) remotePath(localPath string) (string, error) {
+	rel, err := filepath.Rel(w.localRoot, localPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("路径不在监视目录内: %s", localPath)
+	}
+	return RemotePaths.Join(w.remoteRoot, filepath.ToSlash(rel)), nil
+}
+
+// emit 发送事件
+func (w *Watcher) emit(event WatchEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if w.onEvent != nil {
+		w.onEvent(event)
+	}
+}
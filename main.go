@@ -2,12 +2,10 @@ package main
 
 import (
 	"xftp798/internal/gui"
-	"xftp798/internal/transfer"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
-	"fyne.io/fyne/v2/dialog"
 )
 
 func main() {
@@ -15,34 +13,45 @@ func main() {
 	a := app.New()
 	window := a.NewWindow("XFTP文件传输")
 
-	// 创建左右文件面板
-	leftPanel := gui.NewFilePanel(window)
-	rightPanel := gui.NewFilePanel(window)
+	// 两侧面板共用的传输列表
+	transferList := gui.NewTransferList()
 
-	// 设置传输回调
-	leftPanel.SetTransferCallback(func(source string, targetPanel *gui.FilePanel, transferType transfer.TransferType) {
-		if err := rightPanel.HandleTransfer(source, transferType); err != nil {
-			dialog.ShowError(err, window)
-		}
-	})
+	// 创建左右两侧的标签页，恢复上次退出时打开的标签页
+	leftTabs := gui.NewPanelTabs(window, "left", transferList)
+	rightTabs := gui.NewPanelTabs(window, "right", transferList)
+	leftTabs.SetPeer(rightTabs)
+	rightTabs.SetPeer(leftTabs)
 
-	rightPanel.SetTransferCallback(func(source string, targetPanel *gui.FilePanel, transferType transfer.TransferType) {
-		if err := leftPanel.HandleTransfer(source, transferType); err != nil {
-			dialog.ShowError(err, window)
-		}
-	})
+	// 传输列表和终端停靠在面板下方
+	dock := gui.NewDockTabs(window, transferList)
+	leftTabs.SetDock(dock)
+	rightTabs.SetDock(dock)
+
+	// 接收从系统文件管理器拖入的文件
+	gui.EnableDrop(window, leftTabs, rightTabs)
 
 	// 创建分割面板
 	split := container.NewHSplit(
-		leftPanel.GetContainer(),
-		rightPanel.GetContainer(),
+		leftTabs.GetContainer(),
+		rightTabs.GetContainer(),
 	)
 	split.SetOffset(0.5) // 设置分割线位置在中间
 
+	content := container.NewVSplit(split, dock.GetContainer())
+	content.SetOffset(0.75)
+
 	// 设置窗口内容
-	window.SetContent(split)
+	window.SetContent(content)
+	leftTabs.Focus()
 	window.Resize(fyne.NewSize(1024, 768))
 
+	// 退出时保存标签页并结束会话，清理临时文件
+	window.SetOnClosed(func() {
+		leftTabs.Close()
+		rightTabs.Close()
+		dock.Close()
+	})
+
 	// 运行应用
 	window.ShowAndRun()
 }